@@ -2,8 +2,14 @@ module iqop
 
 go 1.25.3
 
+require scraper v0.0.0
+
 require (
 	github.com/PuerkitoBio/goquery v1.10.3 // indirect
 	github.com/andybalholm/cascadia v1.3.3 // indirect
+	golang.org/x/image v0.33.0 // indirect
 	golang.org/x/net v0.39.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace scraper => ../scraper
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"opscrape/catalog"
+)
+
+// ---------- Collation ----------
+
+// packSlot draws count cards, each rarity picked by weight.
+type packSlot struct {
+	Count  int            `json:"count"`
+	Rarity map[string]int `json:"rarity"`
+}
+
+// packInsert replaces one card of the first slot with a card of the given
+// printing (Chromium, Holographic, Foil) with probability Odds. Each insert
+// that hits takes its own slot, counting back from the slot's last card.
+type packInsert struct {
+	Printing string  `json:"printing"`
+	Odds     float64 `json:"odds"`
+}
+
+type collation struct {
+	Slots   []packSlot   `json:"slots"`
+	Inserts []packInsert `json:"inserts"`
+}
+
+// defaultCollation is a 14-card pack: ten commons, three uncommons and a
+// rare slot that upgrades to a Very Rare one time in four.
+var defaultCollation = collation{
+	Slots: []packSlot{
+		{Count: 10, Rarity: map[string]int{"Common": 1}},
+		{Count: 3, Rarity: map[string]int{"Uncommon": 1}},
+		{Count: 1, Rarity: map[string]int{"Rare": 3, "Very Rare": 1}},
+	},
+	Inserts: []packInsert{
+		{Printing: "Chromium", Odds: 0.10},
+		{Printing: "Holographic", Odds: 0.05},
+		{Printing: "Foil", Odds: 0.05},
+	},
+}
+
+// loadCollations reads {"default": {...}, "PSOP": {...}} keyed by set tag.
+func loadCollations(path string) (map[string]collation, error) {
+	out := map[string]collation{"default": defaultCollation}
+	if path == "" {
+		return out, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var custom map[string]collation
+	if err := json.NewDecoder(f).Decode(&custom); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for k, v := range custom {
+		if k != "default" {
+			k = strings.ToUpper(k)
+		}
+		for _, s := range v.Slots {
+			for r := range s.Rarity {
+				if catalog.RarityRank(catalog.NormalizeRarity(r)) == len(catalog.Rarities) {
+					return nil, fmt.Errorf("%s: %s: unknown rarity %q", path, k, r)
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// ---------- Packs ----------
+
+// packer opens packs of one set from rarity and printing pools.
+type packer struct {
+	col       collation
+	rng       *rand.Rand
+	byRarity  map[string][]*catalog.Card // Normal printings only
+	byPrint   map[string][]*catalog.Card
+	allNormal []*catalog.Card
+}
+
+func newPacker(s *catalog.Set, col collation, rng *rand.Rand) (*packer, error) {
+	p := &packer{col: col, rng: rng, byRarity: map[string][]*catalog.Card{}, byPrint: map[string][]*catalog.Card{}}
+	cards := s.OwnCards()
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	for _, c := range cards {
+		if c.Printing() == "Normal" {
+			p.byRarity[c.Rarity()] = append(p.byRarity[c.Rarity()], c)
+			p.allNormal = append(p.allNormal, c)
+		} else {
+			p.byPrint[c.Printing()] = append(p.byPrint[c.Printing()], c)
+		}
+	}
+	if len(p.allNormal) == 0 {
+		return nil, fmt.Errorf("%s has no Normal printings to pack", s.Code)
+	}
+	return p, nil
+}
+
+// pickRarity chooses a slot rarity by weight among rarities the set has.
+func (p *packer) pickRarity(weights map[string]int) string {
+	norm := map[string]int{}
+	for r, w := range weights {
+		if r = catalog.NormalizeRarity(r); w > 0 && len(p.byRarity[r]) > 0 {
+			norm[r] += w
+		}
+	}
+	keys := make([]string, 0, len(norm))
+	total := 0
+	for r, w := range norm {
+		keys = append(keys, r)
+		total += w
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	n := p.rng.IntN(total)
+	for _, r := range keys {
+		n -= norm[r]
+		if n < 0 {
+			return r
+		}
+	}
+	return keys[len(keys)-1]
+}
+
+// draw takes a card not yet in the pack, falling back to any Normal card
+// when the pool is exhausted.
+func (p *packer) draw(pool []*catalog.Card, seen map[*catalog.Card]bool) *catalog.Card {
+	for _, src := range [][]*catalog.Card{pool, p.allNormal} {
+		var free []*catalog.Card
+		for _, c := range src {
+			if !seen[c] {
+				free = append(free, c)
+			}
+		}
+		if len(free) > 0 {
+			c := free[p.rng.IntN(len(free))]
+			seen[c] = true
+			return c
+		}
+	}
+	return p.allNormal[p.rng.IntN(len(p.allNormal))]
+}
+
+func (p *packer) open() []*catalog.Card {
+	seen := map[*catalog.Card]bool{}
+	var pack []*catalog.Card
+	for _, s := range p.col.Slots {
+		for i := 0; i < s.Count; i++ {
+			pack = append(pack, p.draw(p.byRarity[p.pickRarity(s.Rarity)], seen))
+		}
+	}
+	// Inserts take the place of the last cards of the first slot.
+	placed := 0
+	for _, ins := range p.col.Inserts {
+		pool := p.byPrint[ins.Printing]
+		if len(pool) == 0 || p.rng.Float64() >= ins.Odds || len(p.col.Slots) == 0 || placed >= p.col.Slots[0].Count {
+			continue
+		}
+		pack[p.col.Slots[0].Count-1-placed] = pool[p.rng.IntN(len(pool))]
+		placed++
+	}
+	return pack
+}
+
+// ---------- Draft ----------
+
+// draftSeat is one seat of a draft pod: the packs it opened and the cards
+// it picked, both by round.
+type draftSeat struct {
+	Opened [][]*catalog.Card
+	Picks  [][]*catalog.Card
+}
+
+// pool is every card the seat picked, in pick order.
+func (s draftSeat) pool() []*catalog.Card {
+	var out []*catalog.Card
+	for _, r := range s.Picks {
+		out = append(out, r...)
+	}
+	return out
+}
+
+// draft runs a pod of seats for rounds rounds. Each round every seat opens
+// a pack; seats take one card from the pack in front of them and pass the
+// rest, left (to the next seat) in odd rounds and right in even ones, until
+// the packs are empty. Seats pick as bots do: the rarest card, an insert
+// printing before a Normal one, ties broken by the seed.
+func (p *packer) draft(seats, rounds int) []draftSeat {
+	pod := make([]draftSeat, seats)
+	for round := 0; round < rounds; round++ {
+		packs := make([][]*catalog.Card, seats)
+		for i := range pod {
+			packs[i] = p.open()
+			pod[i].Opened = append(pod[i].Opened, packs[i])
+			pod[i].Picks = append(pod[i].Picks, nil)
+		}
+		dir := 1
+		if round%2 == 1 {
+			dir = -1
+		}
+		for len(packs[0]) > 0 {
+			for i := range pod {
+				k := p.pick(packs[i])
+				pod[i].Picks[round] = append(pod[i].Picks[round], packs[i][k])
+				packs[i] = append(packs[i][:k:k], packs[i][k+1:]...)
+			}
+			next := make([][]*catalog.Card, seats)
+			for i := range packs {
+				next[(i+dir+seats)%seats] = packs[i]
+			}
+			packs = next
+		}
+	}
+	return pod
+}
+
+// pick returns the index of the card a bot takes from pack.
+func (p *packer) pick(pack []*catalog.Card) int {
+	score := func(c *catalog.Card) int {
+		s := 2 * min(catalog.RarityRank(c.Rarity()), len(catalog.Rarities)-1)
+		if c.Printing() != "Normal" {
+			s++
+		}
+		return s
+	}
+	var best []int
+	for i, c := range pack {
+		switch {
+		case len(best) == 0 || score(c) > score(pack[best[0]]):
+			best = []int{i}
+		case score(c) == score(pack[best[0]]):
+			best = append(best, i)
+		}
+	}
+	return best[p.rng.IntN(len(best))]
+}
+
+// ---------- Deck JSON ----------
+
+// deckFile matches decks/deck_*.json in the deckbuilder.
+type deckFile struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Cards       []deckCard `json:"cards"`
+	IsPublic    bool       `json:"isPublic"`
+	ID          string     `json:"id"`
+	CreatedAt   string     `json:"createdAt"`
+	UpdatedAt   string     `json:"updatedAt"`
+	UserID      string     `json:"userId"`
+}
+
+type deckCard struct {
+	CardID   string `json:"cardId"`
+	Quantity int    `json:"quantity"`
+}
+
+// newDeckFile collapses cards into cardId/quantity entries in first-seen
+// order, with cardIds from ids. created stamps createdAt and updatedAt.
+func newDeckFile(id, name, desc string, created time.Time, cards []*catalog.Card, ids deckIDs) deckFile {
+	now := created.UTC().Format("2006-01-02T15:04:05.000Z")
+	d := deckFile{Name: name, Description: desc, ID: id, CreatedAt: now, UpdatedAt: now, UserID: "anonymous", Cards: []deckCard{}}
+	pos := map[string]int{}
+	for _, c := range cards {
+		id := ids.cardID(c)
+		if i, ok := pos[id]; ok {
+			d.Cards[i].Quantity++
+			continue
+		}
+		pos[id] = len(d.Cards)
+		d.Cards = append(d.Cards, deckCard{CardID: id, Quantity: 1})
+	}
+	return d
+}
+
+// defaultCreated is the -created default: a fixed time, so the same input
+// always writes the same bytes.
+const defaultCreated = "2000-01-01T00:00:00Z"
+
+func parseCreated(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad -created: %w", err)
+	}
+	return t, nil
+}
+
+// warnMissingIDs reports the cards a deck file names by catalog ID because
+// the card table has no row for them; the deckbuilder cannot load those.
+func warnMissingIDs(table cardTable, ids deckIDs, cards []*catalog.Card) {
+	if table == nil {
+		return
+	}
+	if m := ids.missing(cards); len(m) > 0 {
+		fmt.Fprintf(os.Stderr, "[WARN] not in the card table, written by catalog ID: %s\n", strings.Join(m, ", "))
+	}
+}
+
+func writeJSONFile(path string, v any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// ---------- Command ----------
+
+func runBooster(args []string) error {
+	var root, setCode, mode, outDir, collPath, createdArg, cardsPath string
+	var seed uint64
+	var packs, players int
+	fs := newFlagSet("booster", &root)
+	fs.StringVar(&setCode, "set", "", "Set tag to open, e.g. CLOP (required)")
+	fs.StringVar(&mode, "mode", "pack", "pack, sealed or draft")
+	fs.IntVar(&packs, "packs", 1, "Packs per player (pack/sealed) or rounds of pick-and-pass (draft)")
+	fs.IntVar(&players, "players", 1, "Players (sealed) or seats in the pod (draft)")
+	fs.Uint64Var(&seed, "seed", 1, "Random seed; the same seed opens the same packs")
+	fs.StringVar(&collPath, "collation", "", `JSON collation keyed by set tag, e.g. {"PSOP":{"slots":[...],"inserts":[...]}}`)
+	fs.StringVar(&outDir, "out", "", "Write deck JSON files here (sealed/draft)")
+	fs.StringVar(&createdArg, "created", defaultCreated, "createdAt/updatedAt of the deck files (RFC 3339); fixed so a seed always writes the same bytes")
+	fs.StringVar(&cardsPath, "cards", "", "Deckbuilder card table, to write the deckbuilder's cardIds (see collection -cards)")
+	fs.Parse(args)
+
+	created, err := parseCreated(createdArg)
+	if err != nil {
+		return err
+	}
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	table, err := loadCardTable(cardsPath)
+	if err != nil {
+		return err
+	}
+	cardIDs := newDeckIDs(cat, table)
+	if table == nil && mode != "pack" {
+		fmt.Fprintln(os.Stderr, "[WARN] no -cards table: deck files use catalog IDs, which the deckbuilder cannot load")
+	}
+	s, ok := cat.Set(setCode)
+	if !ok {
+		return fmt.Errorf("unknown -set %q", setCode)
+	}
+	cols, err := loadCollations(collPath)
+	if err != nil {
+		return err
+	}
+	col, ok := cols[s.Code]
+	if !ok {
+		col = cols["default"]
+	}
+	p, err := newPacker(s, col, rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)))
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case "pack":
+		for i := 0; i < packs; i++ {
+			fmt.Printf("Pack %d (%s, seed %d)\n", i+1, s.Code, seed)
+			for _, c := range p.open() {
+				fmt.Printf("  %-10s %-12s %s\n", c.Rarity(), c.Printing(), c.Name)
+			}
+		}
+		return nil
+
+	case "sealed":
+		for seat := 1; seat <= players; seat++ {
+			var pool []*catalog.Card
+			for i := 0; i < packs; i++ {
+				pool = append(pool, p.open()...)
+			}
+			id := fmt.Sprintf("sealed_%s_%d_%d", strings.ToLower(s.Code), seed, seat)
+			d := newDeckFile(id, fmt.Sprintf("%s sealed pool %d", s.Name, seat),
+				fmt.Sprintf("%d packs of %s, seed %d", packs, s.Code, seed), created, pool, cardIDs)
+			warnMissingIDs(table, cardIDs, pool)
+			if err := emitDeck(outDir, d); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "draft":
+		type seatLog struct {
+			Seat  int        `json:"seat"`
+			Packs [][]string `json:"packs"` // as opened, by round
+			Picks [][]string `json:"picks"` // in pick order, by round
+		}
+		ids := func(cards []*catalog.Card) []string {
+			out := make([]string, len(cards))
+			for i, c := range cards {
+				out[i] = c.ID
+			}
+			return out
+		}
+		pod := struct {
+			Set   string    `json:"set"`
+			Seed  uint64    `json:"seed"`
+			Seats []seatLog `json:"seats"`
+		}{Set: s.Code, Seed: seed}
+		seats := p.draft(players, packs)
+		for i, st := range seats {
+			l := seatLog{Seat: i + 1}
+			for r := range st.Opened {
+				l.Packs = append(l.Packs, ids(st.Opened[r]))
+				l.Picks = append(l.Picks, ids(st.Picks[r]))
+			}
+			pod.Seats = append(pod.Seats, l)
+		}
+		if outDir == "" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(pod)
+		}
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return err
+		}
+		podPath := filepath.Join(outDir, fmt.Sprintf("draft_%s_%d.json", strings.ToLower(s.Code), seed))
+		if err := writeJSONFile(podPath, pod); err != nil {
+			return err
+		}
+		fmt.Printf("[OK ] pod -> %s\n", podPath)
+		for i, st := range seats {
+			id := fmt.Sprintf("draft_%s_%d_%d", strings.ToLower(s.Code), seed, i+1)
+			d := newDeckFile(id, fmt.Sprintf("%s draft seat %d", s.Name, i+1),
+				fmt.Sprintf("Picks of seat %d over %d rounds of %s, seed %d", i+1, packs, s.Code, seed), created, st.pool(), cardIDs)
+			warnMissingIDs(table, cardIDs, st.pool())
+			if err := emitDeck(outDir, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown -mode %q", mode)
+}
+
+// emitDeck writes d to dir/<id>.json, or to stdout when dir is empty.
+func emitDeck(dir string, d deckFile) error {
+	if dir == "" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, d.ID+".json")
+	if err := writeJSONFile(path, d); err != nil {
+		return err
+	}
+	fmt.Printf("[OK ] %s -> %s\n", d.Name, path)
+	return nil
+}
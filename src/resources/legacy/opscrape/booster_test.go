@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"opscrape/catalog"
+)
+
+func TestBoosterSameSeedSameBytes(t *testing.T) {
+	var runs [2]map[string][]byte
+	for i := range runs {
+		dir := t.TempDir()
+		for _, mode := range []string{"sealed", "draft"} {
+			err := runBooster([]string{"-root", filepath.Join("testdata", "legacy"), "-set", "DCOP",
+				"-mode", mode, "-players", "2", "-packs", "2", "-seed", "42", "-out", dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		runs[i] = readTree(t, dir)
+	}
+	if len(runs[0]) != 5 { // 2 sealed decks, the pod and 2 draft decks
+		t.Fatalf("wrote %d files", len(runs[0]))
+	}
+	for name, data := range runs[0] {
+		if !bytes.Equal(data, runs[1][name]) {
+			t.Errorf("%s differs between runs with the same seed", name)
+		}
+	}
+}
+
+func TestInsertsTakeDistinctSlots(t *testing.T) {
+	col := collation{
+		Slots:   []packSlot{{Count: 3, Rarity: map[string]int{"Common": 1}}, {Count: 1, Rarity: map[string]int{"Rare": 1}}},
+		Inserts: []packInsert{{Printing: "Silver", Odds: 1}, {Printing: "Silver", Odds: 1}, {Printing: "Silver", Odds: 1}, {Printing: "Silver", Odds: 1}},
+	}
+	p, err := newPacker(testCatalog(t).Sets[0], col, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := p.open()
+	if len(pack) != 4 {
+		t.Fatalf("pack has %d cards", len(pack))
+	}
+	// Three of four inserts fit the three-card first slot; the Rare slot is left alone.
+	for i, c := range pack {
+		if silver := c.Printing() == "Silver"; silver != (i < 3) {
+			t.Errorf("pack[%d] = %s (%s)", i, c.ID, c.Printing())
+		}
+	}
+}
+
+func TestDraftPicksAndPasses(t *testing.T) {
+	col := collation{Slots: []packSlot{{Count: 4, Rarity: map[string]int{"Common": 2, "Uncommon": 1, "Rare": 1}}}}
+	p, err := newPacker(testCatalog(t).Sets[0], col, rand.New(rand.NewPCG(7, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const seats, rounds = 3, 2
+	pod := p.draft(seats, rounds)
+
+	count := map[*catalog.Card]int{}
+	for i, st := range pod {
+		if len(st.Opened) != rounds || len(st.pool()) != rounds*4 {
+			t.Fatalf("seat %d opened %d packs and picked %d cards", i+1, len(st.Opened), len(st.pool()))
+		}
+		for r := 0; r < rounds; r++ {
+			for _, c := range st.Opened[r] {
+				count[c]++
+			}
+			for _, c := range st.Picks[r] {
+				count[c]--
+			}
+			// The first pick is the rarest card of the seat's own pack.
+			first := catalog.RarityRank(st.Picks[r][0].Rarity())
+			for _, c := range st.Opened[r] {
+				if catalog.RarityRank(c.Rarity()) > first {
+					t.Errorf("seat %d round %d took %s over %s", i+1, r+1, st.Picks[r][0].ID, c.ID)
+				}
+			}
+		}
+	}
+	for c, n := range count {
+		if n != 0 {
+			t.Errorf("%s: %d more opened than picked", c.ID, n)
+		}
+	}
+
+	// The second pick comes from the neighbour's pack: from the seat before
+	// in round 1 (passed left), from the seat after in round 2.
+	from := func(c *catalog.Card, pack []*catalog.Card) bool {
+		for _, x := range pack {
+			if x == c {
+				return true
+			}
+		}
+		return false
+	}
+	for i, st := range pod {
+		prev, next := pod[(i+seats-1)%seats], pod[(i+1)%seats]
+		if !from(st.Picks[0][1], prev.Opened[0]) {
+			t.Errorf("seat %d round 1 second pick %s is not from seat %d's pack", i+1, st.Picks[0][1].ID, (i+seats-1)%seats+1)
+		}
+		if !from(st.Picks[1][1], next.Opened[1]) {
+			t.Errorf("seat %d round 2 second pick %s is not from seat %d's pack", i+1, st.Picks[1][1].ID, (i+1)%seats+1)
+		}
+	}
+}
+
+func TestDeckIDsFromCardTable(t *testing.T) {
+	cat := testCatalog(t)
+	table, err := loadCardTable(filepath.Join("testdata", "deckbuilder_cards.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := newDeckIDs(cat, table)
+	want := map[string]string{
+		"Batman_(DCOP)":               "6f1c0f7e-8a43-4c55-9a53-0d7b0b1f2a01",
+		"Batman_-_Bat-Grapple_(DCOP)": "2b9de0a4-52f3-4f1b-8f0e-7d6c3b1e4a02",
+		"3_Energy_(DCOP)":             "card_12", // over its collection UUID
+		"Flash_(DCOP)":                "91e3d5c7-6b2a-4e8f-b0d1-2c4e6a8b0a04",
+		"Flash_(DCOP)#silver":         "Flash_(DCOP)#silver", // no row for the printing
+	}
+	for id, deckID := range want {
+		c, _ := cat.Card(id)
+		if got := ids.cardID(c); got != deckID {
+			t.Errorf("%s: cardId %q, want %q", id, got, deckID)
+		}
+	}
+}
+
+func TestSealedDeckUsesDeckbuilderIDs(t *testing.T) {
+	dir := t.TempDir()
+	cards := filepath.Join("testdata", "deckbuilder_cards.csv")
+	err := runBooster([]string{"-root", filepath.Join("testdata", "legacy"), "-set", "DCOP",
+		"-mode", "sealed", "-packs", "2", "-seed", "42", "-cards", cards, "-out", dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "sealed_dcop_42_1.json")
+	d, err := readDeckFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := testCatalog(t)
+	table, err := loadCardTable(cards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := newDeckIDs(cat, table)
+	for _, dc := range d.Cards {
+		if _, ok := table[dc.CardID]; ok {
+			continue
+		}
+		// A catalog ID is only kept for a card the table lacks.
+		if c, ok := cat.Card(dc.CardID); !ok || ids[c] != "" {
+			t.Errorf("cardId %q: want the deckbuilder's ID", dc.CardID)
+		}
+	}
+	_, items, err := readDeckCards(cat, table, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, it := range items {
+		n += it.qty
+	}
+	if n != 28 {
+		t.Errorf("deck reads back as %d cards, want two 14-card packs", n)
+	}
+}
+
+// readTree returns every file under dir by its relative path.
+func readTree(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	out := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(dir, path)
+		out[filepath.ToSlash(rel)] = data
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
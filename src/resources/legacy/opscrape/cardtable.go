@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+)
+
+// ---------- Deckbuilder card table ----------
+
+// tableCard is one row of the deckbuilder's card tables (characters,
+// special_cards, power_cards, ...). collection_cards.card_id and a deck's
+// cardId point at these rows, not at catalog IDs.
+type tableCard struct {
+	Name      string
+	Type      string // card_type as the deckbuilder spells it: character, special, ...
+	Character string // special_cards.character_name
+}
+
+// cardTable maps deckbuilder card IDs to their rows. Export it from the
+// database as CSV or a JSON array with id, name, card_type and, for
+// specials, character_name:
+//
+//	SELECT id, name, 'character' AS card_type, '' AS character_name FROM characters
+//	UNION ALL SELECT id, name, 'special', character_name FROM special_cards
+//	UNION ALL ...
+type cardTable map[string]tableCard
+
+var cardTableColumns = map[string]string{
+	"id": "id", "card_id": "id", "cardid": "id",
+	"name": "name", "card_name": "name",
+	"card_type": "type", "type": "type",
+	"character_name": "character", "character": "character",
+}
+
+// loadCardTable reads a card table export; an empty path gives a nil table.
+func loadCardTable(path string) (cardTable, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		rows, err = readTableJSON(f)
+	} else {
+		rows, err = readTableCSV(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t := cardTable{}
+	for i, row := range rows {
+		if row["id"] == "" || row["name"] == "" {
+			return nil, fmt.Errorf("%s: row %d needs an id and a name", path, i+1)
+		}
+		t[row["id"]] = tableCard{Name: row["name"], Type: row["type"], Character: row["character"]}
+	}
+	return t, nil
+}
+
+func readTableCSV(r io.Reader) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	var out []map[string]string
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, h := range header {
+			if k := cardTableColumns[strings.ToLower(strings.TrimSpace(h))]; k != "" && i < len(rec) {
+				row[k] = strings.TrimSpace(rec[i])
+			}
+		}
+		out = append(out, row)
+	}
+}
+
+func readTableJSON(r io.Reader) ([]map[string]string, error) {
+	var raw []map[string]any
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	out := make([]map[string]string, 0, len(raw))
+	for _, rec := range raw {
+		row := map[string]string{}
+		for k, v := range rec {
+			if k = cardTableColumns[strings.ToLower(k)]; k != "" && v != nil {
+				row[k] = strings.TrimSpace(fmt.Sprint(v))
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isUUID reports whether id has the shape of a collection_cards card_id.
+func isUUID(id string) bool { return uuidPattern.MatchString(id) }
+
+// name is the row's card as the catalog names it: specials are
+// "Character - Special".
+func (tc tableCard) name() string {
+	if tc.Character != "" && !strings.HasPrefix(tc.Name, tc.Character+" - ") {
+		return tc.Character + " - " + tc.Name
+	}
+	return tc.Name
+}
+
+// deckIDs maps catalog cards to the card table IDs a deckbuilder deck's
+// cardId holds: the collection join run backwards, each row matched as
+// newNameIndex matches it.
+type deckIDs map[*catalog.Card]string
+
+// newDeckIDs joins every row of table to the catalog. When several rows
+// match one card, a deck-style ID ("card_12") wins over a collection UUID,
+// then the first in order. A nil table gives no IDs.
+func newDeckIDs(cat *catalog.Catalog, table cardTable) deckIDs {
+	ids := make([]string, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if a, b := isUUID(ids[i]), isUUID(ids[j]); a != b {
+			return b
+		}
+		return ids[i] < ids[j]
+	})
+	idx := newNameIndex(cat, table)
+	out := deckIDs{}
+	for _, id := range ids {
+		if c := idx.match(ownedLine{CardID: id}); c != nil {
+			if _, ok := out[c]; !ok {
+				out[c] = id
+			}
+		}
+	}
+	return out
+}
+
+// cardID is c's deckbuilder ID, or its catalog ID when the table has no row
+// for it; opscrape reads both back.
+func (d deckIDs) cardID(c *catalog.Card) string {
+	if id, ok := d[c]; ok {
+		return id
+	}
+	return c.ID
+}
+
+// missing lists, once each and sorted, the catalog IDs of cards with no
+// deckbuilder ID.
+func (d deckIDs) missing(cards []*catalog.Card) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, c := range cards {
+		if _, ok := d[c]; !ok && !seen[c.ID] {
+			seen[c.ID] = true
+			out = append(out, c.ID)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,296 @@
+// Package catalog loads the manifest.csv files written by the per-set
+// scrapers into a single typed card catalog.
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scraper/setcode"
+)
+
+// ---------- Types ----------
+
+// Card is one manifest row. KV and OrderedKeys hold the wiki's Statistics
+// table exactly as the scraper recorded it.
+type Card struct {
+	ID          string // wiki page title, e.g. "Adam_Warlock_-_The_Infinity_Watch_(CLOP)"; later printings add "#silver"
+	Name        string
+	ImageName   string
+	PageURL     string
+	SetCode     string
+	ControlCode string
+	SetMismatch bool
+
+	KV          map[string]string
+	OrderedKeys []string
+
+	Set *Set
+}
+
+// Set is one scraper directory and the cards its manifest lists.
+type Set struct {
+	Code     string // majority set tag of the cards, e.g. "CLOP"
+	Name     string
+	Dir      string
+	ImageDir string // "" when no images were downloaded
+	Cards    []*Card
+}
+
+// Catalog is every set found under a legacy root directory.
+type Catalog struct {
+	Root  string
+	Sets  []*Set
+	Cards []*Card
+
+	byID map[string]*Card
+}
+
+// manifest columns written by the scraper itself rather than taken from the
+// wiki's Statistics table.
+var fixedColumns = map[string]bool{
+	"Name": true, "ImageName": true, "ImageURL": true, "PageURL": true,
+	"SetCode": true, "ControlCode": true, "ControlOrder": true, "SetMismatch": true,
+}
+
+// ---------- Loading ----------
+
+// Load reads every <root>/<set>/manifest.csv.
+func Load(root string) (*Catalog, error) {
+	paths, err := filepath.Glob(filepath.Join(root, "*", "manifest.csv"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no */manifest.csv under %s", root)
+	}
+	sort.Strings(paths)
+
+	c := &Catalog{Root: root, byID: map[string]*Card{}}
+	for _, p := range paths {
+		s, err := LoadSet(filepath.Dir(p))
+		if err != nil {
+			return nil, err
+		}
+		c.Sets = append(c.Sets, s)
+		for _, card := range s.Cards {
+			c.Cards = append(c.Cards, card)
+			// Promos linked from expansion pages are scraped twice; the
+			// copy from the card's own set wins.
+			if prev, dup := c.byID[card.ID]; !dup || (!prev.IsOwn() && card.IsOwn()) {
+				c.byID[card.ID] = card
+			}
+		}
+	}
+	return c, nil
+}
+
+// LoadSet reads dir/manifest.csv.
+func LoadSet(dir string) (*Set, error) {
+	path := filepath.Join(dir, "manifest.csv")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cards, err := ReadManifest(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	s := &Set{Dir: dir, Cards: cards}
+	for _, name := range []string{"images", "mission-control-images"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && fi.IsDir() {
+			s.ImageDir = filepath.Join(dir, name)
+			break
+		}
+	}
+
+	counts := map[string]int{}
+	for _, card := range cards {
+		card.Set = s
+		if card.SetCode != "" && card.SetCode != "P" {
+			counts[card.SetCode]++
+		}
+	}
+	for code, n := range counts {
+		if n > counts[s.Code] || (n == counts[s.Code] && code < s.Code) {
+			s.Code = code
+		}
+	}
+	if s.Code == "" {
+		s.Code = "P" // the promo scrape only has promo tags
+	}
+	s.Name = setcode.Name(s.Code)
+	return s, nil
+}
+
+// ReadManifest parses a scraper manifest. Manifests written before set codes
+// were recorded get them derived from PageURL and ImageName.
+func ReadManifest(r io.Reader) ([]*Card, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var cards []*Card
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		card := &Card{KV: map[string]string{}}
+		for i, h := range header {
+			if i >= len(row) {
+				break
+			}
+			v := row[i]
+			switch h {
+			case "Name":
+				card.Name = v
+			case "ImageName":
+				card.ImageName = v
+			case "PageURL":
+				card.PageURL = v
+			case "SetCode":
+				card.SetCode = v
+			case "ControlCode":
+				card.ControlCode = v
+			case "SetMismatch":
+				card.SetMismatch = v == "true"
+			}
+			if !fixedColumns[h] && v != "" {
+				card.KV[h] = v
+			}
+		}
+		for _, h := range header {
+			if _, ok := card.KV[h]; ok {
+				card.OrderedKeys = append(card.OrderedKeys, h)
+			}
+		}
+		if card.SetCode == "" {
+			card.SetCode = setcode.FromPageTitle(PageTitle(card.PageURL))
+		}
+		if card.SetCode == "" {
+			card.SetCode = setcode.FromImageName(card.ImageName)
+		}
+		if card.ControlCode == "" {
+			card.ControlCode = setcode.NormalizeControl(card.KV["Control"])
+		}
+		card.ID = PageTitle(card.PageURL)
+		if card.ID == "" {
+			card.ID = card.Name
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// ---------- Lookup ----------
+
+// Card returns the card with the given ID.
+func (c *Catalog) Card(id string) (*Card, bool) {
+	card, ok := c.byID[id]
+	return card, ok
+}
+
+// Set returns the set with the given code (case-insensitive).
+func (c *Catalog) Set(code string) (*Set, bool) {
+	for _, s := range c.Sets {
+		if strings.EqualFold(s.Code, code) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// OwnCards returns the set's cards minus those tagged for another set, such
+// as promos linked from the expansion index page.
+func (s *Set) OwnCards() []*Card {
+	var out []*Card
+	for _, c := range s.Cards {
+		if c.IsOwn() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// IsOwn reports whether the card belongs to the set it was scraped with.
+func (c *Card) IsOwn() bool {
+	return c.Set == nil || c.SetCode == "" || c.SetCode == c.Set.Code
+}
+
+// ---------- Card accessors ----------
+
+func (c *Card) Type() string     { return c.KV["Type"] }
+func (c *Card) GameText() string { return c.KV["Game Text"] }
+func (c *Card) Numbers() string  { return c.KV["Numbers"] }
+
+// Rarity is the canonical rarity; see NormalizeRarity.
+func (c *Card) Rarity() string { return NormalizeRarity(c.KV["Rarity"]) }
+
+// Printing defaults to "Normal" like the wiki does.
+func (c *Card) Printing() string {
+	if p := strings.TrimSpace(c.KV["Printing"]); p != "" {
+		return p
+	}
+	return "Normal"
+}
+
+// Characters splits the comma-separated Characters field.
+func (c *Card) Characters() []string {
+	var out []string
+	for _, s := range strings.Split(c.KV["Characters"], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ImagePath is the downloaded image on disk, or "" when it is missing.
+func (c *Card) ImagePath() string {
+	if c.Set == nil || c.Set.ImageDir == "" || c.ImageName == "" {
+		return ""
+	}
+	p := filepath.Join(c.Set.ImageDir, c.ImageName)
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+// PageTitle returns the card ID for a page URL: the unescaped last path
+// segment, plus the "#<printing>" fragment the scraper gives a page's later
+// printings, e.g. "Flash_(DCOP)#silver". It must agree with the scraper's
+// pageTitle, which overrides are matched against.
+func PageTitle(pageURL string) string {
+	p, frag := pageURL, ""
+	if u, err := url.Parse(pageURL); err == nil {
+		p, frag = u.Path, u.Fragment
+	}
+	if dec, err := url.PathUnescape(p); err == nil {
+		p = dec
+	}
+	title := p[strings.LastIndex(p, "/")+1:]
+	if frag != "" {
+		title += "#" + frag
+	}
+	return title
+}
@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeManifest(t *testing.T, root, set, body string) {
+	t.Helper()
+	dir := filepath.Join(root, set)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.csv"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeepsEveryPrinting(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "dcop", `Name,ImageName,PageURL,SetCode,Printing,Rarity,Type
+Flash,Flash-DCOP.jpg,https://cardguide.fandom.com/wiki/Flash_(DCOP),DCOP,Normal,Rare,Character
+Flash,FlashSilver-DCOP.jpg,https://cardguide.fandom.com/wiki/Flash_(DCOP)#silver,DCOP,Silver,Rare,Character
+Superman,Superman-P.jpg,https://cardguide.fandom.com/wiki/Superman_(P),P,Normal,Promo,Character
+`)
+	writeManifest(t, root, "promosop", `Name,ImageName,PageURL,SetCode,Printing,Rarity,Type
+Superman,Superman-P.jpg,https://cardguide.fandom.com/wiki/Superman_(P),P,Normal,Promo,Character
+`)
+	cat, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normal, ok1 := cat.Card("Flash_(DCOP)")
+	silver, ok2 := cat.Card("Flash_(DCOP)#silver")
+	if !ok1 || !ok2 || normal == silver {
+		t.Fatalf("printings = %v %v; each needs its own ID", normal, silver)
+	}
+	if normal.Field("Printing") != "Normal" || silver.Field("Printing") != "Silver" || silver.ImageName != "FlashSilver-DCOP.jpg" {
+		t.Errorf("normal = %+v, silver = %+v", normal, silver)
+	}
+	if silver.SetCode != "DCOP" || silver.Set.Code != "DCOP" {
+		t.Errorf("silver set = %q / %q", silver.SetCode, silver.Set.Code)
+	}
+	// The promo linked from the DC page is scraped twice; the promo set's copy wins.
+	if s, ok := cat.Card("Superman_(P)"); !ok || s.Set.Code != "P" {
+		t.Errorf("Superman_(P) = %+v", s)
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://cardguide.fandom.com/wiki/Flash_(DCOP)":                      "Flash_(DCOP)",
+		"https://cardguide.fandom.com/wiki/Flash_(DCOP)#silver":               "Flash_(DCOP)#silver",
+		"https://cardguide.fandom.com/wiki/Asteroid_%22M%22_-_Hideout_(XMOP)": `Asteroid_"M"_-_Hideout_(XMOP)`,
+	} {
+		if got := PageTitle(raw); got != want {
+			t.Errorf("PageTitle(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
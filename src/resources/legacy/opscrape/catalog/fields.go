@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"strings"
+
+	"scraper/setcode"
+)
+
+// ---------- Fields ----------
+
+// Fields is the canonical field vocabulary. The manifests spell the same
+// Statistics row several ways ("Character List", "TRIVIA", "Illustrators");
+// Field folds those onto these names.
+var Fields = []string{
+	"ID", "Name", "Set", "Type", "Subtype", "Characters", "Rarity", "Printing",
+	"Control", "Game Text", "Flavor Text", "Numbers", "Traits", "Trivia", "Illustrator",
+}
+
+// fieldAliases maps lower-cased spellings, including short query names,
+// onto Fields.
+var fieldAliases = map[string]string{
+	"id": "ID", "page": "ID",
+	"name": "Name", "n": "Name",
+	"set": "Set", "setcode": "Set", "s": "Set",
+	"type": "Type", "t": "Type",
+	"subtype":    "Subtype",
+	"characters": "Characters", "character": "Characters", "character list": "Characters", "char": "Characters", "c": "Characters",
+	"rarity": "Rarity", "r": "Rarity",
+	"printing": "Printing", "print": "Printing",
+	"control": "Control", "controlcode": "Control",
+	"game text": "Game Text", "gametext": "Game Text", "text": "Game Text", "o": "Game Text",
+	"flavor text": "Flavor Text", "flavortext": "Flavor Text", "flavor": "Flavor Text", "ft": "Flavor Text",
+	"numbers": "Numbers", "grid": "Numbers",
+	"traits": "Traits", "trait": "Traits",
+	"trivia":      "Trivia",
+	"illustrator": "Illustrator", "illustrators": "Illustrator", "artist": "Illustrator",
+}
+
+// CanonicalField returns the Fields name for a manifest column or query
+// alias, case-insensitively.
+func CanonicalField(name string) (string, bool) {
+	f, ok := fieldAliases[strings.ToLower(strings.TrimSpace(name))]
+	return f, ok
+}
+
+// Field returns a card's value for a canonical field name. Multi-valued
+// fields are joined with ", ".
+func (c *Card) Field(name string) string {
+	switch name {
+	case "ID":
+		return c.ID
+	case "Name":
+		return c.Name
+	case "Set":
+		if c.SetCode != "" {
+			return c.SetCode
+		}
+		if c.Set != nil {
+			return c.Set.Code
+		}
+		return ""
+	case "Rarity":
+		return c.Rarity()
+	case "Printing":
+		return c.Printing()
+	case "Control":
+		if c.ControlCode != "" {
+			return c.ControlCode
+		}
+		return setcode.NormalizeControl(c.KV["Control"])
+	case "Characters":
+		seen := map[string]bool{}
+		var out []string
+		for _, k := range []string{"Characters", "Character", "Character List"} {
+			for _, s := range strings.Split(c.KV[k], ",") {
+				if s = strings.TrimSpace(s); s != "" && !seen[s] {
+					seen[s] = true
+					out = append(out, s)
+				}
+			}
+		}
+		return strings.Join(out, ", ")
+	}
+	// Statistics rows: take the first manifest column that folds onto name.
+	for _, k := range c.OrderedKeys {
+		if f, ok := CanonicalField(k); ok && f == name && c.KV[k] != "" {
+			return c.KV[k]
+		}
+	}
+	return c.KV[name]
+}
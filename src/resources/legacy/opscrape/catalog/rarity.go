@@ -0,0 +1,47 @@
+package catalog
+
+import "strings"
+
+// ---------- Rarity ----------
+
+// Rarities is the canonical rarity vocabulary, most common first.
+var Rarities = []string{"Common", "Uncommon", "Rare", "Very Rare", "Promo", "Insert", "Silver"}
+
+// NormalizeRarity folds the wiki's spellings ("Commoon", "Uncommo",
+// "Rare (Misprint)") onto Rarities. Unknown values are returned trimmed.
+func NormalizeRarity(raw string) string {
+	s := strings.TrimSpace(raw)
+	if i := strings.Index(s, "("); i > 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	l := strings.ToLower(s)
+	switch {
+	case l == "":
+		return ""
+	case strings.HasPrefix(l, "very"), l == "vr":
+		return "Very Rare"
+	case strings.HasPrefix(l, "uncom"), l == "u":
+		return "Uncommon"
+	case strings.HasPrefix(l, "com"), l == "c":
+		return "Common"
+	case strings.HasPrefix(l, "rare"), l == "r":
+		return "Rare"
+	case strings.HasPrefix(l, "promo"), l == "p":
+		return "Promo"
+	case strings.HasPrefix(l, "insert"):
+		return "Insert"
+	case strings.HasPrefix(l, "silver"):
+		return "Silver"
+	}
+	return s
+}
+
+// RarityRank orders rarities by Rarities; unknown values sort last.
+func RarityRank(r string) int {
+	for i, v := range Rarities {
+		if v == r {
+			return i
+		}
+	}
+	return len(Rarities)
+}
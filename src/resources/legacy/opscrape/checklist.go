@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"opscrape/catalog"
+	"scraper/setcode"
+)
+
+// ---------- Checklist ----------
+
+// checklistHeader is the header of data/checklist_erb_world_legends.csv,
+// trailing spaces included, so the sheets line up with the existing export.
+var checklistHeader = []string{"Set ", "#", "Card Name", "Card Special", "Rarity ", "Location"}
+
+var checklistColumns = `"Set", "#", "Card Name", "Card Special", "Rarity", "Location"`
+
+type checklistRow struct {
+	Set, Num, CardName, CardSpecial, Rarity, Location string
+}
+
+func (r checklistRow) cells() []string {
+	return []string{r.Set, r.Num, r.CardName, r.CardSpecial, r.Rarity, r.Location}
+}
+
+func runChecklist(args []string) error {
+	var root, dataDir, migDir, only string
+	var version int
+	fs := newFlagSet("checklist", &root)
+	fs.StringVar(&dataDir, "data", "checklists", "Directory for checklist_<set>.csv files")
+	fs.StringVar(&migDir, "migrations", "", "Flyway migrations directory; empty skips the SQL")
+	fs.IntVar(&version, "version", 0, "First migration version (default: one past the highest in -migrations)")
+	fs.StringVar(&only, "set", "", "Only this set tag, e.g. CLOP")
+	fs.Parse(args)
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	if migDir != "" && version == 0 {
+		if version, err = nextMigrationVersion(migDir); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, s := range cat.Sets {
+		if only != "" && !strings.EqualFold(only, s.Code) {
+			continue
+		}
+		rows := buildChecklist(s)
+		table := "checklist_" + strings.ToLower(s.Code)
+
+		csvPath := filepath.Join(dataDir, table+".csv")
+		if err := writeChecklistCSV(csvPath, rows); err != nil {
+			return err
+		}
+		fmt.Printf("[OK ] %s: %d rows -> %s\n", s.Code, len(rows), csvPath)
+
+		if migDir == "" {
+			continue
+		}
+		create := filepath.Join(migDir, fmt.Sprintf("V%d__Create_%s_table.sql", version, table))
+		populate := filepath.Join(migDir, fmt.Sprintf("V%d__Populate_%s.sql", version+1, table))
+		if err := writeChecklistCreate(create, table, s); err != nil {
+			return err
+		}
+		if err := writeChecklistPopulate(populate, table, s, rows); err != nil {
+			return err
+		}
+		fmt.Printf("[OK ] %s: %s, %s\n", s.Code, filepath.Base(create), filepath.Base(populate))
+		version += 2
+	}
+	return nil
+}
+
+// buildChecklist lists the set's own cards (promos leaking in from the index
+// page are left to the promo checklist) in collector order: Control-coded
+// cards by code, then the rest by type and name.
+func buildChecklist(s *catalog.Set) []checklistRow {
+	cards := s.OwnCards()
+	sortCollectorOrder(cards)
+
+	rows := make([]checklistRow, 0, len(cards))
+	for i, c := range cards {
+		name, special := splitCardName(c)
+		rows = append(rows, checklistRow{
+			Set:         s.Code,
+			Num:         fmt.Sprintf("%03d", i+1),
+			CardName:    name,
+			CardSpecial: special,
+			Rarity:      c.Rarity(),
+			Location:    cardLocation(c),
+		})
+	}
+	return rows
+}
+
+// typeOrder is the order uncoded cards appear in after the Control-coded run.
+var typeOrder = []string{"Character", "Special", "Aspect", "Power", "Universe", "Tactic", "Location", "Event", "Mission"}
+
+func typeRank(t string) int {
+	for i, v := range typeOrder {
+		if strings.EqualFold(v, t) {
+			return i
+		}
+	}
+	return len(typeOrder)
+}
+
+func sortCollectorOrder(cards []*catalog.Card) {
+	sort.SliceStable(cards, func(i, j int) bool {
+		oi, iok := setcode.ControlOrdinal(cards[i].ControlCode)
+		oj, jok := setcode.ControlOrdinal(cards[j].ControlCode)
+		switch {
+		case iok && jok && oi != oj:
+			return oi < oj
+		case iok != jok:
+			return iok
+		}
+		if ti, tj := typeRank(cards[i].Type()), typeRank(cards[j].Type()); ti != tj {
+			return ti < tj
+		}
+		if cards[i].Name != cards[j].Name {
+			return cards[i].Name < cards[j].Name
+		}
+		return cards[i].ID < cards[j].ID
+	})
+}
+
+// splitCardName turns "Character - Special Name" into the checklist's Card
+// Name / Card Special pair. Character cards become "<Name> Character Card"
+// with no special, as in the ERB sheet.
+func splitCardName(c *catalog.Card) (string, string) {
+	name := strings.TrimSpace(c.Name)
+	if strings.EqualFold(c.Type(), "Character") {
+		return name + " Character Card", ""
+	}
+	if i := strings.Index(name, " - "); i > 0 {
+		return strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+3:])
+	}
+	return name, ""
+}
+
+// cardLocation uses the wiki's Distribution field when present; regular
+// rarities came in booster packs.
+func cardLocation(c *catalog.Card) string {
+	if d := strings.TrimSpace(c.KV["Distribution"]); d != "" {
+		return d
+	}
+	switch c.Rarity() {
+	case "Common", "Uncommon", "Rare", "Very Rare":
+		return "Booster Packs"
+	}
+	return ""
+}
+
+// ---------- Output ----------
+
+func writeChecklistCSV(path string, rows []checklistRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.UseCRLF = true // the ERB sheet has CRLF line endings
+	if err := w.Write(checklistHeader); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := w.Write(r.cells()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeChecklistCreate(path, table string, s *catalog.Set) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintf(w, "-- Create %s table\n", table)
+	fmt.Fprintf(w, "-- Mirrors the checklist layout of checklist_erb_world_legends for %s (%s)\n", s.Name, s.Code)
+	fmt.Fprintf(w, "CREATE TABLE %s (\n", table)
+	fmt.Fprintln(w, "    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),")
+	for _, col := range []string{"Set", "#", "Card Name", "Card Special", "Rarity", "Location"} {
+		fmt.Fprintf(w, "    %q TEXT,\n", col)
+	}
+	fmt.Fprintln(w, "    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,")
+	fmt.Fprintln(w, "    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP")
+	fmt.Fprintln(w, ");")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "-- Create trigger for updated_at")
+	fmt.Fprintf(w, "CREATE TRIGGER update_%s_updated_at BEFORE UPDATE ON %s\n", table, table)
+	fmt.Fprintln(w, "    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();")
+	return w.Flush()
+}
+
+func writeChecklistPopulate(path, table string, s *catalog.Set, rows []checklistRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintf(w, "-- Populate %s from the %s checklist\n", table, s.Name)
+	fmt.Fprintf(w, "-- Generated by opscrape checklist from %s\n", filepath.Join(filepath.Base(s.Dir), "manifest.csv"))
+	fmt.Fprintf(w, "-- Total rows: %d\n\n", len(rows))
+	for _, r := range rows {
+		vals := r.cells()
+		for i, v := range vals {
+			vals[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		}
+		fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n", table, checklistColumns, strings.Join(vals, ", "))
+	}
+	return w.Flush()
+}
+
+var migrationVersion = regexp.MustCompile(`^V(\d+)__`)
+
+func nextMigrationVersion(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	max := 0
+	for _, e := range entries {
+		if m := migrationVersion.FindStringSubmatch(e.Name()); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n > max {
+				max = n
+			}
+		}
+	}
+	return max + 1, nil
+}
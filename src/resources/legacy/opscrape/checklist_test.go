@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"opscrape/catalog"
+)
+
+// testCatalog loads testdata/legacy, a small scrape laid out like the
+// legacy directory.
+func testCatalog(t *testing.T) *catalog.Catalog {
+	t.Helper()
+	cat, err := catalog.Load(filepath.Join("testdata", "legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+// TestChecklistGolden checks the CSV against testdata, CRLF endings and
+// trailing-space headers included, as in data/checklist_erb_world_legends.csv.
+func TestChecklistGolden(t *testing.T) {
+	s := testCatalog(t).Sets[0]
+	out := filepath.Join(t.TempDir(), "checklist_dcop.csv")
+	if err := writeChecklistCSV(out, buildChecklist(s)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "checklist_dcop.golden.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("checklist differs from golden:\n got: %q\nwant: %q", got, want)
+	}
+}
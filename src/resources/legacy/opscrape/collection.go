@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/resolve"
+)
+
+// ---------- Owned-card lists ----------
+
+// ownedLine is one row of an imported collection before it is matched.
+type ownedLine struct {
+	Line     int    `json:"line"`
+	CardID   string `json:"card_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Set      string `json:"set,omitempty"`
+	Printing string `json:"printing,omitempty"`
+	CardType string `json:"card_type,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+// Collection is an owned-cards list matched against the catalog.
+type Collection struct {
+	Owned     map[*catalog.Card]int
+	Unmatched []ownedLine
+}
+
+// collectionColumns maps accepted header spellings to ownedLine fields. The
+// collection_cards export uses card_id/card_type/quantity; its id column is
+// the row's own, so a bare id is only used when there is no card_id.
+var collectionColumns = map[string]string{
+	"card_id": "id", "cardid": "id", "id": "rowid",
+	"name": "name", "card": "name", "card_name": "name", "card name": "name",
+	"set": "set", "set_code": "set", "setcode": "set",
+	"printing":  "printing",
+	"card_type": "type", "type": "type",
+	"quantity": "qty", "qty": "qty", "count": "qty", "owned": "qty",
+}
+
+// loadCollection reads a CSV or a JSON array of collection_cards rows and
+// matches every row against the catalog. A collection_cards card_id is a
+// deckbuilder UUID, which only table can turn into a card.
+func loadCollection(path string, cat *catalog.Catalog, table cardTable) (*Collection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []ownedLine
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		lines, err = readCollectionJSON(f)
+	} else {
+		lines, err = readCollectionCSV(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	col := &Collection{Owned: map[*catalog.Card]int{}}
+	idx := newNameIndex(cat, table)
+	for _, l := range lines {
+		if table == nil && l.Name == "" && isUUID(l.CardID) {
+			return nil, fmt.Errorf("%s line %d: card_id %s is a deckbuilder UUID; pass -cards with the deckbuilder card table", path, l.Line, l.CardID)
+		}
+		card := idx.match(l)
+		if card == nil {
+			col.Unmatched = append(col.Unmatched, l)
+			continue
+		}
+		col.Owned[card] += l.Quantity
+	}
+	return col, nil
+}
+
+func readCollectionCSV(r io.Reader) ([]ownedLine, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(header))
+	for i, h := range header {
+		fields[i] = collectionColumns[strings.ToLower(strings.TrimSpace(h))]
+	}
+
+	var out []ownedLine
+	for n := 2; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		l, rowID := ownedLine{Line: n, Quantity: 1}, ""
+		for i, v := range row {
+			if i >= len(fields) {
+				break
+			}
+			v = strings.TrimSpace(v)
+			switch fields[i] {
+			case "id":
+				l.CardID = v
+			case "rowid":
+				rowID = v
+			case "name":
+				l.Name = v
+			case "set":
+				l.Set = v
+			case "printing":
+				l.Printing = v
+			case "type":
+				l.CardType = v
+			case "qty":
+				if q, err := strconv.Atoi(v); err == nil {
+					l.Quantity = q
+				}
+			}
+		}
+		l.CardID = firstNonEmpty(l.CardID, rowID)
+		if l.Name == "" && l.CardID == "" {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+func readCollectionJSON(r io.Reader) ([]ownedLine, error) {
+	var rows []map[string]any
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, err
+	}
+	var out []ownedLine
+	for i, row := range rows {
+		l, rowID := ownedLine{Line: i + 1, Quantity: 1}, ""
+		for k, v := range row {
+			s := strings.TrimSpace(fmt.Sprint(v))
+			switch collectionColumns[strings.ToLower(k)] {
+			case "id":
+				l.CardID = s
+			case "rowid":
+				rowID = s
+			case "name":
+				l.Name = s
+			case "set":
+				l.Set = s
+			case "printing":
+				l.Printing = s
+			case "type":
+				l.CardType = s
+			case "qty":
+				if q, ok := v.(float64); ok {
+					l.Quantity = int(q)
+				} else if q, err := strconv.Atoi(s); err == nil {
+					l.Quantity = q
+				}
+			}
+		}
+		l.CardID = firstNonEmpty(l.CardID, rowID)
+		if l.Name == "" && l.CardID == "" {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// nameIndex matches owned lines by catalog ID, then by normalized name
+// narrowed with the set, printing and card type when given, and finally
+// through the fuzzy resolver when it is confident. An ID found in the
+// deckbuilder card table stands for that row's name and type.
+type nameIndex struct {
+	cat    *catalog.Catalog
+	table  cardTable
+	byName map[string][]*catalog.Card
+	fuzzy  *resolve.Resolver
+}
+
+func newNameIndex(cat *catalog.Catalog, table cardTable) *nameIndex {
+	idx := &nameIndex{cat: cat, table: table, byName: map[string][]*catalog.Card{}, fuzzy: resolve.New(cat)}
+	for _, c := range cat.Cards {
+		k := resolve.Normalize(c.Name)
+		idx.byName[k] = append(idx.byName[k], c)
+	}
+	return idx
+}
+
+func (idx *nameIndex) match(l ownedLine) *catalog.Card {
+	if l.CardID != "" {
+		if c, ok := idx.cat.Card(l.CardID); ok {
+			return c
+		}
+		if tc, ok := idx.table[l.CardID]; ok {
+			l.Name = tc.name()
+			l.CardType = firstNonEmpty(l.CardType, tc.Type)
+		}
+	}
+	if l.Name == "" {
+		return nil
+	}
+	cands := idx.byName[resolve.Normalize(l.Name)]
+	if len(cands) == 0 {
+		res := idx.fuzzy.Resolve(l.Name, resolve.Options{Set: l.Set, Type: l.CardType, Printing: l.Printing})
+		return res.Card
+	}
+	filter := func(keep func(*catalog.Card) bool) {
+		var out []*catalog.Card
+		for _, c := range cands {
+			if keep(c) {
+				out = append(out, c)
+			}
+		}
+		if len(out) > 0 {
+			cands = out
+		}
+	}
+	filter(func(c *catalog.Card) bool { return c.IsOwn() })
+	if l.Set != "" {
+		filter(func(c *catalog.Card) bool {
+			return strings.EqualFold(c.SetCode, l.Set) || strings.EqualFold(c.Set.Code, l.Set)
+		})
+	}
+	if l.CardType != "" {
+		filter(func(c *catalog.Card) bool { return strings.EqualFold(c.Type(), l.CardType) })
+	}
+	filter(func(c *catalog.Card) bool {
+		if l.Printing == "" {
+			return c.Printing() == "Normal"
+		}
+		return strings.EqualFold(c.Printing(), l.Printing)
+	})
+	if len(cands) == 0 {
+		return nil
+	}
+	return cands[0]
+}
+
+// ---------- Completion report ----------
+
+type completion struct {
+	Key   string `json:"key"`
+	Owned int    `json:"owned"`
+	Total int    `json:"total"`
+}
+
+func (c completion) Percent() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return 100 * float64(c.Owned) / float64(c.Total)
+}
+
+type setReport struct {
+	Code       string       `json:"set"`
+	Name       string       `json:"name"`
+	Overall    completion   `json:"overall"`
+	ByRarity   []completion `json:"by_rarity"`
+	ByPrinting []completion `json:"by_printing"`
+	Missing    []cardRef    `json:"missing"`
+	Tradeable  []cardRef    `json:"tradeable"`
+}
+
+type cardRef struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Set      string `json:"set"`
+	Rarity   string `json:"rarity"`
+	Printing string `json:"printing"`
+	Quantity int    `json:"quantity,omitempty"`
+}
+
+func refOf(c *catalog.Card, qty int) cardRef {
+	return cardRef{ID: c.ID, Name: c.Name, Set: c.Set.Code, Rarity: c.Rarity(), Printing: c.Printing(), Quantity: qty}
+}
+
+// buildSetReports tallies completion per set; copies beyond keep are
+// offered for trade.
+func buildSetReports(cat *catalog.Catalog, col *Collection, keep int) []setReport {
+	var out []setReport
+	for _, s := range cat.Sets {
+		rep := setReport{Code: s.Code, Name: s.Name}
+		byRarity := map[string]*completion{}
+		byPrinting := map[string]*completion{}
+		tally := func(m map[string]*completion, k string, owned bool) {
+			c, ok := m[k]
+			if !ok {
+				c = &completion{Key: k}
+				m[k] = c
+			}
+			c.Total++
+			if owned {
+				c.Owned++
+			}
+		}
+		for _, c := range s.OwnCards() {
+			qty := col.Owned[c]
+			owned := qty > 0
+			rep.Overall.Total++
+			if owned {
+				rep.Overall.Owned++
+			} else {
+				rep.Missing = append(rep.Missing, refOf(c, 0))
+			}
+			tally(byRarity, c.Rarity(), owned)
+			tally(byPrinting, c.Printing(), owned)
+			if qty > keep {
+				rep.Tradeable = append(rep.Tradeable, refOf(c, qty-keep))
+			}
+		}
+		rep.Overall.Key = s.Code
+		rep.ByRarity = sortedCompletions(byRarity, func(a, b string) bool {
+			if ra, rb := catalog.RarityRank(a), catalog.RarityRank(b); ra != rb {
+				return ra < rb
+			}
+			return a < b
+		})
+		rep.ByPrinting = sortedCompletions(byPrinting, func(a, b string) bool { return a < b })
+		sortRefs(rep.Missing)
+		sortRefs(rep.Tradeable)
+		out = append(out, rep)
+	}
+	return out
+}
+
+func sortedCompletions(m map[string]*completion, less func(a, b string) bool) []completion {
+	out := make([]completion, 0, len(m))
+	for _, c := range m {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return less(out[i].Key, out[j].Key) })
+	return out
+}
+
+func sortRefs(refs []cardRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Name != refs[j].Name {
+			return refs[i].Name < refs[j].Name
+		}
+		return refs[i].ID < refs[j].ID
+	})
+}
+
+// ---------- Command ----------
+
+func runCollection(args []string) error {
+	var root, in, cardsPath, format, wantPath string
+	var keep int
+	fs := newFlagSet("collection", &root)
+	fs.StringVar(&in, "in", "", "Owned-cards list: CSV (name,set,printing,quantity) or collection_cards JSON (required)")
+	fs.StringVar(&cardsPath, "cards", "", "Deckbuilder card table (id,name,card_type,character_name) for collection_cards UUIDs")
+	fs.StringVar(&format, "format", "text", "Report format: text or json")
+	fs.StringVar(&wantPath, "want", "", "Write a printable Markdown want-list here")
+	fs.IntVar(&keep, "keep", 1, "Copies of each card to keep; the rest are listed for trade")
+	fs.Parse(args)
+
+	if in == "" {
+		return fmt.Errorf("missing -in")
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	table, err := loadCardTable(cardsPath)
+	if err != nil {
+		return err
+	}
+	col, err := loadCollection(in, cat, table)
+	if err != nil {
+		return err
+	}
+	reports := buildSetReports(cat, col, keep)
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"sets": reports, "unmatched": col.Unmatched}); err != nil {
+			return err
+		}
+	case "text":
+		w := bufio.NewWriter(os.Stdout)
+		writeCollectionText(w, reports, col)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown -format %q", format)
+	}
+
+	if wantPath != "" {
+		if err := writeWantList(wantPath, reports); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "[OK ] want-list -> %s\n", wantPath)
+	}
+	return nil
+}
+
+func writeCollectionText(w *bufio.Writer, reports []setReport, col *Collection) {
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s (%s): %d/%d (%.1f%%)\n", r.Name, r.Code, r.Overall.Owned, r.Overall.Total, r.Overall.Percent())
+		for _, c := range r.ByRarity {
+			fmt.Fprintf(w, "  rarity   %-10s %4d/%-4d %5.1f%%\n", c.Key, c.Owned, c.Total, c.Percent())
+		}
+		for _, c := range r.ByPrinting {
+			fmt.Fprintf(w, "  printing %-10s %4d/%-4d %5.1f%%\n", c.Key, c.Owned, c.Total, c.Percent())
+		}
+		if len(r.Tradeable) > 0 {
+			fmt.Fprintf(w, "  for trade:\n")
+			for _, t := range r.Tradeable {
+				fmt.Fprintf(w, "    %dx %s [%s]\n", t.Quantity, t.Name, t.Rarity)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	for _, l := range col.Unmatched {
+		fmt.Fprintf(w, "[WARN] line %d: no catalog match for %q\n", l.Line, firstNonEmpty(l.Name, l.CardID))
+	}
+}
+
+func writeWantList(path string, reports []setReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintln(w, "# OverPower — Want List")
+	fmt.Fprintln(w)
+	for _, r := range reports {
+		if len(r.Missing) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "## %s (%s) — %d missing\n\n", r.Name, r.Code, len(r.Missing))
+		for _, m := range r.Missing {
+			fmt.Fprintf(w, "- [ ] %s — %s", m.Name, m.Rarity)
+			if m.Printing != "Normal" {
+				fmt.Fprintf(w, ", %s", m.Printing)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
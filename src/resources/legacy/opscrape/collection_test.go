@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCollectionCardsExport(t *testing.T) {
+	cat := testCatalog(t)
+	table, err := loadCardTable(filepath.Join("testdata", "deckbuilder_cards.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"Batman_(DCOP)":               2,
+		"Batman_-_Bat-Grapple_(DCOP)": 1,
+		"3_Energy_(DCOP)":             3,
+		"Flash_(DCOP)":                1, // the Normal printing, not #silver
+	}
+	for _, name := range []string{"collection_cards.csv", "collection_cards.json"} {
+		col, err := loadCollection(filepath.Join("testdata", name), cat, table)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got := map[string]int{}
+		for c, n := range col.Owned {
+			got[c.ID] = n
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: owned = %v, want %v", name, got, want)
+		}
+		for id, n := range want {
+			if got[id] != n {
+				t.Errorf("%s: %s = %d, want %d", name, id, got[id], n)
+			}
+		}
+		// The location's UUID is not in the card table.
+		if len(col.Unmatched) != 1 || col.Unmatched[0].CardID != "e0f1a2b3-c4d5-4e6f-8a7b-9c0d1e2f3a05" {
+			t.Errorf("%s: unmatched = %+v", name, col.Unmatched)
+		}
+	}
+}
+
+func TestLoadCollectionNeedsCardTableForUUIDs(t *testing.T) {
+	_, err := loadCollection(filepath.Join("testdata", "collection_cards.csv"), testCatalog(t), nil)
+	if err == nil || !strings.Contains(err.Error(), "-cards") || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, want a line 2 error asking for -cards", err)
+	}
+}
+
+func TestReadCollectionCSVByName(t *testing.T) {
+	lines, err := readCollectionCSV(strings.NewReader("Card Name,Set,Printing,Qty\nFlash,DCOP,Silver,2\n,,,\nBatman,,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0] != (ownedLine{Line: 2, Name: "Flash", Set: "DCOP", Printing: "Silver", Quantity: 2}) ||
+		lines[1] != (ownedLine{Line: 4, Name: "Batman", Quantity: 1}) {
+		t.Errorf("lines = %+v", lines)
+	}
+	idx := newNameIndex(testCatalog(t), nil)
+	if c := idx.match(lines[0]); c == nil || c.ID != "Flash_(DCOP)#silver" {
+		t.Errorf("Flash Silver matched %v", c)
+	}
+}
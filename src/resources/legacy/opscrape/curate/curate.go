@@ -0,0 +1,103 @@
+// Package curate reads and writes the overrides files the per-set scrapers
+// apply after scraping (their -overrides flag), so corrections made here
+// survive the next scrape.
+package curate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"opscrape/catalog"
+)
+
+// DefaultFile is the overrides file kept next to a set's manifest.csv.
+const DefaultFile = "overrides.yaml"
+
+// Entry is one override, in the scrapers' format. Fields with an empty
+// value remove the row.
+type Entry struct {
+	Match    string            `yaml:"match" json:"match"`
+	Note     string            `yaml:"note,omitempty" json:"note,omitempty"`
+	Name     string            `yaml:"name,omitempty" json:"name,omitempty"`
+	Fields   map[string]string `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Image    string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Suppress bool              `yaml:"suppress,omitempty" json:"suppress,omitempty"`
+	Merge    string            `yaml:"merge,omitempty" json:"merge,omitempty"`
+	Split    []Entry           `yaml:"split,omitempty" json:"split,omitempty"`
+}
+
+// PathFor is where overrides for c go: the given file, else DefaultFile in
+// the card's set directory.
+func PathFor(c *catalog.Card, file string) string {
+	if file != "" || c.Set == nil {
+		return file
+	}
+	return filepath.Join(c.Set.Dir, DefaultFile)
+}
+
+// Load reads an overrides file; a missing file has no entries.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	if isJSON(path) {
+		err = json.Unmarshal(data, &out)
+	} else {
+		err = yaml.Unmarshal(data, &out)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+// Append adds e to the file. YAML is appended as text so the comments
+// curators write by hand are kept; a JSON file is rewritten.
+func Append(path string, e Entry) error {
+	if isJSON(path) {
+		entries, err := Load(path)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(append(entries, e), "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, append(data, '\n'), 0o644)
+	}
+	data, err := yaml.Marshal([]Entry{e})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// MatchID returns the card ID an entry's match names: match is a PageURL
+// or a page title, with spaces or underscores.
+func MatchID(match string) string {
+	match = strings.TrimSpace(match)
+	if strings.Contains(match, "://") {
+		return catalog.PageTitle(match)
+	}
+	return strings.ReplaceAll(match, " ", "_")
+}
+
+func isJSON(path string) bool { return strings.EqualFold(filepath.Ext(path), ".json") }
@@ -0,0 +1,86 @@
+package curate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAppendYAMLKeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	existing := `# DC overrides, kept by hand
+- match: Batman_(DCOP)
+  note: wiki has the wrong type # checked 2024
+  fields: {Type: Character}
+`
+	if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e := Entry{Match: "Flash_(DCOP)#silver", Note: "silver rarity", Fields: map[string]string{"Rarity": "Silver"}}
+	if err := Append(path, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(path, Entry{Match: "Card_List_(DCOP)", Suppress: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), existing) {
+		t.Errorf("existing text changed:\n%s", data)
+	}
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Match: "Batman_(DCOP)", Note: "wiki has the wrong type", Fields: map[string]string{"Type": "Character"}},
+		e,
+		{Match: "Card_List_(DCOP)", Suppress: true},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v\nwant %+v", entries, want)
+	}
+}
+
+func TestAppendCreatesFile(t *testing.T) {
+	for _, name := range []string{"overrides.yaml", "overrides.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		e := Entry{Match: "Batman_(DCOP)", Name: "Batman"}
+		for i := 0; i < 2; i++ {
+			if err := Append(path, e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(entries, []Entry{e, e}) {
+			t.Errorf("%s: entries = %+v", name, entries)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	entries, err := Load(filepath.Join(t.TempDir(), DefaultFile))
+	if err != nil || entries != nil {
+		t.Errorf("Load = %v, %v; want no entries", entries, err)
+	}
+}
+
+func TestMatchID(t *testing.T) {
+	for in, want := range map[string]string{
+		"Flash (DCOP)#silver": "Flash_(DCOP)#silver",
+		" Batman_(DCOP) ":     "Batman_(DCOP)",
+		"https://cardguide.fandom.com/wiki/Flash_(DCOP)#silver": "Flash_(DCOP)#silver",
+	} {
+		if got := MatchID(in); got != want {
+			t.Errorf("MatchID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/decklist"
+	"opscrape/resolve"
+)
+
+// ---------- Import ----------
+
+// readDeckList parses a text or .dek deck list; format "auto" goes by the
+// extension, then by whether the file starts with "<".
+func readDeckList(path, format string) ([]decklist.Line, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "auto" {
+		format = "text"
+		if strings.EqualFold(filepath.Ext(path), ".dek") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			format = "lackey"
+		}
+	}
+	var lines []decklist.Line
+	switch format {
+	case "text":
+		lines, err = decklist.ParseText(bytes.NewReader(data))
+	case "lackey":
+		lines, err = decklist.ParseLackey(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown -format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// resolveLines turns deck list lines into cards, one per copy, and reports
+// every line it could not place with certainty.
+func resolveLines(cat *catalog.Catalog, rv *resolve.Resolver, lines []decklist.Line, set string, report io.Writer) (cards []*catalog.Card, unresolved int) {
+	for _, l := range lines {
+		var card *catalog.Card
+		if c, ok := cat.Card(l.ID); ok && l.ID != "" {
+			card = c
+		} else {
+			res := rv.Resolve(l.Ref, resolve.Options{Set: firstNonEmpty(l.Set, set), Printing: l.Printing})
+			switch res.Status {
+			case resolve.Resolved:
+				card = res.Card
+				if res.Score < 1 {
+					fmt.Fprintf(report, "[WARN] line %d: %q read as %s (%.2f)\n", l.Num, l.Ref, card.ID, res.Score)
+				}
+			case resolve.Ambiguous:
+				ids := make([]string, len(res.Candidates))
+				for i, c := range res.Candidates {
+					ids[i] = c.ID
+				}
+				fmt.Fprintf(report, "[ERR] line %d: %q is ambiguous: %s\n", l.Num, l.Ref, strings.Join(ids, " | "))
+			default:
+				fmt.Fprintf(report, "[ERR] line %d: %q matches no card\n", l.Num, l.Ref)
+			}
+		}
+		if card == nil {
+			unresolved++
+			continue
+		}
+		for i := 0; i < l.Qty; i++ {
+			cards = append(cards, card)
+		}
+	}
+	return cards, unresolved
+}
+
+// ---------- Export ----------
+
+type deckItem struct {
+	card *catalog.Card
+	qty  int
+}
+
+// readDeckFile reads a deck JSON in the deckbuilder format.
+func readDeckFile(path string) (*deckFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d deckFile
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// matchDeckCard looks a deck entry up. Decks opscrape writes carry catalog
+// IDs; the deckbuilder's own carry its card table IDs ("card_12"), which
+// only the -cards table turns into names. A cardId is never guessed at as a
+// name.
+func matchDeckCard(idx *nameIndex, dc deckCard) (*catalog.Card, error) {
+	if c := idx.match(ownedLine{CardID: dc.CardID}); c != nil {
+		return c, nil
+	}
+	if tc, ok := idx.table[dc.CardID]; ok {
+		return nil, fmt.Errorf("%q (%s) has no catalog match", dc.CardID, tc.name())
+	}
+	if idx.table == nil {
+		return nil, fmt.Errorf("%q is not a catalog ID; pass -cards with the deckbuilder card table for deckbuilder IDs", dc.CardID)
+	}
+	return nil, fmt.Errorf("%q is in neither the catalog nor the card table", dc.CardID)
+}
+
+// readDeckCards reads a deck JSON and looks every entry up; an entry that
+// does not match is an error.
+func readDeckCards(cat *catalog.Catalog, table cardTable, path string) (*deckFile, []deckItem, error) {
+	d, err := readDeckFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx := newNameIndex(cat, table)
+	var out []deckItem
+	for _, dc := range d.Cards {
+		c, err := matchDeckCard(idx, dc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		out = append(out, deckItem{c, max(dc.Quantity, 1)})
+	}
+	return d, out, nil
+}
+
+// deckZones orders exported zones the way players lay a deck out.
+var deckZones = []string{"Characters", "Missions", "Deck"}
+
+func zoneOf(c *catalog.Card) string {
+	switch c.Type() {
+	case "Character":
+		return "Characters"
+	case "Mission":
+		return "Missions"
+	}
+	return "Deck"
+}
+
+// deckEntries reads a deck JSON and lists its cards by zone. withIDs forces
+// catalog IDs; otherwise an ID is only kept where "Name (SET)" would not
+// resolve back to the same card.
+func deckEntries(cat *catalog.Catalog, table cardTable, rv *resolve.Resolver, path string, withIDs bool) ([]decklist.Entry, error) {
+	_, items, err := readDeckCards(cat, table, path)
+	if err != nil {
+		return nil, err
+	}
+	var out []decklist.Entry
+	for _, it := range items {
+		c := it.card
+		e := decklist.Entry{Qty: it.qty, Name: c.Name, Set: c.Field("Set"), Printing: c.Printing(), Zone: zoneOf(c)}
+		if withIDs {
+			e.ID = c.ID
+		} else if res := rv.Resolve(c.Name, resolve.Options{Set: e.Set, Printing: e.Printing}); res.Card != c {
+			e.ID = c.ID
+		}
+		out = append(out, e)
+	}
+	rank := func(z string) int {
+		for i, dz := range deckZones {
+			if dz == z {
+				return i
+			}
+		}
+		return len(deckZones)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return rank(out[i].Zone) < rank(out[j].Zone) })
+	return out, nil
+}
+
+// ---------- Command ----------
+
+func runDeck(args []string) error {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "export") {
+		return fmt.Errorf("usage: opscrape deck import|export [flags]; see 'opscrape deck import -h'")
+	}
+	sub := args[0]
+	var root, in, format, out, set, name, cardsPath, createdArg string
+	fs := newFlagSet("deck "+sub, &root)
+	fs.StringVar(&out, "out", "", "Output file (default stdout)")
+	if sub == "import" {
+		fs.StringVar(&in, "in", "", "Deck list: plain text or LackeyCCG .dek (required)")
+		fs.StringVar(&format, "format", "auto", "Input format: auto, text or lackey")
+		fs.StringVar(&set, "set", "", "Set tag to prefer for names given without one")
+		fs.StringVar(&name, "name", "", "Deck name (default: the file name)")
+		fs.StringVar(&createdArg, "created", defaultCreated, "createdAt/updatedAt of the deck (RFC 3339); fixed so a list always imports to the same bytes")
+	} else {
+		fs.StringVar(&in, "deck", "", "Deck JSON in the deckbuilder format (required)")
+		fs.StringVar(&format, "format", "text", "Output format: text or lackey")
+	}
+	fs.StringVar(&cardsPath, "cards", "", "Deckbuilder card table for the deckbuilder's cardIds (see collection -cards)")
+	fs.Parse(args[1:])
+	if in == "" {
+		return fmt.Errorf("missing input file; see 'opscrape deck %s -h'", sub)
+	}
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	rv := resolve.New(cat)
+	table, err := loadCardTable(cardsPath)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if sub == "export" {
+		entries, err := deckEntries(cat, table, rv, in, format == "lackey")
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "text":
+			return decklist.WriteText(w, entries)
+		case "lackey":
+			return decklist.WriteLackey(w, entries)
+		}
+		return fmt.Errorf("unknown -format %q", format)
+	}
+
+	created, err := parseCreated(createdArg)
+	if err != nil {
+		return err
+	}
+	lines, err := readDeckList(in, format)
+	if err != nil {
+		return err
+	}
+	cards, unresolved := resolveLines(cat, rv, lines, set, os.Stderr)
+	base := strings.TrimSuffix(filepath.Base(in), filepath.Ext(in))
+	ids := newDeckIDs(cat, table)
+	if table == nil {
+		fmt.Fprintln(os.Stderr, "[WARN] no -cards table: the deck uses catalog IDs, which the deckbuilder cannot load")
+	}
+	warnMissingIDs(table, ids, cards)
+	d := newDeckFile("deck_"+base, firstNonEmpty(name, base), "Imported from "+filepath.Base(in), created, cards, ids)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d cards from %d lines, %d lines unresolved\n", len(cards), len(lines), unresolved)
+	return nil
+}
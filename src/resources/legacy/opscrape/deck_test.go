@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeckImportUsesDeckbuilderIDs(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "list.txt")
+	if err := os.WriteFile(list, []byte("# Characters\n2x Batman\n# Deck\n3x 3 Energy\n1x Batcave\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(out string, extra ...string) []byte {
+		t.Helper()
+		args := append([]string{"import", "-root", filepath.Join("testdata", "legacy"), "-in", list,
+			"-cards", filepath.Join("testdata", "deckbuilder_cards.csv"), "-out", out}, extra...)
+		if err := runDeck(args); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	first := run(filepath.Join(dir, "a.json"))
+	if second := run(filepath.Join(dir, "b.json")); !bytes.Equal(first, second) {
+		t.Errorf("two imports differ:\n%s\n%s", first, second)
+	}
+
+	d, err := readDeckFile(filepath.Join(dir, "a.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []deckCard{
+		{CardID: "6f1c0f7e-8a43-4c55-9a53-0d7b0b1f2a01", Quantity: 2},
+		{CardID: "card_12", Quantity: 3},
+		{CardID: "Batcave_(DCOP)", Quantity: 1}, // not in the card table
+	}
+	if !reflect.DeepEqual(d.Cards, want) {
+		t.Errorf("cards = %+v, want %+v", d.Cards, want)
+	}
+	if d.CreatedAt != "2000-01-01T00:00:00.000Z" {
+		t.Errorf("createdAt = %q", d.CreatedAt)
+	}
+
+	run(filepath.Join(dir, "c.json"), "-created", "2025-08-31T19:05:09Z")
+	if d, err := readDeckFile(filepath.Join(dir, "c.json")); err != nil || d.CreatedAt != "2025-08-31T19:05:09.000Z" || d.UpdatedAt != d.CreatedAt {
+		t.Errorf("-created: %+v, %v", d, err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package decklist reads and writes the deck list formats players share
+// legacy decks in: plain text ("4x 7 Any Power") and LackeyCCG .dek XML.
+// It only parses; resolving names against the catalog is up to the caller.
+//
+// Zones are kept in the order the caller gives them: the parsers return
+// lines in file order, and the writers group entries by zone in the order
+// each zone first appears, keeping entries in input order within a zone.
+// Sorting zones (opscrape writes Characters, Missions, then Deck) is the
+// caller's job. The one exception is plain text, where entries without a
+// zone come first, since a header applies to every line below it.
+package decklist
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Line is one card reference read from a deck list.
+type Line struct {
+	Num      int    `json:"line"`               // 1-based line, or card position in a .dek
+	Qty      int    `json:"quantity"`           //
+	Ref      string `json:"ref"`                // card name as written, set tag removed
+	Set      string `json:"set,omitempty"`      // set tag given with the card
+	Printing string `json:"printing,omitempty"` // e.g. "Chromium", from a {Chromium} suffix
+	ID       string `json:"id,omitempty"`       // catalog ID when the format carries one
+	Zone     string `json:"zone,omitempty"`     // section or superzone, e.g. "Characters"
+}
+
+// Entry is one resolved card to write.
+type Entry struct {
+	Qty      int
+	Name     string
+	Set      string
+	Printing string
+	ID       string
+	Zone     string
+}
+
+// ---------- Plain text ----------
+
+var (
+	// "4x Name", "4 x Name", "x4 Name"
+	qtyX = regexp.MustCompile(`^(?:(\d+)\s*[xX]|[xX]\s*(\d+))\s+(.+)$`)
+	// "4 Name"; only used when the line is not itself a Power card name
+	qtyBare   = regexp.MustCompile(`^(\d+)\s+(.+)$`)
+	powerCard = regexp.MustCompile(`(?i)^\d+\s+(energy|fighting|strength|intellect|any power|multipower|multi-power)$`)
+	printing  = regexp.MustCompile(`\s*\{([^}]+)\}\s*$`)
+	setTag    = regexp.MustCompile(`\s*\(([A-Za-z]+)\)\s*$`)
+	idRef     = regexp.MustCompile(`^\S+_\([A-Za-z]+\)(?:_\(var\))?(?:#[\w-]+)?$`)
+	header    = regexp.MustCompile(`^(?:#+\s*)?([A-Za-z][A-Za-z ]*?)\s*(?:\(\d+\))?:?$`)
+)
+
+// sectionNames are headers recognised in text lists; other lines ending in
+// ":" also start a section.
+var sectionNames = map[string]bool{
+	"characters": true, "character": true, "front line": true, "reserve": true,
+	"missions": true, "mission": true, "deck": true, "power pack": true,
+	"sideboard": true, "battle site": true, "homebase": true,
+}
+
+// ParseText reads a plain-text deck list. Blank lines and lines starting
+// with "//" or ";" are skipped; "# Characters" or "Characters:" start a
+// zone.
+func ParseText(r io.Reader) ([]Line, error) {
+	var out []Line
+	zone := ""
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "//") || strings.HasPrefix(s, ";") {
+			continue
+		}
+		if m := header.FindStringSubmatch(s); m != nil && (strings.HasSuffix(s, ":") || strings.HasPrefix(s, "#") || sectionNames[strings.ToLower(m[1])]) {
+			zone = m[1]
+			continue
+		}
+		if strings.HasPrefix(s, "#") {
+			continue
+		}
+		l := Line{Num: n, Qty: 1, Zone: zone}
+		if m := qtyX.FindStringSubmatch(s); m != nil {
+			l.Qty, _ = strconv.Atoi(m[1] + m[2])
+			s = m[3]
+		} else if m := qtyBare.FindStringSubmatch(s); m != nil && !powerCard.MatchString(s) {
+			l.Qty, _ = strconv.Atoi(m[1])
+			s = m[2]
+		}
+		if m := printing.FindStringSubmatch(s); m != nil {
+			l.Printing = m[1]
+			s = s[:len(s)-len(m[0])]
+		}
+		if idRef.MatchString(s) {
+			l.ID = s
+		} else if m := setTag.FindStringSubmatch(s); m != nil {
+			l.Set = strings.ToUpper(m[1])
+			s = s[:len(s)-len(m[0])]
+		}
+		l.Ref = strings.TrimSpace(s)
+		if l.Qty <= 0 {
+			return nil, fmt.Errorf("line %d: quantity must be positive", n)
+		}
+		out = append(out, l)
+	}
+	return out, sc.Err()
+}
+
+// WriteText writes entries grouped by zone, in first-seen zone order with
+// unzoned entries first, as "4x Name (SET)". Entries with an ID are written
+// by ID, which always resolves back to the same card.
+func WriteText(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	order := zones(entries)
+	for i, z := range order {
+		if z == "" {
+			copy(order[1:i+1], order[:i])
+			order[0] = ""
+		}
+	}
+	for _, zone := range order {
+		if zone != "" {
+			fmt.Fprintf(bw, "# %s\n", zone)
+		}
+		for _, e := range entries {
+			if e.Zone != zone {
+				continue
+			}
+			ref := e.Name
+			if e.Set != "" {
+				ref += " (" + e.Set + ")"
+			}
+			if e.ID != "" {
+				ref = e.ID
+			}
+			if e.Printing != "" && e.Printing != "Normal" {
+				ref += " {" + e.Printing + "}"
+			}
+			fmt.Fprintf(bw, "%dx %s\n", e.Qty, ref)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+func zones(entries []Entry) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if !seen[e.Zone] {
+			seen[e.Zone] = true
+			out = append(out, e.Zone)
+		}
+	}
+	return out
+}
+
+// ---------- LackeyCCG ----------
+
+// lackeyDeck is the subset of a LackeyCCG .dek file we read and write.
+type lackeyDeck struct {
+	XMLName    xml.Name          `xml:"deck"`
+	Version    string            `xml:"version,attr"`
+	Game       string            `xml:"meta>game"`
+	Superzones []lackeySuperzone `xml:"superzone"`
+}
+
+type lackeySuperzone struct {
+	Name  string       `xml:"name,attr"`
+	Cards []lackeyCard `xml:"card"`
+}
+
+type lackeyCard struct {
+	Name lackeyName `xml:"name"`
+	Set  string     `xml:"set"`
+}
+
+type lackeyName struct {
+	ID   string `xml:"id,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+// ParseLackey reads a .dek file. Lackey lists one <card> per copy; copies
+// that agree on name, ID and set within a superzone are counted together.
+func ParseLackey(r io.Reader) ([]Line, error) {
+	var d lackeyDeck
+	if err := xml.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	var out []Line
+	pos := map[string]int{}
+	n := 0
+	for _, z := range d.Superzones {
+		for _, c := range z.Cards {
+			n++
+			name := strings.TrimSpace(c.Name.Text)
+			if name == "" && c.Name.ID == "" {
+				continue
+			}
+			key := z.Name + "\x00" + c.Name.ID + "\x00" + name + "\x00" + c.Set
+			if i, ok := pos[key]; ok {
+				out[i].Qty++
+				continue
+			}
+			pos[key] = len(out)
+			l := Line{Num: n, Qty: 1, Ref: name, ID: c.Name.ID, Set: strings.ToUpper(strings.TrimSpace(c.Set)), Zone: z.Name}
+			if m := printing.FindStringSubmatch(l.Ref); m != nil {
+				l.Printing = m[1]
+				l.Ref = strings.TrimSpace(l.Ref[:len(l.Ref)-len(m[0])])
+			}
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// WriteLackey writes entries as a .dek file, one <card> per copy and one
+// superzone per zone ("Deck" when the zone is empty).
+func WriteLackey(w io.Writer, entries []Entry) error {
+	d := lackeyDeck{Version: "0.8", Game: "overpower"}
+	for _, zone := range zones(entries) {
+		z := lackeySuperzone{Name: zone}
+		if z.Name == "" {
+			z.Name = "Deck"
+		}
+		for _, e := range entries {
+			if e.Zone != zone {
+				continue
+			}
+			name := e.Name
+			if e.Printing != "" && e.Printing != "Normal" {
+				name += " {" + e.Printing + "}"
+			}
+			for i := 0; i < e.Qty; i++ {
+				z.Cards = append(z.Cards, lackeyCard{Name: lackeyName{ID: e.ID, Text: name}, Set: e.Set})
+			}
+		}
+		d.Superzones = append(d.Superzones, z)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,155 @@
+package decklist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// entries lists a deck the way opscrape's deck export does: Characters,
+// Missions, then Deck.
+var entries = []Entry{
+	{Qty: 1, Name: "Wolverine", Set: "CLOP", Printing: "Normal", Zone: "Characters"},
+	{Qty: 1, Name: "Flash", Set: "DCOP", Printing: "Silver", ID: "Flash_(DCOP)#silver", Zone: "Characters"},
+	{Qty: 7, Name: "Mission: Rescue", Set: "CLOP", Zone: "Missions"},
+	{Qty: 4, Name: "7 Any Power", Set: "CLOP", Printing: "Normal", Zone: "Deck"},
+	{Qty: 2, Name: "Wolverine - Berserker Rage", Set: "CLOP", Printing: "Chromium", Zone: "Deck"},
+	{Qty: 1, Name: "Batman", Set: "DCOP", ID: "Batman_(DCOP)_(var)", Zone: "Deck"},
+}
+
+// toEntries feeds parsed lines back to a writer.
+func toEntries(lines []Line) []Entry {
+	out := make([]Entry, len(lines))
+	for i, l := range lines {
+		out[i] = Entry{Qty: l.Qty, Name: l.Ref, Set: l.Set, Printing: l.Printing, ID: l.ID, Zone: l.Zone}
+	}
+	return out
+}
+
+// strip drops what a format cannot carry so parsed lines compare with entries.
+func strip(lines []Line) []Line {
+	out := append([]Line(nil), lines...)
+	for i := range out {
+		out[i].Num = 0
+	}
+	return out
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	var first bytes.Buffer
+	if err := WriteText(&first, entries); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ParseText(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Line{
+		{Qty: 1, Ref: "Wolverine", Set: "CLOP", Zone: "Characters"},
+		{Qty: 1, Ref: "Flash_(DCOP)#silver", Printing: "Silver", ID: "Flash_(DCOP)#silver", Zone: "Characters"},
+		{Qty: 7, Ref: "Mission: Rescue", Set: "CLOP", Zone: "Missions"},
+		{Qty: 4, Ref: "7 Any Power", Set: "CLOP", Zone: "Deck"},
+		{Qty: 2, Ref: "Wolverine - Berserker Rage", Set: "CLOP", Printing: "Chromium", Zone: "Deck"},
+		{Qty: 1, Ref: "Batman_(DCOP)_(var)", ID: "Batman_(DCOP)_(var)", Zone: "Deck"},
+	}
+	if got := strip(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsed:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	var second, third bytes.Buffer
+	if err := WriteText(&second, toEntries(lines)); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseText(bytes.NewReader(second.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteText(&third, toEntries(again)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(strip(again), want) || second.String() != third.String() {
+		t.Errorf("second round trip drifted:\n%s---\n%s", second.String(), third.String())
+	}
+}
+
+func TestTextWritesUnzonedEntriesFirst(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteText(&buf, []Entry{
+		{Qty: 1, Name: "Wolverine", Zone: "Characters"},
+		{Qty: 3, Name: "5 Energy"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "3x 5 Energy\n\n# Characters\n1x Wolverine\n\n"; buf.String() != want {
+		t.Fatalf("wrote %q, want %q", buf.String(), want)
+	}
+	lines, _ := ParseText(&buf)
+	if len(lines) != 2 || lines[0].Zone != "" || lines[1].Zone != "Characters" {
+		t.Errorf("zones = %+v", lines)
+	}
+}
+
+func TestLackeyRoundTrip(t *testing.T) {
+	var first bytes.Buffer
+	if err := WriteLackey(&first, entries); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(first.String(), "<card>"); n != 16 {
+		t.Errorf("wrote %d <card> elements, want one per copy (16)", n)
+	}
+	lines, err := ParseLackey(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Line{
+		{Num: 1, Qty: 1, Ref: "Wolverine", Set: "CLOP", Zone: "Characters"},
+		{Num: 2, Qty: 1, Ref: "Flash", Set: "DCOP", Printing: "Silver", ID: "Flash_(DCOP)#silver", Zone: "Characters"},
+		{Num: 3, Qty: 7, Ref: "Mission: Rescue", Set: "CLOP", Zone: "Missions"},
+		{Num: 10, Qty: 4, Ref: "7 Any Power", Set: "CLOP", Zone: "Deck"},
+		{Num: 14, Qty: 2, Ref: "Wolverine - Berserker Rage", Set: "CLOP", Printing: "Chromium", Zone: "Deck"},
+		{Num: 16, Qty: 1, Ref: "Batman", Set: "DCOP", ID: "Batman_(DCOP)_(var)", Zone: "Deck"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("parsed:\n%+v\nwant:\n%+v", lines, want)
+	}
+
+	var second bytes.Buffer
+	if err := WriteLackey(&second, toEntries(lines)); err != nil {
+		t.Fatal(err)
+	}
+	if second.String() != first.String() {
+		t.Errorf("rewrite differs:\n%s---\n%s", first.String(), second.String())
+	}
+}
+
+func TestParseTextForms(t *testing.T) {
+	in := `// comment
+Front Line:
+4x 7 Any Power
+x2 Wolverine (clop)
+3 Cyclops
+6 Energy
+; skipped
+## Sideboard (2)
+2 x Storm {Foil}
+`
+	lines, err := ParseText(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Line{
+		{Num: 3, Qty: 4, Ref: "7 Any Power", Zone: "Front Line"},
+		{Num: 4, Qty: 2, Ref: "Wolverine", Set: "CLOP", Zone: "Front Line"},
+		{Num: 5, Qty: 3, Ref: "Cyclops", Zone: "Front Line"},
+		{Num: 6, Qty: 1, Ref: "6 Energy", Zone: "Front Line"},
+		{Num: 9, Qty: 2, Ref: "Storm", Printing: "Foil", Zone: "Sideboard"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("parsed:\n%+v\nwant:\n%+v", lines, want)
+	}
+	if _, err := ParseText(strings.NewReader("0x Storm\n")); err == nil {
+		t.Error("0x accepted")
+	}
+}
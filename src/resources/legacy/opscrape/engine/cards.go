@@ -0,0 +1,263 @@
+// Package engine is a headless OverPower battle engine built on the scraped
+// catalog. It models the zones of a game, resolves Power card and parsed
+// Special attacks and defenses, and is driven by Controllers.
+//
+// Only the Special effects ParseEffect recognises are playable; the rest are
+// reported as unsupported so scripted scenarios fail loudly instead of
+// silently ignoring rules text.
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"opscrape/catalog"
+)
+
+// ---------- Power types ----------
+
+type PowerType int
+
+const (
+	Energy PowerType = iota
+	Fighting
+	Strength
+	Intellect
+	AnyPower
+	MultiPower
+)
+
+var powerNames = []string{"Energy", "Fighting", "Strength", "Intellect", "Any Power", "MultiPower"}
+
+func (t PowerType) String() string {
+	if int(t) < len(powerNames) {
+		return powerNames[t]
+	}
+	return "?"
+}
+
+// ParsePowerType accepts the spellings used in card names and game text,
+// including the wiki's "Figthing" typo.
+func ParsePowerType(s string) (PowerType, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "energy":
+		return Energy, true
+	case "fighting", "figthing":
+		return Fighting, true
+	case "strength":
+		return Strength, true
+	case "intellect":
+		return Intellect, true
+	case "any power", "any-power", "any":
+		return AnyPower, true
+	case "multipower", "multi-power", "multi power":
+		return MultiPower, true
+	}
+	return 0, false
+}
+
+// Grid is a character's Energy/Fighting/Strength/Intellect ratings.
+type Grid [4]int
+
+// Total is the sum of the four ratings.
+func (g Grid) Total() int { return g[0] + g[1] + g[2] + g[3] }
+
+var gridPart = regexp.MustCompile(`(Energy|Fighting|Strength|Intellect)\s*(\d+)`)
+
+// ParseGrid reads a Character card's Numbers field, e.g.
+// "Energy 5Fighting 5Strength 7Intellect 1".
+func ParseGrid(numbers string) (Grid, bool) {
+	var g Grid
+	seen := 0
+	for _, m := range gridPart.FindAllStringSubmatch(numbers, -1) {
+		t, _ := ParsePowerType(m[1])
+		n, _ := strconv.Atoi(m[2])
+		g[t] = n
+		seen |= 1 << t
+	}
+	return g, seen == 0b1111
+}
+
+// ---------- Cards ----------
+
+type Kind int
+
+const (
+	KindOther Kind = iota
+	KindCharacter
+	KindPower
+	KindSpecial
+	KindUniverse
+	KindTactic
+	KindEvent
+	KindMission
+)
+
+// Card is a catalog card reduced to what the engine needs.
+type Card struct {
+	ID    string
+	Name  string
+	Kind  Kind
+	Owner string // Specials: the character before " - "
+
+	Power PowerType // Power cards
+	Value int
+
+	Grid   Grid // Characters
+	Effect Effect
+}
+
+var powerName = regexp.MustCompile(`^(\d+)\s+(Energy|Fighting|Strength|Intellect|Any Power|MultiPower)$`)
+
+// FromCatalog converts a catalog card. ok is false for Power cards whose
+// name doesn't parse and Characters without a full grid.
+func FromCatalog(c *catalog.Card) (Card, bool) {
+	card := Card{ID: c.ID, Name: c.Name}
+	switch strings.ToLower(c.Type()) {
+	case "character":
+		card.Kind = KindCharacter
+		g, ok := ParseGrid(c.Numbers())
+		card.Grid = g
+		return card, ok
+	case "power":
+		card.Kind = KindPower
+		m := powerName.FindStringSubmatch(strings.TrimSpace(c.Name))
+		if m == nil {
+			return card, false
+		}
+		card.Value, _ = strconv.Atoi(m[1])
+		card.Power, _ = ParsePowerType(m[2])
+		return card, true
+	case "special":
+		card.Kind = KindSpecial
+		card.Owner = c.Name
+		if i := strings.Index(c.Name, " - "); i > 0 {
+			card.Owner = strings.TrimSpace(c.Name[:i])
+		}
+		card.Effect = ParseEffect(c.GameText())
+	case "universe":
+		card.Kind = KindUniverse
+	case "tactic":
+		card.Kind = KindTactic
+	case "event":
+		card.Kind = KindEvent
+	case "mission":
+		card.Kind = KindMission
+	}
+	return card, true
+}
+
+// CanBeUsedBy reports whether a Power card fits a character's grid.
+func (c Card) CanBeUsedBy(g Grid) bool {
+	if c.Kind != KindPower {
+		return false
+	}
+	switch c.Power {
+	case AnyPower, MultiPower:
+		for _, v := range g {
+			if c.Value <= v {
+				return true
+			}
+		}
+		return false
+	}
+	return c.Value <= g[c.Power]
+}
+
+// ---------- Special effects ----------
+
+type EffectKind int
+
+const (
+	EffectNone EffectKind = iota
+	EffectAttack
+	EffectAvoid
+	EffectUnsupported
+)
+
+// Effect is the parsed part of a Special's game text.
+type Effect struct {
+	Kind  EffectKind
+	Type  PowerType   // attack type
+	Level int         // attack level
+	Avoid []PowerType // types an avoid covers; empty means any attack
+
+	NoSpecialDefense bool // "May not be defended with a Special card."
+	Text             string
+}
+
+var (
+	attackText   = regexp.MustCompile(`(?i)^acts as a level (\d+) (energy|fighting|strength|intellect) attack\.?`)
+	avoidAnyText = regexp.MustCompile(`(?i)^avoid (?:any|1) attack\.?`)
+	avoidText    = regexp.MustCompile(`(?i)^avoid (?:1|any) ((?:energy|fighting|strength|intellect)(?:(?:,| or|, or) (?:energy|fighting|strength|intellect))*) (?:card|attack)s?\.?`)
+	noSpecialDef = regexp.MustCompile(`(?i)^may not be defended with a special card\.?`)
+	avoidTypes   = regexp.MustCompile(`(?i)energy|fighting|strength|intellect`)
+	sentenceEnd  = regexp.MustCompile(`\.\s*`)
+)
+
+// ParseEffect recognises the attack and avoid templates used across the
+// legacy sets. Any sentence it cannot account for makes the whole effect
+// unsupported; "One Per Deck" is a deckbuilding rule and is ignored.
+func ParseEffect(text string) Effect {
+	e := Effect{Text: text}
+	rest := strings.TrimSpace(strings.ReplaceAll(text, "One Per Deck", ""))
+	if rest == "" || rest == "-" {
+		return e
+	}
+	for _, s := range sentenceEnd.Split(rest, -1) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		switch {
+		case attackText.MatchString(s) && e.Kind == EffectNone:
+			m := attackText.FindStringSubmatch(s)
+			e.Kind = EffectAttack
+			e.Level, _ = strconv.Atoi(m[1])
+			e.Type, _ = ParsePowerType(m[2])
+		case avoidAnyText.MatchString(s) && e.Kind == EffectNone:
+			e.Kind = EffectAvoid
+		case avoidText.MatchString(s) && e.Kind == EffectNone:
+			e.Kind = EffectAvoid
+			for _, t := range avoidTypes.FindAllString(avoidText.FindStringSubmatch(s)[1], -1) {
+				pt, _ := ParsePowerType(t)
+				e.Avoid = append(e.Avoid, pt)
+			}
+		case noSpecialDef.MatchString(s) && e.Kind == EffectAttack:
+			e.NoSpecialDefense = true
+		default:
+			e.Kind = EffectUnsupported
+			return e
+		}
+	}
+	return e
+}
+
+// Avoids reports whether the effect avoids an attack of type t.
+func (e Effect) Avoids(t PowerType) bool {
+	if e.Kind != EffectAvoid {
+		return false
+	}
+	if len(e.Avoid) == 0 {
+		return true
+	}
+	for _, a := range e.Avoid {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
+
+// PlayableBy reports whether character ch can play the card: Power cards
+// must fit its grid and Specials must be its own (or "Any Character").
+func (c Card) PlayableBy(ch Card) bool {
+	switch c.Kind {
+	case KindPower:
+		return c.CanBeUsedBy(ch.Grid)
+	case KindSpecial:
+		return canPlaySpecial(c.Owner, ch.Name)
+	}
+	return false
+}
@@ -0,0 +1,532 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// ---------- Rules ----------
+
+// Rules holds the tunable parts of the game.
+type Rules struct {
+	HandSize      int // Draw Phase refills the Hand to this many cards
+	MissionsToWin int // completed Missions needed to win
+	MaxVenture    int // most Mission cards a player may venture per battle
+}
+
+func DefaultRules() Rules {
+	return Rules{HandSize: 8, MissionsToWin: 7, MaxVenture: 7}
+}
+
+// ---------- State ----------
+
+// Hit is one successful attack recorded on a character.
+type Hit struct {
+	CardID string
+	Type   PowerType
+	Points int
+}
+
+// Character is a character in play with its current-battle hits and
+// Permanent Record.
+type Character struct {
+	Card
+	Hits   []Hit
+	Record []Hit
+	KO     bool
+}
+
+// RecordTotal sums the points in the Permanent Record.
+func (c *Character) RecordTotal() int {
+	n := 0
+	for _, h := range c.Record {
+		n += h.Points
+	}
+	return n
+}
+
+// HitsToKO is the character's grid total.
+func (c *Character) HitsToKO() int { return c.Grid.Total() }
+
+// PlayerState is every zone one player owns.
+type PlayerState struct {
+	Name      string
+	FrontLine []*Character
+	Reserve   *Character // plays no cards; joins the Front Line after a KO
+
+	DrawPile []Card
+	Hand     []Card
+	DeadPile []Card
+	Placed   map[int][]Card // by Front Line position
+
+	Missions  []Card
+	Completed []Card
+	Defeated  []Card
+	Venture   int
+}
+
+// Setup is a player's starting position.
+type Setup struct {
+	Name      string
+	FrontLine []Card
+	Reserve   *Card
+	Deck      []Card
+	Missions  []Card
+	Stacked   bool // keep Deck order instead of shuffling (scripted scenarios)
+}
+
+// Attack names the card played from hand, the attacking Front Line position
+// and the targeted opposing Front Line position.
+type Attack struct {
+	CardID   string
+	Attacker int
+	Target   int
+}
+
+// Defense names the card played from hand to stop an attack.
+type Defense struct {
+	CardID string
+}
+
+// Controller drives one player. Attack and Defend return false to pass or
+// to take the hit.
+type Controller interface {
+	Venture(g *Game, me int) int
+	Attack(g *Game, me int) (Attack, bool)
+	Defend(g *Game, me int, a Attack) (Defense, bool)
+}
+
+// Placement sets a card from hand aside on a Front Line position.
+type Placement struct {
+	CardID   string
+	Position int
+}
+
+// Placer is an optional Controller extension called at the end of each
+// battle, before the Hand is discarded. Placed cards stay with their
+// character until it is KO'd; the engine applies no effects from them.
+type Placer interface {
+	Place(g *Game, me int) []Placement
+}
+
+// Event is one line of the game log.
+type Event struct {
+	Battle int
+	Player int
+	Kind   string
+	Detail string
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("battle %d p%d %-8s %s", e.Battle, e.Player+1, e.Kind, e.Detail)
+}
+
+// Game is a two-player game in progress.
+type Game struct {
+	Rules   Rules
+	Players [2]*PlayerState
+	Battle  int
+	Winner  int // -1 while the game is undecided
+	Log     []Event
+
+	first   int
+	pending *pendingAttack
+}
+
+// pendingAttack is the attack waiting for a defense; its card is already
+// in the Dead Pile.
+type pendingAttack struct {
+	card   Card
+	typ    PowerType
+	level  int
+	target int
+}
+
+// ErrUnsupported marks a Special whose game text the engine cannot resolve.
+var ErrUnsupported = errors.New("unsupported special")
+
+// NewGame validates both setups and shuffles the decks with seed.
+func NewGame(rules Rules, setups [2]Setup, seed uint64) (*Game, error) {
+	g := &Game{Rules: rules, Winner: -1}
+	rng := rand.New(rand.NewPCG(seed, seed^0x5851f42d4c957f2d))
+	for i, s := range setups {
+		if len(s.FrontLine) == 0 || len(s.FrontLine) > 4 {
+			return nil, fmt.Errorf("player %d: Front Line needs 1-4 characters, got %d", i+1, len(s.FrontLine))
+		}
+		p := &PlayerState{Name: s.Name, Placed: map[int][]Card{}}
+		for _, c := range s.FrontLine {
+			if c.Kind != KindCharacter {
+				return nil, fmt.Errorf("player %d: %s is not a Character", i+1, c.Name)
+			}
+			p.FrontLine = append(p.FrontLine, &Character{Card: c})
+		}
+		if s.Reserve != nil {
+			if s.Reserve.Kind != KindCharacter {
+				return nil, fmt.Errorf("player %d: Reserve %s is not a Character", i+1, s.Reserve.Name)
+			}
+			p.Reserve = &Character{Card: *s.Reserve}
+		}
+		p.DrawPile = append([]Card{}, s.Deck...)
+		if !s.Stacked {
+			rng.Shuffle(len(p.DrawPile), func(a, b int) { p.DrawPile[a], p.DrawPile[b] = p.DrawPile[b], p.DrawPile[a] })
+		}
+		p.Missions = append([]Card{}, s.Missions...)
+		g.Players[i] = p
+	}
+	return g, nil
+}
+
+func (g *Game) logf(player int, kind, format string, args ...any) {
+	g.Log = append(g.Log, Event{Battle: g.Battle, Player: player, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+// ---------- Legal moves ----------
+
+func handIndex(hand []Card, id string) int {
+	for i, c := range hand {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// canPlaySpecial matches a Special's owner against a character name:
+// "Angel" plays on "Angel: Horseman of Apocalypse", "Any Character" on anyone.
+func canPlaySpecial(owner, character string) bool {
+	if owner == "" || strings.EqualFold(owner, "Any Character") {
+		return true
+	}
+	o, c := strings.ToLower(strings.TrimSuffix(owner, "™")), strings.ToLower(strings.ReplaceAll(character, "™", ""))
+	return o == c || strings.HasPrefix(c, o+":") || strings.HasPrefix(c, o+" (")
+}
+
+// attackOf returns the attack type and level of a card played by attacker.
+func attackOf(card Card, attacker *Character) (PowerType, int, error) {
+	switch card.Kind {
+	case KindPower:
+		if !card.CanBeUsedBy(attacker.Grid) {
+			return 0, 0, fmt.Errorf("%s cannot use %s", attacker.Name, card.Name)
+		}
+		return card.Power, card.Value, nil
+	case KindSpecial:
+		if !canPlaySpecial(card.Owner, attacker.Name) {
+			return 0, 0, fmt.Errorf("%s is not %s's Special", card.Name, attacker.Name)
+		}
+		switch card.Effect.Kind {
+		case EffectAttack:
+			return card.Effect.Type, card.Effect.Level, nil
+		case EffectUnsupported:
+			return 0, 0, fmt.Errorf("%w: %s: %q", ErrUnsupported, card.Name, card.Effect.Text)
+		}
+		return 0, 0, fmt.Errorf("%s is not an attack", card.Name)
+	}
+	return 0, 0, fmt.Errorf("%s cannot attack", card.Name)
+}
+
+func (g *Game) checkAttack(me int, a Attack) (Card, PowerType, int, error) {
+	p, opp := g.Players[me], g.Players[1-me]
+	i := handIndex(p.Hand, a.CardID)
+	if i < 0 {
+		return Card{}, 0, 0, fmt.Errorf("%s not in hand", a.CardID)
+	}
+	if a.Attacker < 0 || a.Attacker >= len(p.FrontLine) || p.FrontLine[a.Attacker].KO {
+		return Card{}, 0, 0, fmt.Errorf("attacker %d is not an active Front Line character", a.Attacker)
+	}
+	if a.Target < 0 || a.Target >= len(opp.FrontLine) || opp.FrontLine[a.Target].KO {
+		return Card{}, 0, 0, fmt.Errorf("target %d is not an active Front Line character", a.Target)
+	}
+	t, lvl, err := attackOf(p.Hand[i], p.FrontLine[a.Attacker])
+	return p.Hand[i], t, lvl, err
+}
+
+func (g *Game) checkDefense(me int, d Defense) (Card, error) {
+	if g.pending == nil {
+		return Card{}, fmt.Errorf("no attack to defend")
+	}
+	p := g.Players[me]
+	atkCard, t, lvl := g.pending.card, g.pending.typ, g.pending.level
+	i := handIndex(p.Hand, d.CardID)
+	if i < 0 {
+		return Card{}, fmt.Errorf("%s not in hand", d.CardID)
+	}
+	card, target := p.Hand[i], p.FrontLine[g.pending.target]
+	switch card.Kind {
+	case KindPower:
+		if !card.CanBeUsedBy(target.Grid) {
+			return Card{}, fmt.Errorf("%s cannot use %s", target.Name, card.Name)
+		}
+		if card.Power != t && card.Power != AnyPower && card.Power != MultiPower {
+			return Card{}, fmt.Errorf("%s cannot stop a %s attack", card.Name, t)
+		}
+		if card.Value < lvl {
+			return Card{}, fmt.Errorf("%s is below the level %d attack", card.Name, lvl)
+		}
+		return card, nil
+	case KindSpecial:
+		if atkCard.Effect.NoSpecialDefense {
+			return Card{}, fmt.Errorf("%s may not be defended with a Special", atkCard.Name)
+		}
+		if !canPlaySpecial(card.Owner, target.Name) {
+			return Card{}, fmt.Errorf("%s is not %s's Special", card.Name, target.Name)
+		}
+		if card.Effect.Kind == EffectUnsupported {
+			return Card{}, fmt.Errorf("%w: %s: %q", ErrUnsupported, card.Name, card.Effect.Text)
+		}
+		if !card.Effect.Avoids(t) {
+			return Card{}, fmt.Errorf("%s does not avoid a %s attack", card.Name, t)
+		}
+		return card, nil
+	}
+	return Card{}, fmt.Errorf("%s cannot defend", card.Name)
+}
+
+// LegalAttacks lists every attack player me can make now.
+func (g *Game) LegalAttacks(me int) []Attack {
+	var out []Attack
+	p, opp := g.Players[me], g.Players[1-me]
+	seen := map[string]bool{}
+	for _, c := range p.Hand {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		for ai := range p.FrontLine {
+			for ti := range opp.FrontLine {
+				a := Attack{CardID: c.ID, Attacker: ai, Target: ti}
+				if _, _, _, err := g.checkAttack(me, a); err == nil {
+					out = append(out, a)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// LegalDefenses lists every card player me can stop the pending attack with.
+func (g *Game) LegalDefenses(me int) []Defense {
+	var out []Defense
+	seen := map[string]bool{}
+	for _, c := range g.Players[me].Hand {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		if _, err := g.checkDefense(me, Defense{CardID: c.ID}); err == nil {
+			out = append(out, Defense{CardID: c.ID})
+		}
+	}
+	return out
+}
+
+// ---------- Battle ----------
+
+// BattleResult summarises one battle. Winner is -1 on a tie.
+type BattleResult struct {
+	Battle    int
+	Winner    int
+	HitPoints [2]int // points each player's characters received
+	Venture   [2]int
+}
+
+func (p *PlayerState) discard(i int) Card {
+	c := p.Hand[i]
+	p.Hand = append(p.Hand[:i], p.Hand[i+1:]...)
+	p.DeadPile = append(p.DeadPile, c)
+	return c
+}
+
+// PlayBattle runs the Draw, Venture and attack phases of one battle, then
+// settles Missions and moves hits to the Permanent Record.
+func (g *Game) PlayBattle(ctrl [2]Controller) (BattleResult, error) {
+	if g.Winner >= 0 {
+		return BattleResult{}, fmt.Errorf("game is over")
+	}
+	g.Battle++
+	res := BattleResult{Battle: g.Battle, Winner: -1}
+
+	// Draw Phase: a Reserve replaces the first character KO'd, then hands
+	// are refilled.
+	for i, p := range g.Players {
+		g.bringInReserve(i)
+		for len(p.Hand) < g.Rules.HandSize && len(p.DrawPile) > 0 {
+			p.Hand = append(p.Hand, p.DrawPile[0])
+			p.DrawPile = p.DrawPile[1:]
+		}
+		g.logf(i, "draw", "hand %d, draw pile %d", len(p.Hand), len(p.DrawPile))
+	}
+
+	// Venture
+	for i, p := range g.Players {
+		v := ctrl[i].Venture(g, i)
+		v = max(0, min(v, g.Rules.MaxVenture, len(p.Missions)))
+		p.Venture = v
+		res.Venture[i] = v
+		g.logf(i, "venture", "%d", v)
+	}
+
+	// Attacks alternate until both players pass in a row.
+	cur, passes := g.first, 0
+	for passes < 2 {
+		a, ok := ctrl[cur].Attack(g, cur)
+		if !ok {
+			g.logf(cur, "pass", "")
+			passes++
+			cur = 1 - cur
+			continue
+		}
+		passes = 0
+		card, t, lvl, err := g.checkAttack(cur, a)
+		if err != nil {
+			return res, fmt.Errorf("battle %d player %d attack: %w", g.Battle, cur+1, err)
+		}
+		p, opp := g.Players[cur], g.Players[1-cur]
+		p.discard(handIndex(p.Hand, card.ID))
+		target := opp.FrontLine[a.Target]
+		g.logf(cur, "attack", "%s: level %d %s on %s", card.Name, lvl, t, target.Name)
+
+		g.pending = &pendingAttack{card: card, typ: t, level: lvl, target: a.Target}
+		d, ok := ctrl[1-cur].Defend(g, 1-cur, a)
+		if ok {
+			dc, err := g.checkDefense(1-cur, d)
+			if err != nil {
+				return res, fmt.Errorf("battle %d player %d defense: %w", g.Battle, 2-cur, err)
+			}
+			opp.discard(handIndex(opp.Hand, dc.ID))
+			g.logf(1-cur, "defend", "%s stops it with %s", target.Name, dc.Name)
+		} else {
+			target.Hits = append(target.Hits, Hit{CardID: card.ID, Type: t, Points: lvl})
+			g.logf(1-cur, "hit", "%s takes %d", target.Name, lvl)
+		}
+		g.pending = nil
+		cur = 1 - cur
+	}
+
+	for i, p := range g.Players {
+		pl, ok := ctrl[i].(Placer)
+		if !ok {
+			continue
+		}
+		for _, pm := range pl.Place(g, i) {
+			j := handIndex(p.Hand, pm.CardID)
+			if j < 0 || pm.Position < 0 || pm.Position >= len(p.FrontLine) || p.FrontLine[pm.Position].KO {
+				return res, fmt.Errorf("battle %d player %d: cannot place %s on %d", g.Battle, i+1, pm.CardID, pm.Position)
+			}
+			c := p.Hand[j]
+			p.Hand = append(p.Hand[:j], p.Hand[j+1:]...)
+			p.Placed[pm.Position] = append(p.Placed[pm.Position], c)
+			g.logf(i, "place", "%s on %s", c.Name, p.FrontLine[pm.Position].Name)
+		}
+	}
+
+	g.settle(&res)
+	g.first = 1 - g.first
+	return res, nil
+}
+
+// settle decides the battle, moves Missions and hits, and checks for KOs
+// and the end of the game.
+func (g *Game) settle(res *BattleResult) {
+	for i, p := range g.Players {
+		for _, c := range p.FrontLine {
+			for _, h := range c.Hits {
+				res.HitPoints[i] += h.Points
+			}
+		}
+	}
+	switch {
+	case res.HitPoints[0] < res.HitPoints[1]:
+		res.Winner = 0
+	case res.HitPoints[1] < res.HitPoints[0]:
+		res.Winner = 1
+	}
+
+	// The winner completes the Missions they ventured; the loser's go to
+	// the Defeated pile. A tie leaves both Mission piles alone.
+	if res.Winner >= 0 {
+		w, l := g.Players[res.Winner], g.Players[1-res.Winner]
+		w.Completed = append(w.Completed, w.Missions[:w.Venture]...)
+		w.Missions = w.Missions[w.Venture:]
+		l.Defeated = append(l.Defeated, l.Missions[:l.Venture]...)
+		l.Missions = l.Missions[l.Venture:]
+		g.logf(res.Winner, "win", "battle %d-%d, %d missions completed", res.HitPoints[1-res.Winner], res.HitPoints[res.Winner], len(w.Completed))
+	} else {
+		g.logf(0, "tie", "battle %d-%d", res.HitPoints[1], res.HitPoints[0])
+	}
+
+	for i, p := range g.Players {
+		p.Venture = 0
+		for pos, c := range p.FrontLine {
+			c.Record = append(c.Record, c.Hits...)
+			c.Hits = nil
+			if !c.KO && c.RecordTotal() >= c.HitsToKO() {
+				c.KO = true
+				p.DeadPile = append(p.DeadPile, p.Placed[pos]...)
+				delete(p.Placed, pos)
+				g.logf(i, "ko", "%s (%d/%d)", c.Name, c.RecordTotal(), c.HitsToKO())
+			}
+		}
+		for len(p.Hand) > 0 {
+			p.discard(0)
+		}
+	}
+
+	for i, p := range g.Players {
+		if len(p.Completed) >= g.Rules.MissionsToWin {
+			g.Winner = i
+		}
+	}
+	for i, p := range g.Players {
+		alive := p.Reserve != nil
+		for _, c := range p.FrontLine {
+			alive = alive || !c.KO
+		}
+		if !alive && g.Winner < 0 {
+			g.Winner = 1 - i
+		}
+	}
+	if g.Winner < 0 && len(g.Players[0].DrawPile) == 0 && len(g.Players[1].DrawPile) == 0 {
+		switch a, b := len(g.Players[0].Completed), len(g.Players[1].Completed); {
+		case a > b:
+			g.Winner = 0
+		case b > a:
+			g.Winner = 1
+		}
+	}
+	if g.Winner >= 0 {
+		g.logf(g.Winner, "game", "%s wins", g.Players[g.Winner].Name)
+	}
+}
+
+// bringInReserve moves player me's Reserve to the Front Line once one of
+// its characters is KO'd. It joins at the next position, so the KO'd
+// character's position and placed cards stay as they were.
+func (g *Game) bringInReserve(me int) {
+	p := g.Players[me]
+	if p.Reserve == nil {
+		return
+	}
+	for _, c := range p.FrontLine {
+		if c.KO {
+			p.FrontLine = append(p.FrontLine, p.Reserve)
+			g.logf(me, "reserve", "%s joins the Front Line", p.Reserve.Name)
+			p.Reserve = nil
+			return
+		}
+	}
+}
+
+// Play runs battles until the game is decided or maxBattles is reached.
+func (g *Game) Play(ctrl [2]Controller, maxBattles int) error {
+	for g.Winner < 0 && g.Battle < maxBattles {
+		if _, err := g.PlayBattle(ctrl); err != nil {
+			return err
+		}
+		if len(g.Players[0].DrawPile) == 0 && len(g.Players[1].DrawPile) == 0 && g.Winner < 0 {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func character(id string, e, f, s, i int) Card {
+	return Card{ID: id, Name: id, Kind: KindCharacter, Grid: Grid{e, f, s, i}}
+}
+
+func power(id string, t PowerType, v int) Card {
+	return Card{ID: id, Name: id, Kind: KindPower, Power: t, Value: v}
+}
+
+func special(id, owner, text string) Card {
+	return Card{ID: id, Name: owner + " - " + id, Kind: KindSpecial, Owner: owner, Effect: ParseEffect(text)}
+}
+
+func mission(id string) Card { return Card{ID: id, Name: id, Kind: KindMission} }
+
+// newTestGame deals stacked decks so the first HandSize cards are the hand.
+func newTestGame(t *testing.T, a, b Setup) *Game {
+	t.Helper()
+	a.Stacked, b.Stacked = true, true
+	g, err := NewGame(Rules{HandSize: 4, MissionsToWin: 7, MaxVenture: 7}, [2]Setup{a, b}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestCheckDefenseNeedsTheAttackType(t *testing.T) {
+	g := newTestGame(t,
+		Setup{Name: "A", FrontLine: []Card{character("Hulk", 6, 6, 8, 2)}},
+		Setup{Name: "B", FrontLine: []Card{character("Storm", 7, 6, 4, 5)}, Deck: []Card{
+			power("6F", Fighting, 6), power("6E", Energy, 6), power("4E", Energy, 4), power("5A", AnyPower, 5),
+			special("Dodge", "Storm", "Avoid 1 Fighting or Strength attack."),
+			special("Shield", "Storm", "Avoid any attack."),
+			special("Zap", "Storm", "Acts as a level 5 Energy attack."),
+		}},
+	)
+	b := g.Players[1]
+	b.Hand, b.DrawPile = b.DrawPile, nil
+	g.pending = &pendingAttack{card: power("5E", Energy, 5), typ: Energy, level: 5, target: 0}
+
+	cases := map[string]string{
+		"6E":     "",
+		"5A":     "",
+		"Shield": "",
+		"6F":     "cannot stop a Energy attack",
+		"4E":     "below the level 5",
+		"Dodge":  "does not avoid",
+		"Zap":    "does not avoid",
+	}
+	for id, want := range cases {
+		_, err := g.checkDefense(1, Defense{CardID: id})
+		if (want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), want)) {
+			t.Errorf("defend with %s: err = %v, want %q", id, err, want)
+		}
+	}
+	var legal []string
+	for _, d := range g.LegalDefenses(1) {
+		legal = append(legal, d.CardID)
+	}
+	if got := strings.Join(legal, ","); got != "6E,5A,Shield" {
+		t.Errorf("LegalDefenses = %s", got)
+	}
+}
+
+func TestBattleRejectsCrossTypeDefense(t *testing.T) {
+	g := newTestGame(t,
+		Setup{Name: "A", FrontLine: []Card{character("Hulk", 6, 6, 8, 2)}, Deck: []Card{power("5E", Energy, 5)}},
+		Setup{Name: "B", FrontLine: []Card{character("Storm", 7, 6, 4, 5)}, Deck: []Card{power("6F", Fighting, 6)}},
+	)
+	a := &Script{Steps: []Step{{Kind: "attack", CardID: "5E"}}}
+	b := &Script{Steps: []Step{{Kind: "defend", CardID: "6F"}}}
+	_, err := g.PlayBattle([2]Controller{a, b})
+	if err == nil || !strings.Contains(err.Error(), "player 2 defense") || !strings.Contains(err.Error(), "Energy attack") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBattleHitsAndMissions(t *testing.T) {
+	g := newTestGame(t,
+		Setup{Name: "A", FrontLine: []Card{character("Hulk", 6, 6, 8, 2)},
+			Deck: []Card{power("8S", Strength, 8), power("5E", Energy, 5)}, Missions: []Card{mission("M1"), mission("M2")}},
+		Setup{Name: "B", FrontLine: []Card{character("Storm", 7, 6, 4, 5)},
+			Deck: []Card{power("7E", Energy, 7)}, Missions: []Card{mission("N1")}},
+	)
+	a := &Script{Ventures: []int{2}, Steps: []Step{{Kind: "attack", CardID: "8S"}, {Kind: "take"}, {Kind: "pass"}}}
+	b := &Script{Ventures: []int{1}, Steps: []Step{{Kind: "take"}, {Kind: "attack", CardID: "7E"}, {Kind: "pass"}}}
+	res, err := g.PlayBattle([2]Controller{a, b})
+	if err != nil || a.Err != nil || b.Err != nil {
+		t.Fatal(err, a.Err, b.Err)
+	}
+	if res.HitPoints != [2]int{7, 8} || res.Winner != 0 {
+		t.Errorf("result = %+v", res)
+	}
+	pa, pb := g.Players[0], g.Players[1]
+	if len(pa.Completed) != 2 || len(pb.Defeated) != 1 || len(pb.Missions) != 0 {
+		t.Errorf("A completed %d, B defeated %d", len(pa.Completed), len(pb.Defeated))
+	}
+	if pb.FrontLine[0].RecordTotal() != 8 || len(pb.FrontLine[0].Hits) != 0 {
+		t.Errorf("Storm record = %+v", pb.FrontLine[0].Record)
+	}
+	if len(pa.Hand) != 0 || len(pa.DeadPile) != 2 {
+		t.Errorf("A hand %d, dead pile %d; the hand is discarded after the battle", len(pa.Hand), len(pa.DeadPile))
+	}
+}
+
+func TestReserveJoinsAfterKO(t *testing.T) {
+	reserve := character("Wolverine", 4, 7, 5, 3)
+	g := newTestGame(t,
+		Setup{Name: "A", FrontLine: []Card{character("Hulk", 6, 6, 8, 2)}, Deck: []Card{power("8S", Strength, 8)}},
+		Setup{Name: "B", FrontLine: []Card{character("Kitty", 1, 2, 1, 3)}, Reserve: &reserve},
+	)
+	a := &Script{Steps: []Step{{Kind: "attack", CardID: "8S"}, {Kind: "pass"}, {Kind: "pass"}}}
+	b := &Script{Steps: []Step{{Kind: "take"}, {Kind: "pass"}, {Kind: "pass"}}}
+	if _, err := g.PlayBattle([2]Controller{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	pb := g.Players[1]
+	if !pb.FrontLine[0].KO || g.Winner != -1 {
+		t.Fatalf("Kitty KO = %v, winner = %d; the Reserve keeps B in the game", pb.FrontLine[0].KO, g.Winner)
+	}
+	if len(pb.FrontLine) != 1 || pb.Reserve == nil {
+		t.Fatal("the Reserve moved before the next Draw Phase")
+	}
+	if _, err := g.PlayBattle([2]Controller{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	if pb.Reserve != nil || len(pb.FrontLine) != 2 || pb.FrontLine[1].Name != "Wolverine" {
+		t.Errorf("Front Line = %v, Reserve = %v", pb.FrontLine, pb.Reserve)
+	}
+	if atk := g.LegalAttacks(0); len(atk) != 0 {
+		t.Errorf("A has no cards left but can attack: %v", atk)
+	}
+}
+
+func TestReserveMustBeCharacter(t *testing.T) {
+	p := power("5E", Energy, 5)
+	_, err := NewGame(DefaultRules(), [2]Setup{
+		{FrontLine: []Card{character("Hulk", 6, 6, 8, 2)}, Reserve: &p},
+		{FrontLine: []Card{character("Storm", 7, 6, 4, 5)}},
+	}, 1)
+	if err == nil {
+		t.Error("a Power card was accepted as the Reserve")
+	}
+}
+
+func TestParseEffect(t *testing.T) {
+	cases := []struct {
+		text string
+		want Effect
+	}{
+		{"Acts as a level 6 Strength attack. May not be defended with a Special card.", Effect{Kind: EffectAttack, Type: Strength, Level: 6, NoSpecialDefense: true}},
+		{"Avoid 1 Energy, Fighting or Intellect attack. One Per Deck", Effect{Kind: EffectAvoid, Avoid: []PowerType{Energy, Fighting, Intellect}}},
+		{"Avoid any attack.", Effect{Kind: EffectAvoid}},
+		{"Draw 2 cards.", Effect{Kind: EffectUnsupported}},
+		{"One Per Deck", Effect{}},
+	}
+	for _, c := range cases {
+		got := ParseEffect(c.text)
+		got.Text = ""
+		if got.Kind != c.want.Kind || got.Type != c.want.Type || got.Level != c.want.Level ||
+			got.NoSpecialDefense != c.want.NoSpecialDefense || len(got.Avoid) != len(c.want.Avoid) {
+			t.Errorf("ParseEffect(%q) = %+v, want %+v", c.text, got, c.want)
+			continue
+		}
+		for i := range got.Avoid {
+			if got.Avoid[i] != c.want.Avoid[i] {
+				t.Errorf("ParseEffect(%q).Avoid = %v", c.text, got.Avoid)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ---------- Scripted controller ----------
+
+// Step is one scripted decision. Kind is "attack", "pass", "defend" or
+// "take" (take the hit).
+type Step struct {
+	Kind     string `json:"kind"`
+	CardID   string `json:"card,omitempty"`
+	Attacker int    `json:"attacker,omitempty"`
+	Target   int    `json:"target,omitempty"`
+}
+
+// Script replays fixed decisions, for regression-testing rules questions.
+// When the script runs out, Fallback decides (nil passes and takes hits).
+// A step of the wrong kind is recorded in Err and treated as a pass.
+type Script struct {
+	Ventures []int
+	Steps    []Step
+	Fallback Controller
+	Err      error
+
+	battle int
+}
+
+func (s *Script) Venture(g *Game, me int) int {
+	s.battle++
+	if s.battle <= len(s.Ventures) {
+		return s.Ventures[s.battle-1]
+	}
+	if s.Fallback != nil {
+		return s.Fallback.Venture(g, me)
+	}
+	return 0
+}
+
+func (s *Script) next(want ...string) (Step, bool) {
+	if len(s.Steps) == 0 {
+		return Step{}, false
+	}
+	st := s.Steps[0]
+	for _, w := range want {
+		if st.Kind == w {
+			s.Steps = s.Steps[1:]
+			return st, true
+		}
+	}
+	if s.Err == nil {
+		s.Err = fmt.Errorf("script step %q when expecting %v", st.Kind, want)
+	}
+	s.Steps = nil
+	return Step{Kind: want[len(want)-1]}, true
+}
+
+func (s *Script) Attack(g *Game, me int) (Attack, bool) {
+	st, ok := s.next("attack", "pass")
+	if !ok {
+		if s.Fallback != nil {
+			return s.Fallback.Attack(g, me)
+		}
+		return Attack{}, false
+	}
+	if st.Kind != "attack" {
+		return Attack{}, false
+	}
+	return Attack{CardID: st.CardID, Attacker: st.Attacker, Target: st.Target}, true
+}
+
+func (s *Script) Defend(g *Game, me int, a Attack) (Defense, bool) {
+	st, ok := s.next("defend", "take")
+	if !ok {
+		if s.Fallback != nil {
+			return s.Fallback.Defend(g, me, a)
+		}
+		return Defense{}, false
+	}
+	if st.Kind != "defend" {
+		return Defense{}, false
+	}
+	return Defense{CardID: st.CardID}, true
+}
+
+// ---------- Greedy controller ----------
+
+// Greedy attacks with its strongest legal card, targets the opposing
+// character closest to a KO and defends with the cheapest card that works.
+type Greedy struct{}
+
+func (Greedy) Venture(g *Game, me int) int {
+	attacks := map[string]bool{}
+	for _, a := range g.LegalAttacks(me) {
+		attacks[a.CardID] = true
+	}
+	return len(attacks) / 2
+}
+
+func (Greedy) Attack(g *Game, me int) (Attack, bool) {
+	legal := g.LegalAttacks(me)
+	if len(legal) == 0 {
+		return Attack{}, false
+	}
+	p, opp := g.Players[me], g.Players[1-me]
+	level := func(a Attack) int {
+		_, lvl, _ := attackOf(p.Hand[handIndex(p.Hand, a.CardID)], p.FrontLine[a.Attacker])
+		return lvl
+	}
+	left := func(a Attack) int {
+		t := opp.FrontLine[a.Target]
+		return t.HitsToKO() - t.RecordTotal()
+	}
+	sort.SliceStable(legal, func(i, j int) bool {
+		if li, lj := level(legal[i]), level(legal[j]); li != lj {
+			return li > lj
+		}
+		return left(legal[i]) < left(legal[j])
+	})
+	return legal[0], true
+}
+
+func (Greedy) Defend(g *Game, me int, a Attack) (Defense, bool) {
+	legal := g.LegalDefenses(me)
+	if len(legal) == 0 {
+		return Defense{}, false
+	}
+	hand := g.Players[me].Hand
+	cost := func(d Defense) int {
+		c := hand[handIndex(hand, d.CardID)]
+		if c.Kind == KindPower {
+			return c.Value
+		}
+		return 100 // keep Specials for when nothing else works
+	}
+	sort.SliceStable(legal, func(i, j int) bool { return cost(legal[i]) < cost(legal[j]) })
+	return legal[0], true
+}
@@ -0,0 +1,14 @@
+module opscrape
+
+go 1.25.3
+
+require (
+	golang.org/x/image v0.33.0
+	golang.org/x/term v0.37.0
+	gopkg.in/yaml.v3 v3.0.1
+	scraper v0.0.0
+)
+
+require golang.org/x/sys v0.38.0 // indirect
+
+replace scraper => ../scraper
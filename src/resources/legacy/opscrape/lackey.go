@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/lackey"
+)
+
+func runLackey(args []string) error {
+	var root, out, name, sets, imageURL string
+	var copyImages, zip bool
+	fs := newFlagSet("lackey", &root)
+	fs.StringVar(&out, "out", "lackey/OverPowerLegacy", "Plugin directory to write")
+	fs.StringVar(&name, "name", "", "Plugin name (default: the directory name)")
+	fs.StringVar(&sets, "sets", "", "Comma-separated set tags to include (default all)")
+	fs.StringVar(&imageURL, "image-url", "", "Base URL the images are served from (default: the plugin's own setimages folder)")
+	fs.BoolVar(&copyImages, "copy-images", true, "Copy downloaded images into the plugin")
+	fs.BoolVar(&zip, "zip", false, "Also write <out>.zip")
+	fs.Parse(args)
+	if imageURL == "" && !copyImages {
+		return fmt.Errorf("-copy-images=false needs -image-url, or the plugin has no images")
+	}
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	var only []string
+	for _, s := range strings.Split(sets, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			only = append(only, s)
+		}
+	}
+	p, err := lackey.Generate(cat, out, lackey.Options{Name: name, Sets: only, ImageURL: imageURL, CopyImages: copyImages, Zip: zip})
+	if err != nil {
+		return err
+	}
+	for _, s := range p.Sets {
+		fmt.Printf("[OK ] %-6s %-32s %d cards\n", s.Code, s.Name, s.Cards)
+	}
+	if len(p.MissingImages) > 0 {
+		fmt.Printf("[WARN] %d cards have no downloaded image and no CardImageURLs entry\n", len(p.MissingImages))
+	}
+	fmt.Printf("[OK ] plugin -> %s\n", p.Dir)
+	if p.Zip != "" {
+		fmt.Printf("[OK ] zip -> %s\n", p.Zip)
+	}
+	return nil
+}
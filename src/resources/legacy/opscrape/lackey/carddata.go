@@ -0,0 +1,153 @@
+// Package lackey generates a LackeyCCG plugin from the catalog: card data,
+// set list, image URL list and images, optionally zipped for upload.
+package lackey
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"opscrape/catalog"
+)
+
+// ---------- Card data ----------
+
+// Columns is the carddata.txt header. Lackey needs Name, Set and ImageFile
+// first; the rest are shown in its card info pane and used by its filters.
+var Columns = []string{
+	"Name", "Set", "ImageFile", "Type", "Subtype", "Rarity", "Printing",
+	"Characters", "Numbers", "Control", "Text", "ID",
+}
+
+// Row is one carddata.txt line.
+type Row struct {
+	Name, Set, ImageFile, Type, Subtype, Rarity, Printing string
+	Characters, Numbers, Control, Text, ID                string
+
+	Card *catalog.Card
+}
+
+func (r Row) cells() []string {
+	return []string{
+		r.Name, r.Set, r.ImageFile, r.Type, r.Subtype, r.Rarity, r.Printing,
+		r.Characters, r.Numbers, r.Control, r.Text, r.ID,
+	}
+}
+
+// flatten keeps a cell on one line: carddata.txt is tab-separated with no
+// quoting, so tabs and line breaks in Game Text become spaces.
+func flatten(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var badImageChars = regexp.MustCompile(`[^A-Za-z0-9._\-]+`)
+
+// imageFile is the card's ID made safe for every file system Lackey runs on,
+// with the downloaded image's extension (".jpg" when it has none). It is the
+// name the image has in setimages and CardImageURLs.txt.
+func imageFile(c *catalog.Card) string {
+	ext := strings.ToLower(filepath.Ext(c.ImageName))
+	if ext == "" {
+		ext = ".jpg"
+	}
+	return strings.Trim(badImageChars.ReplaceAllString(c.ID, "_"), "_") + ext
+}
+
+// BuildRows lists every own card of the given sets (all when empty). Lackey
+// keys cards by name within a set, so repeated names get the printing,
+// "(var)" or, as a last resort, the ID appended.
+func BuildRows(cat *catalog.Catalog, sets []string) []Row {
+	want := map[string]bool{}
+	for _, s := range sets {
+		want[strings.ToUpper(s)] = true
+	}
+	var rows []Row
+	used := map[string]bool{}
+	for _, s := range cat.Sets {
+		if len(want) > 0 && !want[s.Code] {
+			continue
+		}
+		for _, c := range s.OwnCards() {
+			r := Row{
+				Name: flatten(c.Name), Set: c.Field("Set"), ImageFile: imageFile(c),
+				Type: c.Type(), Subtype: flatten(c.Field("Subtype")), Rarity: c.Rarity(), Printing: c.Printing(),
+				Characters: flatten(c.Field("Characters")), Numbers: flatten(c.Numbers()),
+				Control: c.Field("Control"), Text: flatten(c.GameText()), ID: c.ID, Card: c,
+			}
+			key := func() string { return r.Set + "\x00" + strings.ToLower(r.Name) }
+			if used[key()] && r.Printing != "Normal" {
+				r.Name += " (" + r.Printing + ")"
+			}
+			if used[key()] && strings.HasSuffix(c.ID, "_(var)") {
+				r.Name += " (var)"
+			}
+			if used[key()] {
+				r.Name += " [" + c.ID + "]"
+			}
+			used[key()] = true
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// WriteCardData writes rows as Lackey's tab-separated carddata.txt.
+func WriteCardData(w io.Writer, rows []Row) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, strings.Join(Columns, "\t"))
+	for _, r := range rows {
+		fmt.Fprintln(bw, strings.Join(r.cells(), "\t"))
+	}
+	return bw.Flush()
+}
+
+// ReadCardData parses a carddata.txt. Columns are matched by header name, so
+// files with extra or reordered columns still read.
+func ReadCardData(r io.Reader) ([]Row, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("carddata: empty file")
+	}
+	col := map[string]int{}
+	for i, h := range strings.Split(sc.Text(), "\t") {
+		col[strings.TrimSpace(h)] = i
+	}
+	for _, need := range Columns[:3] {
+		if _, ok := col[need]; !ok {
+			return nil, fmt.Errorf("carddata: missing %s column", need)
+		}
+	}
+	var rows []Row
+	line := 1
+	for sc.Scan() {
+		line++
+		if sc.Text() == "" {
+			continue
+		}
+		cells := strings.Split(sc.Text(), "\t")
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(cells) {
+				return cells[i]
+			}
+			return ""
+		}
+		r := Row{
+			Name: get("Name"), Set: get("Set"), ImageFile: get("ImageFile"), Type: get("Type"),
+			Subtype: get("Subtype"), Rarity: get("Rarity"), Printing: get("Printing"),
+			Characters: get("Characters"), Numbers: get("Numbers"), Control: get("Control"),
+			Text: get("Text"), ID: get("ID"),
+		}
+		if r.Name == "" || r.Set == "" {
+			return nil, fmt.Errorf("carddata: line %d: missing Name or Set", line)
+		}
+		rows = append(rows, r)
+	}
+	return rows, sc.Err()
+}
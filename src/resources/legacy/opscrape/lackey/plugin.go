@@ -0,0 +1,240 @@
+package lackey
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+	"scraper/setcode"
+)
+
+// ---------- Plugin ----------
+
+// Options control Generate.
+type Options struct {
+	Name       string   // plugin name shown in Lackey
+	Sets       []string // set tags to include; empty means all
+	ImageURL   string   // base URL for CardImageURLs.txt; "" points at the copied images
+	CopyImages bool     // copy downloaded images into sets/setimages; needed when ImageURL is ""
+	Zip        bool     // also write <dir>.zip
+}
+
+// Plugin is what Generate wrote.
+type Plugin struct {
+	Dir           string
+	Zip           string // "" unless Options.Zip
+	Rows          []Row
+	Sets          []SetInfo
+	MissingImages []string // IDs with no downloaded image
+}
+
+// SetInfo is one sets/setlist.txt line.
+type SetInfo struct {
+	Code, Name string
+	Cards      int
+}
+
+// Files inside a plugin directory.
+const (
+	PluginInfoFile = "plugininfo.txt"
+	CardDataFile   = "sets/carddata.txt"
+	SetListFile    = "sets/setlist.txt"
+	ImageURLsFile  = "CardImageURLs.txt"
+	SetImagesDir   = "sets/setimages"
+)
+
+// Generate writes a Lackey plugin for the catalog into dir. The images come
+// from opt.ImageURL or from the copies in setimages, so one of the two must
+// be given.
+func Generate(cat *catalog.Catalog, dir string, opt Options) (*Plugin, error) {
+	if opt.ImageURL == "" && !opt.CopyImages {
+		return nil, fmt.Errorf("no image source: give an image URL or copy the images into the plugin")
+	}
+	if opt.Name == "" {
+		opt.Name = filepath.Base(dir)
+	}
+	p := &Plugin{Dir: dir, Rows: BuildRows(cat, opt.Sets)}
+	if len(p.Rows) == 0 {
+		return nil, fmt.Errorf("no cards for sets %v", opt.Sets)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, SetImagesDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(opt.ImageURL, "/")
+	if base == "" {
+		abs, err := filepath.Abs(filepath.Join(dir, SetImagesDir))
+		if err != nil {
+			return nil, err
+		}
+		base = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+
+	counts := map[string]int{}
+	var urls []string
+	for _, r := range p.Rows {
+		counts[r.Set]++
+		src := r.Card.ImagePath()
+		if src == "" {
+			p.MissingImages = append(p.MissingImages, r.ID)
+			continue
+		}
+		rel := path.Join(r.Set, r.ImageFile)
+		urls = append(urls, rel+"\t"+base+"/"+rel)
+		if opt.CopyImages {
+			if err := copyFile(src, filepath.Join(dir, SetImagesDir, filepath.FromSlash(rel))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for code, n := range counts {
+		p.Sets = append(p.Sets, SetInfo{Code: code, Name: setcode.Name(code), Cards: n})
+	}
+	sort.Slice(p.Sets, func(i, j int) bool { return p.Sets[i].Code < p.Sets[j].Code })
+
+	err := writeFile(filepath.Join(dir, CardDataFile), func(w io.Writer) error { return WriteCardData(w, p.Rows) })
+	if err == nil {
+		err = writeFile(filepath.Join(dir, SetListFile), func(w io.Writer) error {
+			for _, s := range p.Sets {
+				fmt.Fprintf(w, "%s\t%s\t%d\n", s.Code, s.Name, s.Cards)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = writeFile(filepath.Join(dir, ImageURLsFile), func(w io.Writer) error {
+			for _, u := range urls {
+				fmt.Fprintln(w, u)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = writeFile(filepath.Join(dir, PluginInfoFile), func(w io.Writer) error {
+			fmt.Fprintf(w, "name\t%s\n", opt.Name)
+			fmt.Fprintf(w, "carddata\t%s\n", CardDataFile)
+			fmt.Fprintf(w, "setlist\t%s\n", SetListFile)
+			fmt.Fprintf(w, "imageurls\t%s\n", ImageURLsFile)
+			fmt.Fprintf(w, "setimages\t%s\n", SetImagesDir)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.Zip {
+		p.Zip = strings.TrimSuffix(dir, string(filepath.Separator)) + ".zip"
+		if err := zipDir(dir, p.Zip, filepath.Base(dir)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ReadImageURLs parses CardImageURLs.txt into image path -> URL.
+func ReadImageURLs(r io.Reader) (map[string]string, error) {
+	out := map[string]string{}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		if sc.Text() == "" {
+			continue
+		}
+		rel, u, ok := strings.Cut(sc.Text(), "\t")
+		if !ok || rel == "" || u == "" {
+			return nil, fmt.Errorf("%s: line %d: want <image>\\t<url>", ImageURLsFile, n)
+		}
+		out[rel] = u
+	}
+	return out, sc.Err()
+}
+
+// ---------- Files ----------
+
+func writeFile(path string, fill func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := fill(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// zipDir archives dir under prefix/ so the zip unpacks into Lackey's
+// plugins folder as one directory.
+func zipDir(dir, zipPath, prefix string) error {
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(path.Join(prefix, filepath.ToSlash(rel)))
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(w, in)
+		return err
+	})
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package lackey
+
+import (
+	"archive/zip"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"opscrape/catalog"
+	"scraper/setcode"
+)
+
+const testManifest = `Name,ImageName,PageURL,Characters,Control,Game Text,Numbers,Printing,Rarity,Type
+Azrael™,Azrael-DCOP.jpg,https://cardguide.fandom.com/wiki/Azrael_(DCOP),Azrael,,,Energy 5Fighting 7Strength 6Intellect 3,Normal,Rare,Character
+Azrael™,Azrael-var-DCOP.jpg,https://cardguide.fandom.com/wiki/Azrael_(DCOP)_(var),Azrael,,,Energy 5Fighting 7Strength 6Intellect 3,Normal,Rare,Character
+Batman™ - Detective,Batman-Detective-DCOP.png,https://cardguide.fandom.com/wiki/Batman_-_Detective_(DCOP),Batman,,"Acts as a level 5 Intellect attack.
+	May not be defended with a Special card.",,Normal,Uncommon,Special
+Batman™ - Detective,Batman-Detective-Chromium-DCOP.jpg,https://cardguide.fandom.com/wiki/Batman_-_Detective_(Chromium)_(DCOP),Batman,,"Acts as a level 5 Intellect attack.",,Chromium,Uncommon,Special
+`
+
+// newTestCatalog writes a one-set legacy root with images for all but the
+// Chromium card.
+func newTestCatalog(t *testing.T) *catalog.Catalog {
+	t.Helper()
+	root := t.TempDir()
+	set := filepath.Join(root, "dcop")
+	if err := os.MkdirAll(filepath.Join(set, "images"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(set, "manifest.csv"), []byte(testManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range []string{"Azrael-DCOP.jpg", "Azrael-var-DCOP.jpg", "Batman-Detective-DCOP.png"} {
+		if err := os.WriteFile(filepath.Join(set, "images", img), []byte("img:"+img), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+func TestGenerateRoundTrip(t *testing.T) {
+	cat := newTestCatalog(t)
+	dir := filepath.Join(t.TempDir(), "OverPowerLegacy")
+	p, err := Generate(cat, dir, Options{ImageURL: "https://example.org/op/", CopyImages: true, Zip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// carddata.txt reads back to the rows that were written.
+	f, err := os.Open(filepath.Join(dir, CardDataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := ReadCardData(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(p.Rows) {
+		t.Fatalf("read %d rows, wrote %d", len(got), len(p.Rows))
+	}
+	names := map[string]bool{}
+	for i, r := range got {
+		if want := p.Rows[i].cells(); !reflect.DeepEqual(r.cells(), want) {
+			t.Errorf("row %d:\n got %q\nwant %q", i, r.cells(), want)
+		}
+		key := r.Set + "/" + r.Name
+		if names[key] {
+			t.Errorf("duplicate Lackey name %s", key)
+		}
+		names[key] = true
+	}
+	for _, want := range []string{"DCOP/Azrael™", "DCOP/Azrael™ (var)", "DCOP/Batman™ - Detective (Chromium)"} {
+		if !names[want] {
+			t.Errorf("missing card %s; have %v", want, names)
+		}
+	}
+	if got[2].Text != "Acts as a level 5 Intellect attack. May not be defended with a Special card." {
+		t.Errorf("multi-line Game Text not flattened: %q", got[2].Text)
+	}
+
+	// Every URL points at a copied image; the card without one is reported.
+	uf, err := os.Open(filepath.Join(dir, ImageURLsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uf.Close()
+	urls, err := ReadImageURLs(uf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 {
+		t.Errorf("got %d image URLs, want 3: %v", len(urls), urls)
+	}
+	// Each row's ImageFile is the file its URL and copy are named after,
+	// extension included.
+	for _, r := range got {
+		if r.ID == "Batman_-_Detective_(Chromium)_(DCOP)" {
+			continue
+		}
+		if _, ok := urls[r.Set+"/"+r.ImageFile]; !ok {
+			t.Errorf("%s: ImageFile %q has no image URL", r.ID, r.ImageFile)
+		}
+	}
+	if got[2].ImageFile != "Batman_-_Detective__DCOP.png" {
+		t.Errorf("ImageFile = %q, want the .png extension", got[2].ImageFile)
+	}
+	for rel, u := range urls {
+		if u != "https://example.org/op/"+rel {
+			t.Errorf("%s -> %s", rel, u)
+		}
+		if _, err := os.Stat(filepath.Join(dir, SetImagesDir, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("image not copied: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(p.MissingImages, []string{"Batman_-_Detective_(Chromium)_(DCOP)"}) {
+		t.Errorf("MissingImages = %v", p.MissingImages)
+	}
+
+	// The set list names the set and its card count.
+	setList, err := os.ReadFile(filepath.Join(dir, SetListFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "DCOP\t" + setcode.Name("DCOP") + "\t4\n"; string(setList) != want {
+		t.Errorf("setlist = %q, want %q", setList, want)
+	}
+
+	// The zip holds the same files under the plugin directory name.
+	zr, err := zip.OpenReader(p.Zip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	inZip := map[string]*zip.File{}
+	for _, zf := range zr.File {
+		inZip[zf.Name] = zf
+	}
+	for _, name := range []string{PluginInfoFile, CardDataFile, SetListFile, ImageURLsFile} {
+		if inZip[path.Join("OverPowerLegacy", name)] == nil {
+			t.Errorf("zip is missing %s", name)
+		}
+	}
+	rc, err := inZip[path.Join("OverPowerLegacy", CardDataFile)].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	zipped, err := ReadCardData(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zipped) != len(got) {
+		t.Errorf("zipped carddata has %d rows, want %d", len(zipped), len(got))
+	}
+}
+
+func TestGenerateImageSource(t *testing.T) {
+	cat := newTestCatalog(t)
+	if _, err := Generate(cat, t.TempDir(), Options{}); err == nil || !strings.Contains(err.Error(), "no image source") {
+		t.Errorf("err = %v, want no image source", err)
+	}
+
+	// Served images are not copied, and every URL is on the server.
+	dir := t.TempDir()
+	if _, err := Generate(cat, dir, Options{ImageURL: "https://example.org/op"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, SetImagesDir)); len(entries) != 0 {
+		t.Errorf("copied %d entries without CopyImages", len(entries))
+	}
+	f, err := os.Open(filepath.Join(dir, ImageURLsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	urls, err := ReadImageURLs(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rel, u := range urls {
+		if u != "https://example.org/op/"+rel {
+			t.Errorf("%s -> %s", rel, u)
+		}
+	}
+}
+
+func TestReadCardDataRejectsMissingColumns(t *testing.T) {
+	_, err := ReadCardData(strings.NewReader("Name\tType\nAzrael\tCharacter\n"))
+	if err == nil || !strings.Contains(err.Error(), "Set") {
+		t.Errorf("err = %v, want a missing Set column", err)
+	}
+}
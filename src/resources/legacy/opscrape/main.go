@@ -0,0 +1,88 @@
+// Command opscrape works on the catalog the per-set scrapers produce: every
+// <set>/manifest.csv under the legacy directory, plus the downloaded images.
+//
+//	go run . checklist -root .. -data ../../../../data -migrations ../../../../migrations
+//	go run . collection -in collection_cards.json -cards deckbuilder_cards.csv -want want.md
+//	go run . trade -a alice.csv -b bob.csv -format json
+//	go run . booster -set CLOP -mode sealed -players 8 -packs 6 -seed 42 -out pools
+//	go run . rules -coverage
+//	go run . rules -scenario scenario.json
+//	go run . simulate -deck deck.json -trials 20000 -seed 7 -json sim.json
+//	go run . search type:special char:"Absorbing Man" text:avoid
+//	go run . resolve -in names.txt -format csv
+//	go run . deck import -in wolverine.txt -out deck.json && go run . deck export -deck deck.json -format lackey
+//	go run . lackey -out /tmp/OverPowerLegacy -image-url https://example.org/op -zip
+//	go run . tts -deck deck.json -width 300 -base-url https://example.org/tts
+//	go run . site -out ../../../../docs/site/content -static ../../../../docs/site/static/images
+//	go run . tui -images sixel
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"checklist":  {"Write checklist CSVs and checklist_<set> migrations", runChecklist},
+	"collection": {"Report collection completion, trades and a want-list", runCollection},
+	"trade":      {"Propose balanced trades between two collections", runTrade},
+	"booster":    {"Open seeded booster packs, sealed pools and draft pods", runBooster},
+	"rules":      {"Resolve battles headlessly; report card coverage or replay a scenario", runRules},
+	"simulate":   {"Monte Carlo opening hands for a deck JSON", runSimulate},
+	"search":     {"Query the catalog, e.g. type:special set:CLOP numbers.energy>=6", runSearch},
+	"resolve":    {"Map free-text card names to catalog IDs with confidence scores", runResolve},
+	"deck":       {"Import and export deck lists as text or LackeyCCG .dek", runDeck},
+	"lackey":     {"Generate a LackeyCCG plugin from the catalog and images", runLackey},
+	"tts":        {"Build Tabletop Simulator sprite sheets and a saved deck object", runTTS},
+	"site":       {"Export one Markdown page per card plus set, type and character listings", runSite},
+	"tui":        {"Browse the catalog in the terminal and write curation overrides", runTUI},
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	name := flag.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Printf("[FATAL] unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(flag.Args()[1:]); err != nil {
+		fmt.Println("[FATAL]", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: opscrape <command> [flags]")
+	fmt.Println()
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Printf("  %-12s %s\n", n, commands[n].summary)
+	}
+	fmt.Println()
+	fmt.Println("Run 'opscrape <command> -h' for command flags.")
+}
+
+// newFlagSet returns a FlagSet for a subcommand with the shared -root flag.
+func newFlagSet(name string, root *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("opscrape "+name, flag.ExitOnError)
+	fs.StringVar(root, "root", "..", "Legacy directory holding <set>/manifest.csv")
+	return fs
+}
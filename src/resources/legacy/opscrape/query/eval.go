@@ -0,0 +1,172 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/engine"
+	"scraper/setcode"
+)
+
+// Expr is a compiled query.
+type Expr interface {
+	Match(c *catalog.Card) bool
+	String() string
+}
+
+func (e and) Match(c *catalog.Card) bool { return e.l.Match(c) && e.r.Match(c) }
+func (e or) Match(c *catalog.Card) bool  { return e.l.Match(c) || e.r.Match(c) }
+func (e not) Match(c *catalog.Card) bool { return !e.e.Match(c) }
+func (all) Match(*catalog.Card) bool     { return true }
+
+// ---------- Terms ----------
+
+// valueKind says how a field compares.
+type valueKind int
+
+const (
+	textValue    valueKind = iota // ":" is a substring match
+	keywordValue                  // ":" is an exact match (Type, Set, Printing)
+	rankValue                     // ordered vocabulary (Rarity, Control)
+	numberValue                   // numbers.<type> and numbers.total
+)
+
+// term is one field comparison. num holds the parsed number or rank for
+// rankValue and numberValue fields.
+type term struct {
+	field string // canonical field, or "numbers.<type>"
+	op    string
+	value string
+	kind  valueKind
+	num   int
+}
+
+var keywordFields = map[string]bool{"Type": true, "Set": true, "Printing": true, "Subtype": true}
+
+// numberFields maps numbers.<sub> onto a grid index; -1 is the total.
+var numberFields = map[string]int{
+	"energy": int(engine.Energy), "fighting": int(engine.Fighting),
+	"strength": int(engine.Strength), "intellect": int(engine.Intellect),
+	"e": int(engine.Energy), "f": int(engine.Fighting), "s": int(engine.Strength), "i": int(engine.Intellect),
+	"total": -1,
+}
+
+func newTerm(pos int, field, op, value string) (Expr, error) {
+	t := term{op: op, value: value}
+	base, sub, dotted := strings.Cut(field, ".")
+	canon, ok := catalog.CanonicalField(base)
+	if !ok {
+		return nil, &Error{pos, fmt.Sprintf("unknown field %q", field)}
+	}
+	t.field = canon
+
+	switch {
+	case dotted:
+		if _, ok := numberFields[strings.ToLower(sub)]; canon != "Numbers" || !ok {
+			return nil, &Error{pos, fmt.Sprintf("unknown field %q; try numbers.energy, .fighting, .strength, .intellect or .total", field)}
+		}
+		t.field = "numbers." + strings.ToLower(sub)
+		t.kind = numberValue
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, &Error{pos, fmt.Sprintf("%s needs a number, got %q", field, value)}
+		}
+		t.num = n
+	case canon == "Rarity":
+		t.kind = rankValue
+		t.value = catalog.NormalizeRarity(value)
+		t.num = catalog.RarityRank(t.value)
+		if t.num == len(catalog.Rarities) {
+			return nil, &Error{pos, fmt.Sprintf("unknown rarity %q; one of %s", value, strings.Join(catalog.Rarities, ", "))}
+		}
+	case canon == "Control" && isComparison(op):
+		t.kind = rankValue
+		t.value = setcode.NormalizeControl(value)
+		n, ok := setcode.ControlOrdinal(t.value)
+		if !ok {
+			return nil, &Error{pos, fmt.Sprintf("unknown Control code %q", value)}
+		}
+		t.num = n
+	case keywordFields[canon]:
+		t.kind = keywordValue
+	}
+	if isComparison(op) && t.kind != numberValue && t.kind != rankValue {
+		return nil, &Error{pos, fmt.Sprintf("%s cannot be compared with %s", field, op)}
+	}
+	return t, nil
+}
+
+func isComparison(op string) bool { return op == "<" || op == "<=" || op == ">" || op == ">=" }
+
+func (t term) String() string {
+	if t.field == "Name" && t.op == ":" {
+		return quote(t.value)
+	}
+	f := strings.ReplaceAll(strings.ToLower(t.field), " ", "")
+	return f + t.op + quote(t.value)
+}
+
+// fold lower-cases and drops the trademark signs the wiki puts on names.
+func fold(s string) string {
+	return strings.ToLower(strings.NewReplacer("™", "", "®", "").Replace(strings.TrimSpace(s)))
+}
+
+func (t term) Match(c *catalog.Card) bool {
+	switch t.kind {
+	case numberValue:
+		g, ok := engine.ParseGrid(c.Numbers())
+		if !ok {
+			return false
+		}
+		n := g.Total()
+		if i := numberFields[strings.TrimPrefix(t.field, "numbers.")]; i >= 0 {
+			n = g[i]
+		}
+		return compare(n, t.op, t.num)
+	case rankValue:
+		var n int
+		if t.field == "Rarity" {
+			r := c.Rarity()
+			if r == "" {
+				return t.op == "!="
+			}
+			n = catalog.RarityRank(r)
+		} else {
+			var ok bool
+			if n, ok = setcode.ControlOrdinal(c.Field("Control")); !ok {
+				return false
+			}
+		}
+		return compare(n, t.op, t.num)
+	}
+
+	got, want := fold(c.Field(t.field)), fold(t.value)
+	switch t.op {
+	case "=":
+		return got == want
+	case "!=":
+		return got != want
+	}
+	if t.kind == keywordValue {
+		return got == want
+	}
+	return strings.Contains(got, want)
+}
+
+func compare(a int, op string, b int) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "!=":
+		return a != b
+	}
+	return a == b
+}
@@ -0,0 +1,137 @@
+// Package query parses and evaluates catalog search expressions such as
+//
+//	type:special char:"Absorbing Man" rarity:rare text:avoid set:CLOP numbers.energy>=6
+//
+// Terms are field:value (substring), field=value (exact) or a comparison
+// (<, <=, >, >=, !=). Bare words search the card name. Terms next to each
+// other are ANDed; OR, NOT (or a leading -) and parentheses combine them.
+// Field names go through catalog.CanonicalField, so "c", "char" and
+// "characters" are the same field.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ---------- Tokens ----------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp // : = != < <= > >=
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the query
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// Error is a lexing or parsing error at a byte offset in the query.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("query: at %d: %s", e.Pos, e.Msg) }
+
+// ---------- Lexer ----------
+
+func isOpByte(b byte) bool { return b == ':' || b == '=' || b == '<' || b == '>' || b == '!' }
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && r != '(' && r != ')' && r != '"' && (r > 0x7f || !isOpByte(byte(r)))
+}
+
+// lex splits a query into tokens. Quoted strings support \" and \\.
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		r := rune(src[i])
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(src) {
+					return nil, &Error{start, "unterminated string"}
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					b.WriteByte(src[i+1])
+					i += 2
+					continue
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			toks = append(toks, token{tokString, b.String(), start})
+		case isOpByte(src[i]):
+			start := i
+			op := src[i : i+1]
+			if i+1 < len(src) && src[i+1] == '=' && src[i] != ':' && src[i] != '=' {
+				op = src[i : i+2]
+			}
+			if op == "!" {
+				return nil, &Error{start, `"!" must be followed by "="`}
+			}
+			i += len(op)
+			toks = append(toks, token{tokOp, op, start})
+		case r == '-' && (len(toks) == 0 || toks[len(toks)-1].kind != tokOp):
+			toks = append(toks, token{tokNot, "-", i})
+			i++
+		default:
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if !isWordRune(r) {
+					break
+				}
+				i += size
+			}
+			word := src[start:i]
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			toks = append(toks, token{kind, word, start})
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
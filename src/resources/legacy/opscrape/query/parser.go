@@ -0,0 +1,146 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------- Grammar ----------
+//
+//	expr    = and { "OR" and }
+//	and     = unary { [ "AND" ] unary }
+//	unary   = ( "NOT" | "-" ) unary | primary
+//	primary = "(" expr ")" | term
+//	term    = word op value | value
+//	value   = word | string
+
+// Parse compiles a query. The empty query matches every card.
+func Parse(src string) (Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return all{}, nil
+	}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &Error{t.pos, fmt.Sprintf("unexpected %s", t)}
+	}
+	return e, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expr() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokString, tokNot, tokLParen:
+		default:
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{e}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, &Error{c.pos, fmt.Sprintf("expected ) to close the ( at %d, got %s", t.pos, c)}
+		}
+		return e, nil
+	case tokString:
+		return newTerm(t.pos, "name", ":", t.text)
+	case tokWord:
+		if p.peek().kind != tokOp {
+			return newTerm(t.pos, "name", ":", t.text)
+		}
+		op := p.next()
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokString {
+			return nil, &Error{v.pos, fmt.Sprintf("expected a value after %s%s, got %s", t.text, op.text, v)}
+		}
+		return newTerm(t.pos, t.text, op.text, v.text)
+	}
+	return nil, &Error{t.pos, fmt.Sprintf("unexpected %s", t)}
+}
+
+// ---------- Nodes ----------
+
+type and struct{ l, r Expr }
+type or struct{ l, r Expr }
+type not struct{ e Expr }
+type all struct{}
+
+func (e and) String() string { return e.l.String() + " " + e.r.String() }
+func (e or) String() string  { return "(" + e.l.String() + " OR " + e.r.String() + ")" }
+func (e not) String() string { return "-" + e.e.String() }
+func (all) String() string   { return "" }
+
+func quote(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\"():=<>!") {
+		return fmt.Sprintf("%q", v)
+	}
+	return v
+}
@@ -0,0 +1,155 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"opscrape/catalog"
+)
+
+func card(id, name, set string, kv map[string]string) *catalog.Card {
+	c := &catalog.Card{ID: id, Name: name, SetCode: set, KV: kv}
+	for k := range kv {
+		c.OrderedKeys = append(c.OrderedKeys, k)
+	}
+	return c
+}
+
+var cards = []*catalog.Card{
+	card("Absorbing_Man_(CLOP)", "Absorbing Man", "CLOP", map[string]string{
+		"Type": "Character", "Rarity": "Rare", "Numbers": "Energy 3Fighting 6Strength 8Intellect 2",
+	}),
+	card("Absorbing_Man_-_Wrecking_Ball_(CLOP)", "Absorbing Man - Wrecking Ball", "CLOP", map[string]string{
+		"Type": "Special", "Rarity": "Common", "Game Text": "Acts as a level 6 Strength attack.", "Characters": "Absorbing Man",
+	}),
+	card("Storm_(XMOP)", "Storm™", "XMOP", map[string]string{
+		"Type": "Character", "Rarity": "Uncommon", "Numbers": "Energy 7Fighting 4Strength 3Intellect 5", "Control": "A2",
+	}),
+	card("Storm_-_Lightning_Strike_(XMOP)", `Storm - "Lightning" Strike`, "XMOP", map[string]string{
+		"Type": "Special", "Rarity": "Very Rare", "Game Text": "Avoid any attack.", "Characters": "Storm", "Control": "B1",
+	}),
+	card("7_Any_Power_(XMOP)", "7 Any Power", "XMOP", map[string]string{"Type": "Power", "Rarity": "Common"}),
+}
+
+// matches runs q over cards and returns the matching IDs, in order.
+func matches(t *testing.T, q string) string {
+	t.Helper()
+	e, err := Parse(q)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", q, err)
+	}
+	var ids []string
+	for _, c := range cards {
+		if e.Match(c) {
+			ids = append(ids, strings.SplitN(c.ID, "_(", 2)[0])
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestEval(t *testing.T) {
+	for _, c := range []struct{ q, want string }{
+		{"", "Absorbing_Man,Absorbing_Man_-_Wrecking_Ball,Storm,Storm_-_Lightning_Strike,7_Any_Power"},
+		{"storm", "Storm,Storm_-_Lightning_Strike"},
+		{"type:special", "Absorbing_Man_-_Wrecking_Ball,Storm_-_Lightning_Strike"},
+		{"t:spec", ""}, // keyword fields match whole values
+		{"type:special set:xmop", "Storm_-_Lightning_Strike"},
+		{"type:special AND set:CLOP", "Absorbing_Man_-_Wrecking_Ball"},
+		{"set:CLOP OR type:power", "Absorbing_Man,Absorbing_Man_-_Wrecking_Ball,7_Any_Power"},
+		{"type:power OR set:CLOP type:character", "Absorbing_Man,7_Any_Power"},
+		{"(type:power OR set:CLOP) type:character", "Absorbing_Man"},
+		{"NOT set:CLOP", "Storm,Storm_-_Lightning_Strike,7_Any_Power"},
+		{"-set:CLOP -type:power", "Storm,Storm_-_Lightning_Strike"},
+		{"NOT (set:CLOP OR type:power)", "Storm,Storm_-_Lightning_Strike"},
+		{"NOT NOT set:CLOP", "Absorbing_Man,Absorbing_Man_-_Wrecking_Ball"},
+		{`char:"Absorbing Man"`, "Absorbing_Man_-_Wrecking_Ball"},
+		{`"wrecking ball"`, "Absorbing_Man_-_Wrecking_Ball"},
+		{`name:"\"Lightning\""`, "Storm_-_Lightning_Strike"},
+		{`name="storm"`, "Storm"}, // ™ is folded away
+		{"name!=storm type:character", "Absorbing_Man"},
+		{"text:avoid", "Storm_-_Lightning_Strike"},
+		{"o:strength", "Absorbing_Man_-_Wrecking_Ball"},
+		{"numbers.energy>=6", "Storm"},
+		{"numbers.strength>7", "Absorbing_Man"},
+		{"numbers.e<4", "Absorbing_Man"},
+		{"numbers.total=19", "Absorbing_Man,Storm"},
+		{"numbers.intellect!=5", "Absorbing_Man"}, // cards without a grid never match
+		{"rarity>=rare", "Absorbing_Man,Storm_-_Lightning_Strike"},
+		{"r:vr", "Storm_-_Lightning_Strike"},
+		{"rarity<uncommon", "Absorbing_Man_-_Wrecking_Ball,7_Any_Power"},
+		{"control>A2", "Storm_-_Lightning_Strike"},
+		{"control:b1", "Storm_-_Lightning_Strike"},
+	} {
+		if got := matches(t, c.q); got != c.want {
+			t.Errorf("%q matched %s, want %s", c.q, got, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		q   string
+		pos int
+		msg string
+	}{
+		{"colour:red", 0, `unknown field "colour"`},
+		{"type:special colour:red", 13, `unknown field "colour"`},
+		{"numbers.speed>3", 0, "numbers.energy"},
+		{"name.energy>3", 0, `unknown field "name.energy"`},
+		{"numbers.energy>=high", 0, "needs a number"},
+		{"rarity:mythic", 0, "unknown rarity"},
+		{"control>=ZZZ", 0, "unknown Control code"},
+		{"set<CLOP", 0, "cannot be compared"},
+		{`char:"Absorbing Man`, 5, "unterminated string"},
+		{"type!special", 4, `"!" must be followed by "="`},
+		{"(type:special", 13, "expected ) to close the ( at 0"},
+		{"type:special)", 12, `unexpected ")"`},
+		{"type:", 5, "expected a value after type:"},
+		{"type: OR", 6, "expected a value"},
+		{"set:CLOP OR", 11, "unexpected end of query"},
+		{"NOT", 3, "unexpected end of query"},
+		{"storm ()", 7, `unexpected ")"`},
+	} {
+		_, err := Parse(c.q)
+		var qe *Error
+		if !errors.As(err, &qe) {
+			t.Errorf("Parse(%q) = %v, want a query error", c.q, err)
+			continue
+		}
+		if qe.Pos != c.pos || !strings.Contains(qe.Msg, c.msg) {
+			t.Errorf("Parse(%q) = at %d %q, want at %d %q", c.q, qe.Pos, qe.Msg, c.pos, c.msg)
+		}
+	}
+}
+
+func TestLexer(t *testing.T) {
+	toks, err := lex(`-set:CLOP (c:"Ka\"Ra" OR n>=2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tk := range toks {
+		got = append(got, fmt.Sprintf("%s@%d", tk.text, tk.pos))
+	}
+	want := `-@0 set@1 :@4 CLOP@5 (@10 c@11 :@12 Ka"Ra@13 OR@22 n@25 >=@26 2@28 )@29 @30`
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("tokens = %s\nwant     %s", s, want)
+	}
+	// A "-" right after an operator is part of the value, not NOT.
+	toks, _ = lex("numbers.energy>-1")
+	if toks[2].kind != tokWord || toks[2].text != "-1" {
+		t.Errorf("value token = %+v", toks[2])
+	}
+}
+
+func TestString(t *testing.T) {
+	e, err := Parse(`type:special (c:"Absorbing Man" OR -set:clop) storm`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e.String(), `type:special (characters:"Absorbing Man" OR -set:clop) storm`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
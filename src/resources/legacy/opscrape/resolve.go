@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/resolve"
+)
+
+// readRefs reads one card reference per line, skipping blanks and # comments.
+func readRefs(r io.Reader) ([]string, error) {
+	var out []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, sc.Err()
+}
+
+func runResolve(args []string) error {
+	var root, in, format, set, typ, printing string
+	var minScore, margin float64
+	fs := newFlagSet("resolve", &root)
+	fs.StringVar(&in, "in", "", "File with one card reference per line (default: arguments, else stdin)")
+	fs.StringVar(&format, "format", "text", "Output format: text, csv or json")
+	fs.StringVar(&set, "set", "", "Prefer cards from this set tag")
+	fs.StringVar(&typ, "type", "", "Prefer cards of this type, e.g. Special")
+	fs.StringVar(&printing, "printing", "", "Prefer this printing (default Normal)")
+	fs.Float64Var(&minScore, "min", 0.75, "Lowest score a candidate may have")
+	fs.Float64Var(&margin, "margin", 0.05, "Lead the best candidate needs over the next to resolve")
+	fs.Parse(args)
+
+	refs := fs.Args()
+	if len(refs) == 0 {
+		r := io.Reader(os.Stdin)
+		if in != "" {
+			f, err := os.Open(in)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		var err error
+		if refs, err = readRefs(r); err != nil {
+			return err
+		}
+	}
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	rv := resolve.New(cat)
+	opt := resolve.Options{Set: set, Type: typ, Printing: printing, MinScore: minScore, Margin: margin}
+	results := make([]resolve.Result, len(refs))
+	counts := map[resolve.Status]int{}
+	for i, ref := range refs {
+		results[i] = rv.Resolve(ref, opt)
+		counts[results[i].Status]++
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"ref", "status", "id", "score", "alternatives"})
+		for _, r := range results {
+			id, alts := "", []string{}
+			if r.Card != nil {
+				id = r.Card.ID
+			}
+			for _, c := range r.Candidates {
+				if c.ID != id {
+					alts = append(alts, c.ID)
+				}
+			}
+			w.Write([]string{r.Ref, string(r.Status), id, fmt.Sprintf("%.2f", r.Score), strings.Join(alts, " | ")})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	case "text":
+		for _, r := range results {
+			switch r.Status {
+			case resolve.Resolved:
+				fmt.Printf("[OK ] %s -> %s (%.2f)\n", r.Ref, r.Card.ID, r.Score)
+			case resolve.Ambiguous:
+				fmt.Printf("[WARN] %s is ambiguous:\n", r.Ref)
+				for _, c := range r.Candidates {
+					fmt.Printf("         %.2f %s (%s)\n", c.Score, c.ID, c.Reason)
+				}
+			default:
+				fmt.Printf("[ERR] %s: no match\n", r.Ref)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown -format %q", format)
+	}
+	fmt.Fprintf(os.Stderr, "%d resolved, %d ambiguous, %d not found\n",
+		counts[resolve.Resolved], counts[resolve.Ambiguous], counts[resolve.NotFound])
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package resolve maps free-text card references ("Cyclops", "the acolytes",
+// "Wolverine - Berserker Rage (XMOP)") to catalog cards. Names are
+// normalized, ranked by edit distance and token overlap, and returned with a
+// confidence score; references that fit several cards equally well are
+// reported as ambiguous rather than guessed.
+package resolve
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ---------- Normalization ----------
+
+// Clean mirrors the scrapers' cleanInline: non-breaking spaces become
+// spaces, typographic quotes become ASCII and runs of whitespace collapse.
+func Clean(s string) string {
+	s = strings.ReplaceAll(s, " ", " ")
+	s = strings.ReplaceAll(s, "’", "'")
+	s = strings.ReplaceAll(s, "‘", "'")
+	s = strings.ReplaceAll(s, "“", "\"")
+	s = strings.ReplaceAll(s, "”", "\"")
+	s = strings.Join(strings.Fields(s), " ")
+	return s
+}
+
+var glyphs = strings.NewReplacer("™", "", "®", "", "©", "", "_", " ", "–", "-", "—", "-")
+
+// Normalize is the comparison key for a name: Clean, trademark glyphs and
+// wiki underscores removed, lower case, and punctuation other than the
+// " - " separator between a character and a card title dropped.
+func Normalize(s string) string {
+	s = strings.ToLower(glyphs.Replace(Clean(s)))
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == ' ', r == '-', r == '&':
+			b.WriteRune(r)
+		case r == '\'' || r == '.' || r == '"':
+			// "Comm. Gordon", "Ka'Ra", quoted titles
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// stripPrefixes are dropped from the front of a normalized name to give an
+// extra key, so "Acolytes" finds "The Acolytes" and "Energy Blast" finds
+// "Any Character - Energy Blast".
+var stripPrefixes = []string{"any character - ", "the "}
+
+// keys returns the normalized name and its prefix-stripped variants.
+func keys(name string) []string {
+	k := Normalize(name)
+	out := []string{k}
+	for _, p := range stripPrefixes {
+		if rest, ok := strings.CutPrefix(k, p); ok && rest != "" {
+			out = append(out, rest)
+		}
+	}
+	return out
+}
+
+// tokens splits a normalized key into words, dropping the " - " separator.
+func tokens(key string) []string {
+	var out []string
+	for _, t := range strings.Fields(key) {
+		if t != "-" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,238 @@
+package resolve
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+	"scraper/setcode"
+)
+
+// ---------- Results ----------
+
+type Status string
+
+const (
+	Resolved  Status = "resolved"  // one card clearly fits
+	Ambiguous Status = "ambiguous" // several cards fit about equally well
+	NotFound  Status = "not_found" // nothing scores above Options.MinScore
+)
+
+// Candidate is a card a reference may mean. Score is 1 for an exact
+// normalized match and falls towards 0 as the names drift apart.
+type Candidate struct {
+	Card   *catalog.Card `json:"-"`
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Set    string        `json:"set"`
+	Score  float64       `json:"score"`
+	Reason string        `json:"reason"`
+}
+
+// Result is the outcome for one reference. Card is set only when Status is
+// Resolved; Candidates lists the best matches either way.
+type Result struct {
+	Ref        string        `json:"ref"`
+	Status     Status        `json:"status"`
+	Card       *catalog.Card `json:"-"`
+	Score      float64       `json:"score"`
+	Candidates []Candidate   `json:"candidates,omitempty"`
+}
+
+// Options narrow and tune a lookup.
+type Options struct {
+	Set      string  // preferred set tag; also read from a "(CLOP)" suffix
+	Type     string  // preferred card type, e.g. "Special"
+	Printing string  // preferred printing; "" prefers Normal
+	MinScore float64 // below this a candidate is dropped (default 0.75)
+	Margin   float64 // the best must lead the runner-up by this much (default 0.05)
+	Limit    int     // candidates returned (default 5)
+}
+
+func (o Options) withDefaults() Options {
+	if o.MinScore == 0 {
+		o.MinScore = 0.75
+	}
+	if o.Margin == 0 {
+		o.Margin = 0.05
+	}
+	if o.Limit == 0 {
+		o.Limit = 5
+	}
+	return o
+}
+
+// ---------- Resolver ----------
+
+type entry struct {
+	card *catalog.Card
+	keys []string
+	toks [][]string
+}
+
+// Resolver holds a prepared index of the catalog's own cards.
+type Resolver struct {
+	cat     *catalog.Catalog
+	entries []entry
+	exact   map[string][]int
+}
+
+// New indexes every card under its name, prefix-stripped name and page
+// title. Cards a set directory lists for another set are left out so each
+// printing is indexed once.
+func New(cat *catalog.Catalog) *Resolver {
+	r := &Resolver{cat: cat, exact: map[string][]int{}}
+	for _, c := range cat.Cards {
+		if !c.IsOwn() {
+			continue
+		}
+		e := entry{card: c, keys: keys(c.Name)}
+		if id := Normalize(strings.Replace(c.ID, "_("+c.Field("Set")+")", "", 1)); id != e.keys[0] {
+			e.keys = append(e.keys, id)
+		}
+		for _, k := range e.keys {
+			e.toks = append(e.toks, tokens(k))
+			r.exact[k] = append(r.exact[k], len(r.entries))
+		}
+		r.entries = append(r.entries, e)
+	}
+	return r
+}
+
+// setSuffix matches a trailing set hint: "(CLOP)", "[CLOP]" or "- CLOP".
+var setSuffix = regexp.MustCompile(`\s*(?:\(([A-Za-z]+)\)|\[([A-Za-z]+)\]|-\s*([A-Za-z]+))\s*$`)
+
+// splitSetHint removes a recognised set tag from the end of ref.
+func splitSetHint(ref string) (string, string) {
+	m := setSuffix.FindStringSubmatchIndex(ref)
+	if m == nil {
+		return ref, ""
+	}
+	for g := 1; g <= 3; g++ {
+		if m[2*g] >= 0 {
+			code := strings.ToUpper(ref[m[2*g]:m[2*g+1]])
+			if setcode.Known(code) {
+				return ref[:m[0]], code
+			}
+		}
+	}
+	return ref, ""
+}
+
+// Resolve ranks the catalog against one reference. A catalog ID always
+// resolves exactly.
+func (r *Resolver) Resolve(ref string, opt Options) Result {
+	opt = opt.withDefaults()
+	res := Result{Ref: ref, Status: NotFound}
+	if c, ok := r.cat.Card(strings.TrimSpace(ref)); ok {
+		res.Status, res.Card, res.Score = Resolved, c, 1
+		res.Candidates = []Candidate{newCandidate(c, 1, "catalog id")}
+		return res
+	}
+
+	name, hint := splitSetHint(Clean(ref))
+	if opt.Set != "" {
+		hint = strings.ToUpper(opt.Set)
+	}
+	qkeys := keys(name)
+	if qkeys[0] == "" {
+		return res
+	}
+
+	best := map[int]Candidate{}
+	consider := func(i int, score float64, reason string) {
+		if c, ok := best[i]; !ok || score > c.Score {
+			best[i] = newCandidate(r.entries[i].card, score, reason)
+		}
+	}
+	for _, k := range qkeys {
+		for _, i := range r.exact[k] {
+			consider(i, 1, "exact")
+		}
+	}
+	if len(best) == 0 {
+		for _, q := range qkeys {
+			qt := tokens(q)
+			for i, e := range r.entries {
+				for ki, k := range e.keys {
+					if s := similarity(q, k, qt, e.toks[ki]); s >= opt.MinScore {
+						consider(i, s, "fuzzy")
+					}
+				}
+			}
+		}
+	}
+
+	cands := make([]Candidate, 0, len(best))
+	for _, c := range best {
+		cands = append(cands, c)
+	}
+	cands = applyHints(cands, hint, opt)
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].Score != cands[j].Score {
+			return cands[i].Score > cands[j].Score
+		}
+		return cands[i].ID < cands[j].ID
+	})
+	if len(cands) == 0 {
+		return res
+	}
+	res.Score = cands[0].Score
+	if len(cands) == 1 || cands[0].Score-cands[1].Score >= opt.Margin {
+		res.Status, res.Card = Resolved, cands[0].Card
+	} else {
+		res.Status = Ambiguous
+	}
+	// Trim after judging the margin, so Limit 1 cannot hide a runner-up.
+	if len(cands) > opt.Limit {
+		cands = cands[:opt.Limit]
+	}
+	res.Candidates = cands
+	return res
+}
+
+func newCandidate(c *catalog.Card, score float64, reason string) Candidate {
+	return Candidate{Card: c, ID: c.ID, Name: c.Name, Set: c.Field("Set"), Score: score, Reason: reason}
+}
+
+// applyHints narrows candidates to the hinted set, type and printing when
+// any of them match, so a hint never turns a hit into a miss. Without a
+// printing hint, other printings of a Normal card are dropped.
+func applyHints(cands []Candidate, set string, opt Options) []Candidate {
+	narrow := func(keep func(*catalog.Card) bool, reason string) {
+		var out []Candidate
+		for _, c := range cands {
+			if keep(c.Card) {
+				c.Reason += ", " + reason
+				out = append(out, c)
+			}
+		}
+		if len(out) > 0 {
+			cands = out
+		}
+	}
+	if set != "" {
+		narrow(func(c *catalog.Card) bool { return strings.EqualFold(c.Field("Set"), set) }, "set "+set)
+	}
+	if opt.Type != "" {
+		narrow(func(c *catalog.Card) bool { return strings.EqualFold(c.Type(), opt.Type) }, "type "+opt.Type)
+	}
+	if opt.Printing != "" {
+		narrow(func(c *catalog.Card) bool { return strings.EqualFold(c.Printing(), opt.Printing) }, "printing "+opt.Printing)
+		return cands
+	}
+	normal := map[string]bool{}
+	for _, c := range cands {
+		if c.Card.Printing() == "Normal" {
+			normal[Normalize(c.Name)+"|"+c.Set] = true
+		}
+	}
+	out := cands[:0]
+	for _, c := range cands {
+		if c.Card.Printing() == "Normal" || !normal[Normalize(c.Name)+"|"+c.Set] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
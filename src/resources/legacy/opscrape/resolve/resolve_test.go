@@ -0,0 +1,161 @@
+package resolve
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"opscrape/catalog"
+)
+
+func testResolver(t *testing.T) *Resolver {
+	t.Helper()
+	cat, err := catalog.Load(filepath.Join("testdata", "legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(cat)
+}
+
+func TestCleanAndNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"  Ka’Ra  “Heat”  ": `Ka'Ra "Heat"`,
+		"Comm.\t\tGordon":   "Comm. Gordon",
+		"The Acolytes  ":    "The Acolytes",
+	} {
+		if got := Clean(in); got != want {
+			t.Errorf("Clean(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for in, want := range map[string]string{
+		"Wolverine™ - Berserker Rage":   "wolverine - berserker rage",
+		"Avenger's_ID_Card":             "avengers id card",
+		"Comm. Gordon":                  "comm gordon",
+		"Ka’Ra — “Heat”":                "kara - heat",
+		"Cloak & Dagger (Mutant Power)": "cloak & dagger mutant power",
+		"Asteroid \"M\": Hideout!":      "asteroid m hideout",
+	} {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestKeysStripPrefixes(t *testing.T) {
+	for in, want := range map[string][]string{
+		"Any Character - Energy Blast": {"any character - energy blast", "energy blast"},
+		"The Acolytes":                 {"the acolytes", "acolytes"},
+		"Theodore":                     {"theodore"},
+		"the ":                         {"the"},
+		"Cyclops":                      {"cyclops"},
+	} {
+		if got := keys(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("keys(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r := testResolver(t)
+	cases := []struct {
+		ref    string
+		opt    Options
+		status Status
+		id     string
+	}{
+		{"acolytes", Options{}, Resolved, "The_Acolytes_(MNOP)"},
+		{"the Acolytes", Options{}, Resolved, "The_Acolytes_(MNOP)"},
+		{"Energy Blast", Options{}, Resolved, "Any_Character_-_Energy_Blast_(MNOP)"},
+		{"Any Character – Energy Blast", Options{}, Resolved, "Any_Character_-_Energy_Blast_(MNOP)"},
+		{"Cyclops (XMOP)", Options{}, Resolved, "Cyclops_(XMOP)"},
+		{"Cyclops - MNOP", Options{}, Resolved, "Cyclops_(MNOP)"},
+		{"Cyclops", Options{Set: "mnop"}, Resolved, "Cyclops_(MNOP)"},
+		{"Cyclops", Options{Set: "MNOP", Printing: "Chromium"}, Resolved, "Cyclops_(MNOP)#chromium"},
+		{"Cyclops", Options{}, Ambiguous, ""}, // MNOP and XMOP
+		{"Cyclops_(XMOP)", Options{}, Resolved, "Cyclops_(XMOP)"},
+		{"Image Inducer", Options{}, Resolved, "Image_Inducer_(P)"}, // by page title
+		{"Wolverine - Beserker Rage", Options{}, Resolved, "Wolverine_-_Berserker_Rage_(MNOP)"},
+		{"Rage Berserker Wolverine", Options{}, Resolved, "Wolverine_-_Berserker_Rage_(MNOP)"},
+		{"Magneto", Options{}, NotFound, ""},
+		{"  ", Options{}, NotFound, ""},
+	}
+	for _, c := range cases {
+		res := r.Resolve(c.ref, c.opt)
+		id := ""
+		if res.Card != nil {
+			id = res.Card.ID
+		}
+		if res.Status != c.status || id != c.id {
+			t.Errorf("Resolve(%q, %+v) = %s %q, want %s %q (candidates %+v)", c.ref, c.opt, res.Status, id, c.status, c.id, res.Candidates)
+		}
+	}
+}
+
+// Two promo pages are both titled "Avenger's ID Card"; neither is guessed.
+func TestResolveAmbiguousAvengersIDCard(t *testing.T) {
+	r := testResolver(t)
+	res := r.Resolve("Avenger's ID Card", Options{})
+	if res.Status != Ambiguous || res.Card != nil || res.Score != 1 {
+		t.Fatalf("status = %s, card = %v, score = %v", res.Status, res.Card, res.Score)
+	}
+	var ids []string
+	for _, c := range res.Candidates {
+		ids = append(ids, c.ID)
+	}
+	if want := []string{"Avenger's_ID_Card_(P)", "Image_Inducer_(P)"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("candidates = %q, want %q", ids, want)
+	}
+	if res := r.Resolve("Avenger's_ID_Card_(P)", Options{}); res.Status != Resolved || res.Candidates[0].Reason != "catalog id" {
+		t.Errorf("by ID: %+v", res)
+	}
+}
+
+func TestResolveThresholds(t *testing.T) {
+	r := testResolver(t)
+
+	// MinScore: a typo scores below 1 and is kept only at or above MinScore.
+	res := r.Resolve("Cyclopps (XMOP)", Options{})
+	if res.Status != Resolved || res.Score >= 1 || res.Score < 0.75 {
+		t.Fatalf("Cyclopps: %s %.3f", res.Status, res.Score)
+	}
+	s := res.Score
+	if res := r.Resolve("Cyclopps (XMOP)", Options{MinScore: s + 0.001}); res.Status != NotFound {
+		t.Errorf("MinScore above the score: %s", res.Status)
+	}
+	if res := r.Resolve("Cyclopps (XMOP)", Options{MinScore: s}); res.Status != Resolved {
+		t.Errorf("MinScore equal to the score: %s", res.Status)
+	}
+
+	// Margin: the best must lead the runner-up by at least Margin.
+	res = r.Resolve("Storm - Lightning Stri", Options{})
+	if len(res.Candidates) < 2 {
+		t.Fatalf("want two Storm candidates, got %+v", res.Candidates)
+	}
+	lead := res.Candidates[0].Score - res.Candidates[1].Score
+	if res.Candidates[0].ID != "Storm_-_Lightning_Strike_(MNOP)" || lead <= 0 {
+		t.Fatalf("candidates = %+v", res.Candidates)
+	}
+	if res := r.Resolve("Storm - Lightning Stri", Options{Margin: lead + 0.001}); res.Status != Ambiguous {
+		t.Errorf("Margin above the lead: %s", res.Status)
+	}
+	if res := r.Resolve("Storm - Lightning Stri", Options{Margin: lead - 0.001}); res.Status != Resolved {
+		t.Errorf("Margin below the lead: %s", res.Status)
+	}
+	// Limit trims the list, not the competition.
+	if res := r.Resolve("Storm - Lightning Stri", Options{Limit: 1, Margin: lead + 0.001}); len(res.Candidates) != 1 || res.Status != Ambiguous {
+		t.Errorf("Limit 1: %s %+v", res.Status, res.Candidates)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	a, b := Normalize("Wolverine - Berserker Rage"), Normalize("Rage Berserker Wolverine")
+	if s := similarity(a, b, tokens(a), tokens(b)); s != 0.9 {
+		t.Errorf("reordered words = %.3f, want 0.9 (just below exact)", s)
+	}
+	if s := editSimilarity("cyclops", "cyclopps"); s != 1-1.0/8 {
+		t.Errorf("one edit = %.3f", s)
+	}
+	if s := editSimilarity("", ""); s != 1 {
+		t.Errorf("empty = %.3f", s)
+	}
+}
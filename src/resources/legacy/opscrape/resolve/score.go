@@ -0,0 +1,72 @@
+package resolve
+
+// ---------- Scoring ----------
+
+// similarity blends whole-name edit distance with word overlap. Edit
+// distance catches typos ("Cyclopps"); word overlap catches reordering
+// ("Rage Wolverine"), which on its own is capped just below an exact match.
+func similarity(a, b string, at, bt []string) float64 {
+	tok := tokenSimilarity(at, bt)
+	return max(0.6*editSimilarity(a, b)+0.4*tok, 0.9*tok)
+}
+
+// editSimilarity is 1 - Levenshtein distance / longer length, in runes.
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := max(len(ra), len(rb))
+	if n == 0 {
+		return 1
+	}
+	// Lengths this far apart cannot score well; skip the table.
+	if d := len(ra) - len(rb); d*2 > n || -d*2 > n {
+		return 1 - float64(abs(d))/float64(n)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// tokenSimilarity is the Dice coefficient of the two word sets, counting a
+// word as shared when it is within one edit of a word on the other side.
+func tokenSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	used := make([]bool, len(b))
+	shared := 0
+	for _, x := range a {
+		for j, y := range b {
+			if !used[j] && (x == y || (len(x) > 3 && len(y) > 3 && levenshtein([]rune(x), []rune(y)) <= 1)) {
+				used[j] = true
+				shared++
+				break
+			}
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"opscrape/catalog"
+	"opscrape/engine"
+)
+
+// ---------- Scenarios ----------
+
+// scenario is a scripted game, e.g.
+//
+//	{"seed": 1, "battles": 1, "expect_winner": "Alice",
+//	 "players": [{"name": "Alice", "front": ["Wolverine_(CLOP)"], "deck": [...],
+//	              "missions": [...], "stacked": true, "ventures": [2],
+//	              "steps": [{"kind": "attack", "card": "5_Fighting_(CLOP)", "target": 0}]}, ...]}
+type scenario struct {
+	Seed         uint64           `json:"seed"`
+	Battles      int              `json:"battles"`
+	HandSize     int              `json:"hand_size"`
+	ExpectWinner string           `json:"expect_winner"`
+	Players      []scenarioPlayer `json:"players"`
+}
+
+type scenarioPlayer struct {
+	Name     string        `json:"name"`
+	Front    []string      `json:"front"`
+	Reserve  string        `json:"reserve"`
+	Deck     []string      `json:"deck"`
+	Missions []string      `json:"missions"`
+	Stacked  bool          `json:"stacked"`
+	Ventures []int         `json:"ventures"`
+	Steps    []engine.Step `json:"steps"`
+	Bot      string        `json:"bot"` // "greedy" plays on when the steps run out
+}
+
+// lookupCard finds a card by catalog ID, then by exact name.
+func lookupCard(cat *catalog.Catalog, ref string) (engine.Card, error) {
+	c, ok := cat.Card(ref)
+	if !ok {
+		for _, cand := range cat.Cards {
+			if cand.Name == ref && cand.IsOwn() {
+				c, ok = cand, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return engine.Card{}, fmt.Errorf("unknown card %q", ref)
+	}
+	card, ok := engine.FromCatalog(c)
+	if !ok {
+		return card, fmt.Errorf("%s: cannot read its stats", c.ID)
+	}
+	return card, nil
+}
+
+func lookupCards(cat *catalog.Catalog, refs []string) ([]engine.Card, error) {
+	out := make([]engine.Card, 0, len(refs))
+	for _, r := range refs {
+		c, err := lookupCard(cat, r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func runScenario(cat *catalog.Catalog, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var sc scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(sc.Players) != 2 {
+		return fmt.Errorf("%s: need exactly 2 players", path)
+	}
+
+	var setups [2]engine.Setup
+	var ctrl [2]engine.Controller
+	var scripts [2]*engine.Script
+	for i, sp := range sc.Players {
+		s := engine.Setup{Name: sp.Name, Stacked: sp.Stacked}
+		if s.FrontLine, err = lookupCards(cat, sp.Front); err != nil {
+			return err
+		}
+		if s.Deck, err = lookupCards(cat, sp.Deck); err != nil {
+			return err
+		}
+		if s.Missions, err = lookupCards(cat, sp.Missions); err != nil {
+			return err
+		}
+		if sp.Reserve != "" {
+			r, err := lookupCard(cat, sp.Reserve)
+			if err != nil {
+				return err
+			}
+			s.Reserve = &r
+		}
+		setups[i] = s
+
+		script := &engine.Script{Ventures: sp.Ventures, Steps: sp.Steps}
+		if sp.Bot == "greedy" {
+			script.Fallback = engine.Greedy{}
+		}
+		scripts[i], ctrl[i] = script, script
+	}
+
+	rules := engine.DefaultRules()
+	if sc.HandSize > 0 {
+		rules.HandSize = sc.HandSize
+	}
+	g, err := engine.NewGame(rules, setups, sc.Seed)
+	if err != nil {
+		return err
+	}
+	if sc.Battles <= 0 {
+		sc.Battles = 1
+	}
+	playErr := g.Play(ctrl, sc.Battles)
+	for _, e := range g.Log {
+		fmt.Println(e)
+	}
+	printGameState(g)
+	if playErr != nil {
+		return playErr
+	}
+	for i, s := range scripts {
+		if s.Err != nil {
+			return fmt.Errorf("player %d: %w", i+1, s.Err)
+		}
+	}
+	if sc.ExpectWinner != "" {
+		got := "none"
+		if g.Winner >= 0 {
+			got = g.Players[g.Winner].Name
+		}
+		if got != sc.ExpectWinner {
+			return fmt.Errorf("expected winner %q, got %q", sc.ExpectWinner, got)
+		}
+	}
+	return nil
+}
+
+func printGameState(g *engine.Game) {
+	for _, p := range g.Players {
+		fmt.Printf("%s: draw %d, dead %d, missions %d, completed %d, defeated %d\n",
+			p.Name, len(p.DrawPile), len(p.DeadPile), len(p.Missions), len(p.Completed), len(p.Defeated))
+		for _, c := range p.FrontLine {
+			state := ""
+			if c.KO {
+				state = " KO"
+			}
+			fmt.Printf("  %-32s record %d/%d%s\n", c.Name, c.RecordTotal(), c.HitsToKO(), state)
+		}
+	}
+}
+
+// ---------- Coverage ----------
+
+// printCoverage reports per set how many cards the engine can read.
+func printCoverage(cat *catalog.Catalog) {
+	fmt.Printf("%-6s %10s %10s %8s %8s %8s %12s\n", "Set", "Characters", "Powers", "Attacks", "Avoids", "No-op", "Unsupported")
+	for _, s := range cat.Sets {
+		var chars, charsOK, powers, powersOK int
+		kinds := map[engine.EffectKind]int{}
+		for _, c := range s.OwnCards() {
+			card, ok := engine.FromCatalog(c)
+			switch card.Kind {
+			case engine.KindCharacter:
+				chars++
+				if ok {
+					charsOK++
+				}
+			case engine.KindPower:
+				powers++
+				if ok {
+					powersOK++
+				}
+			case engine.KindSpecial:
+				kinds[card.Effect.Kind]++
+			}
+		}
+		fmt.Printf("%-6s %4d/%-5d %4d/%-5d %8d %8d %8d %12d\n", s.Code, charsOK, chars, powersOK, powers,
+			kinds[engine.EffectAttack], kinds[engine.EffectAvoid], kinds[engine.EffectNone], kinds[engine.EffectUnsupported])
+	}
+}
+
+// ---------- Command ----------
+
+func runRules(args []string) error {
+	var root, scenarioPath string
+	var coverage bool
+	fs := newFlagSet("rules", &root)
+	fs.StringVar(&scenarioPath, "scenario", "", "Play a scripted scenario JSON and print the log")
+	fs.BoolVar(&coverage, "coverage", false, "Report which cards the engine can resolve, per set")
+	fs.Parse(args)
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	switch {
+	case coverage:
+		printCoverage(cat)
+		return nil
+	case scenarioPath != "":
+		return runScenario(cat, scenarioPath)
+	}
+	return fmt.Errorf("need -coverage or -scenario; see 'opscrape rules -h'")
+}
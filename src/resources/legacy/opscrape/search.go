@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"opscrape/catalog"
+	"opscrape/query"
+)
+
+// defaultSearchFields are the columns shown when -fields is empty.
+var defaultSearchFields = []string{"Set", "Type", "Rarity", "Name", "Characters"}
+
+// parseFieldList resolves a comma-separated list of field names or aliases.
+func parseFieldList(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaultSearchFields, nil
+	}
+	if s == "all" {
+		return catalog.Fields, nil
+	}
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		canon, ok := catalog.CanonicalField(f)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q; one of %s", f, strings.Join(catalog.Fields, ", "))
+		}
+		out = append(out, canon)
+	}
+	return out, nil
+}
+
+// splitQueryArgs separates flags from query words so flags may follow the
+// query and "-type:special" stays a negated term: only arguments naming a
+// defined flag are flags.
+func splitQueryArgs(fs *flag.FlagSet, args []string) (words, flagArgs []string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		f := fs.Lookup(name)
+		isHelp := name == "h" || name == "help"
+		if !strings.HasPrefix(a, "-") || (f == nil && !isHelp) {
+			words = append(words, a)
+			continue
+		}
+		flagArgs = append(flagArgs, a)
+		if isHelp || hasValue {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+	return words, flagArgs
+}
+
+func runSearch(args []string) error {
+	var root, format, fieldList string
+	var limit int
+	var includeForeign bool
+	fs := newFlagSet("search", &root)
+	fs.StringVar(&format, "format", "table", "Output format: table, csv or json")
+	fs.StringVar(&fieldList, "fields", "", `Comma-separated fields to print, or "all"`)
+	fs.IntVar(&limit, "limit", 0, "Stop after this many cards (0 = no limit)")
+	fs.BoolVar(&includeForeign, "foreign", false, "Include cards listed under another set's directory (promos)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: opscrape search [flags] <query>\n\n")
+		fmt.Fprintf(os.Stderr, "e.g. type:special char:\"Absorbing Man\" rarity:rare text:avoid set:CLOP\n")
+		fmt.Fprintf(os.Stderr, "     numbers.energy>=6 (type:character OR type:special) -printing:normal\n\n")
+		fmt.Fprintf(os.Stderr, "fields: %s\n\n", strings.Join(catalog.Fields, ", "))
+		fs.PrintDefaults()
+	}
+	words, flagArgs := splitQueryArgs(fs, args)
+	fs.Parse(flagArgs)
+
+	q, err := query.Parse(strings.Join(words, " "))
+	if err != nil {
+		return err
+	}
+	fields, err := parseFieldList(fieldList)
+	if err != nil {
+		return err
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+
+	var hits []*catalog.Card
+	for _, c := range cat.Cards {
+		if (includeForeign || c.IsOwn()) && q.Match(c) {
+			hits = append(hits, c)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if a, b := hits[i].Field("Set"), hits[j].Field("Set"); a != b {
+			return a < b
+		}
+		return hits[i].Name < hits[j].Name
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	switch format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+		for _, c := range hits {
+			row := make([]string, len(fields))
+			for i, f := range fields {
+				// Keep multi-line Game Text on one row.
+				row[i] = strings.Join(strings.Fields(c.Field(f)), " ")
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d cards\n", len(hits))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write(fields)
+		for _, c := range hits {
+			row := make([]string, len(fields))
+			for i, f := range fields {
+				row[i] = c.Field(f)
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		return w.Error()
+	case "json":
+		out := make([]map[string]string, 0, len(hits))
+		for _, c := range hits {
+			m := map[string]string{}
+			for _, f := range fields {
+				m[f] = c.Field(f)
+			}
+			out = append(out, m)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	default:
+		return fmt.Errorf("unknown -format %q", format)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/engine"
+)
+
+// ---------- Decks ----------
+
+// loadedDeck is a deck JSON resolved against the catalog: Characters form
+// the Front Line, Missions are set aside and everything else is drawn.
+type loadedDeck struct {
+	Name       string
+	FrontLine  []engine.Card
+	Draw       []engine.Card
+	Missions   int
+	Unresolved []string
+}
+
+// loadDeck reads a deckbuilder deck JSON. Entries are matched as
+// matchDeckCard does; the ones that do not match are listed in Unresolved
+// and reported, not fatal.
+func loadDeck(path string, cat *catalog.Catalog, table cardTable, report io.Writer) (*loadedDeck, error) {
+	d, err := readDeckFile(path)
+	if err != nil {
+		return nil, err
+	}
+	deck := &loadedDeck{Name: firstNonEmpty(d.Name, d.ID, path)}
+	idx := newNameIndex(cat, table)
+	for _, dc := range d.Cards {
+		c, err := matchDeckCard(idx, dc)
+		if err != nil {
+			fmt.Fprintf(report, "[WARN] %s: %v\n", path, err)
+			deck.Unresolved = append(deck.Unresolved, dc.CardID)
+			continue
+		}
+		card, ok := engine.FromCatalog(c)
+		if !ok {
+			fmt.Fprintf(report, "[WARN] %s: %s: cannot read its stats\n", path, c.ID)
+			deck.Unresolved = append(deck.Unresolved, dc.CardID)
+			continue
+		}
+		qty := max(dc.Quantity, 1)
+		switch card.Kind {
+		case engine.KindCharacter:
+			deck.FrontLine = append(deck.FrontLine, card)
+		case engine.KindMission:
+			deck.Missions += qty
+		default:
+			for i := 0; i < qty; i++ {
+				deck.Draw = append(deck.Draw, card)
+			}
+		}
+	}
+	return deck, nil
+}
+
+// ---------- Simulation ----------
+
+type histogram map[int]int
+
+// simReport is the JSON form of a simulation; the Markdown report is
+// rendered from it.
+type simReport struct {
+	Deck       string   `json:"deck"`
+	Seed       uint64   `json:"seed"`
+	Trials     int      `json:"trials"`
+	HandSize   int      `json:"hand_size"`
+	DrawPile   int      `json:"draw_pile"`
+	FrontLine  []string `json:"front_line"`
+	Unresolved []string `json:"unresolved,omitempty"`
+
+	UsablePowers     histogram          `json:"usable_powers"`
+	MeanUsablePowers float64            `json:"mean_usable_powers"`
+	AvoidChance      map[string]float64 `json:"avoid_chance"`
+	Strength         histogram          `json:"venture_strength"`
+	MeanStrength     float64            `json:"mean_venture_strength"`
+	DeadHand         map[string]float64 `json:"dead_hand_chance"`
+}
+
+// attackTypes are the four types an attack can be; Any Power and
+// MultiPower only describe Power cards.
+var attackTypes = []engine.PowerType{engine.Energy, engine.Fighting, engine.Strength, engine.Intellect}
+
+// simulate deals trials hands of handSize from a shuffled deck.
+//
+// A Power card is usable when some Front Line character can play it. A hand
+// has an avoid for a type when it holds a Front Line Special that avoids it.
+// Venture strength is the sum of the best attack level each card in hand
+// gives, a rough measure of how many Missions the hand can back.
+func simulate(deck *loadedDeck, trials, handSize int, seed uint64) simReport {
+	r := simReport{
+		Deck: deck.Name, Seed: seed, Trials: trials, HandSize: handSize, DrawPile: len(deck.Draw),
+		Unresolved: deck.Unresolved, UsablePowers: histogram{}, Strength: histogram{},
+		AvoidChance: map[string]float64{}, DeadHand: map[string]float64{},
+	}
+	for _, ch := range deck.FrontLine {
+		r.FrontLine = append(r.FrontLine, ch.Name)
+	}
+	if trials <= 0 || len(deck.Draw) == 0 {
+		return r
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed^0xda942042e4dd58b5))
+	pile := append([]engine.Card{}, deck.Draw...)
+	n := min(handSize, len(pile))
+	avoids := make([]int, len(attackTypes))
+	dead := make([]int, len(deck.FrontLine))
+	var usableSum, strengthSum int
+
+	for t := 0; t < trials; t++ {
+		rng.Shuffle(len(pile), func(a, b int) { pile[a], pile[b] = pile[b], pile[a] })
+		hand := pile[:n]
+
+		usable, strength := 0, 0
+		hasAvoid := make([]bool, len(attackTypes))
+		playable := make([]bool, len(deck.FrontLine))
+		for _, c := range hand {
+			best := 0
+			for ci, ch := range deck.FrontLine {
+				if !c.PlayableBy(ch) {
+					continue
+				}
+				playable[ci] = true
+				switch {
+				case c.Kind == engine.KindPower:
+					best = max(best, c.Value)
+				case c.Effect.Kind == engine.EffectAttack:
+					best = max(best, c.Effect.Level)
+				case c.Effect.Kind == engine.EffectAvoid:
+					for ti, at := range attackTypes {
+						if c.Effect.Avoids(at) {
+							hasAvoid[ti] = true
+						}
+					}
+				}
+			}
+			if c.Kind == engine.KindPower && best > 0 {
+				usable++
+			}
+			strength += best
+		}
+
+		r.UsablePowers[usable]++
+		r.Strength[strength]++
+		usableSum += usable
+		strengthSum += strength
+		for ti, ok := range hasAvoid {
+			if ok {
+				avoids[ti]++
+			}
+		}
+		for ci, ok := range playable {
+			if !ok {
+				dead[ci]++
+			}
+		}
+	}
+
+	r.MeanUsablePowers = float64(usableSum) / float64(trials)
+	r.MeanStrength = float64(strengthSum) / float64(trials)
+	for ti, at := range attackTypes {
+		r.AvoidChance[at.String()] = float64(avoids[ti]) / float64(trials)
+	}
+	for ci, ch := range deck.FrontLine {
+		r.DeadHand[ch.Name] = float64(dead[ci]) / float64(trials)
+	}
+	return r
+}
+
+// ---------- Markdown ----------
+
+const histWidth = 40
+
+// writeHistogram draws one bar per value from the smallest to the largest
+// seen, scaled so the most common value fills histWidth.
+func writeHistogram(w io.Writer, h histogram, trials int) {
+	if len(h) == 0 {
+		fmt.Fprintln(w, "(no hands)")
+		return
+	}
+	keys := make([]int, 0, len(h))
+	peak := 0
+	for k, v := range h {
+		keys = append(keys, k)
+		peak = max(peak, v)
+	}
+	sort.Ints(keys)
+	fmt.Fprintln(w, "```")
+	for k := keys[0]; k <= keys[len(keys)-1]; k++ {
+		v := h[k]
+		bar := strings.Repeat("#", (v*histWidth+peak-1)/peak)
+		fmt.Fprintf(w, "%3d | %-*s %5.1f%%\n", k, histWidth, bar, 100*float64(v)/float64(trials))
+	}
+	fmt.Fprintln(w, "```")
+}
+
+func writeSimMarkdown(w io.Writer, r simReport) {
+	fmt.Fprintf(w, "# Deck simulation: %s\n\n", r.Deck)
+	fmt.Fprintf(w, "%d hands of %d from a %d-card draw pile, seed %d.\n\n", r.Trials, r.HandSize, r.DrawPile, r.Seed)
+	fmt.Fprintf(w, "Front Line: %s\n\n", strings.Join(r.FrontLine, ", "))
+	if len(r.Unresolved) > 0 {
+		fmt.Fprintf(w, "Not in the catalog: %s\n\n", strings.Join(r.Unresolved, ", "))
+	}
+
+	fmt.Fprintf(w, "## Usable Power cards per hand\n\nMean %.2f.\n\n", r.MeanUsablePowers)
+	writeHistogram(w, r.UsablePowers, r.Trials)
+
+	fmt.Fprintf(w, "\n## Avoids in hand\n\n| Attack type | Chance |\n|---|---:|\n")
+	for _, at := range attackTypes {
+		fmt.Fprintf(w, "| %s | %.1f%% |\n", at, 100*r.AvoidChance[at.String()])
+	}
+
+	fmt.Fprintf(w, "\n## Venture strength\n\nSum of the best attack level of each card in hand. Mean %.2f.\n\n", r.MeanStrength)
+	writeHistogram(w, r.Strength, r.Trials)
+
+	fmt.Fprintf(w, "\n## Characters with nothing to play\n\n| Character | Chance |\n|---|---:|\n")
+	for _, name := range r.FrontLine {
+		fmt.Fprintf(w, "| %s | %.1f%% |\n", name, 100*r.DeadHand[name])
+	}
+}
+
+// ---------- Command ----------
+
+func runSimulate(args []string) error {
+	var root, deckPath, cardsPath, outPath, jsonPath string
+	var trials, handSize int
+	var seed uint64
+	fs := newFlagSet("simulate", &root)
+	fs.StringVar(&deckPath, "deck", "", "Deck JSON in the deckbuilder format (required)")
+	fs.StringVar(&cardsPath, "cards", "", "Deckbuilder card table for the deckbuilder's cardIds (see collection -cards)")
+	fs.IntVar(&trials, "trials", 10000, "Hands to deal")
+	fs.IntVar(&handSize, "hand", engine.DefaultRules().HandSize, "Cards per hand")
+	fs.Uint64Var(&seed, "seed", 1, "Random seed; the same seed deals the same hands")
+	fs.StringVar(&outPath, "out", "", "Write the Markdown report here instead of stdout")
+	fs.StringVar(&jsonPath, "json", "", "Also write the report as JSON here")
+	fs.Parse(args)
+
+	if deckPath == "" {
+		return fmt.Errorf("missing -deck")
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	table, err := loadCardTable(cardsPath)
+	if err != nil {
+		return err
+	}
+	deck, err := loadDeck(deckPath, cat, table, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if len(deck.FrontLine) == 0 {
+		return fmt.Errorf("%s: no Characters for the Front Line", deckPath)
+	}
+	if len(deck.Draw) == 0 {
+		return fmt.Errorf("%s: nothing to draw", deckPath)
+	}
+	r := simulate(deck, trials, handSize, seed)
+
+	out := os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	writeSimMarkdown(w, r)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if jsonPath != "" {
+		if err := writeJSONFile(jsonPath, r); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "[OK ] json -> %s\n", jsonPath)
+	}
+	return nil
+}
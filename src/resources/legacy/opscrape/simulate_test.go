@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"opscrape/catalog"
+)
+
+// testdata/deck_2.json is decks/deck_2.json as the deckbuilder saved it: its
+// one cardId, card_12, is a deckbuilder card table ID.
+func TestLoadDeckResolvesDeckbuilderIDs(t *testing.T) {
+	cat := testCatalog(t)
+	table, err := loadCardTable(filepath.Join("testdata", "deckbuilder_cards.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", "deck_2.json")
+
+	var warn bytes.Buffer
+	deck, err := loadDeck(path, cat, table, &warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deck.Draw) != 1 || deck.Draw[0].ID != "3_Energy_(DCOP)" || len(deck.Unresolved) != 0 || warn.Len() != 0 {
+		t.Errorf("draw = %+v, unresolved = %v, warnings %q", deck.Draw, deck.Unresolved, warn.String())
+	}
+
+	// Without the table card_12 is reported, not fuzzy-matched as a name.
+	warn.Reset()
+	deck, err = loadDeck(path, cat, nil, &warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deck.Draw) != 0 || len(deck.Unresolved) != 1 || !strings.Contains(warn.String(), "-cards") {
+		t.Errorf("draw = %+v, unresolved = %v, warnings %q", deck.Draw, deck.Unresolved, warn.String())
+	}
+	if _, _, err := readDeckCards(cat, nil, path); err == nil || !strings.Contains(err.Error(), `"card_12"`) {
+		t.Errorf("readDeckCards without -cards: %v", err)
+	}
+	if _, items, err := readDeckCards(cat, table, path); err != nil || len(items) != 1 || items[0].card.ID != "3_Energy_(DCOP)" {
+		t.Errorf("readDeckCards = %+v, %v", items, err)
+	}
+}
+
+func TestReadDeckCardsByCatalogID(t *testing.T) {
+	cat := testCatalog(t)
+	flash, _ := cat.Card("Flash_(DCOP)#silver")
+	batman, _ := cat.Card("Batman_(DCOP)")
+	path := filepath.Join(t.TempDir(), "deck.json")
+	d := newDeckFile("deck_t", "t", "", time.Time{}, []*catalog.Card{flash, batman, flash}, nil)
+	if err := writeJSONFile(path, d); err != nil {
+		t.Fatal(err)
+	}
+	_, items, err := readDeckCards(cat, nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].card != flash || items[0].qty != 2 || items[1].card != batman {
+		t.Errorf("items = %+v", items)
+	}
+}
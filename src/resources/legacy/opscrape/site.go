@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+	"scraper/setcode"
+)
+
+// ---------- Pages ----------
+
+// sitePage is one card page. Everything is derived from the manifest so the
+// export is byte-for-byte stable between runs.
+type sitePage struct {
+	card  *catalog.Card
+	slug  string // unique within the set
+	url   string // "/cards/clop/absorbing-man/"
+	image string // site path of the image, "" when not downloaded
+}
+
+var slugJunk = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(s string) string {
+	s = strings.NewReplacer("™", "", "®", "", "'", "", "’", "", "&", " and ").Replace(strings.ToLower(s))
+	return strings.Trim(slugJunk.ReplaceAllString(s, "-"), "-")
+}
+
+// cardSlug is the page title without its set tag: "Azrael_(DCOP)_(var)"
+// becomes "azrael-var".
+func cardSlug(c *catalog.Card) string {
+	id := strings.Replace(c.ID, "_("+c.Field("Set")+")", "", 1)
+	if s := slugify(id); s != "" {
+		return s
+	}
+	return slugify(c.Name)
+}
+
+// yamlString quotes s as a YAML double-quoted scalar; JSON string escapes
+// are valid YAML.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func yamlList(vals []string) string {
+	q := make([]string, len(vals))
+	for i, v := range vals {
+		q[i] = yamlString(v)
+	}
+	return "[" + strings.Join(q, ", ") + "]"
+}
+
+// writeCardPage writes front matter with the fixed fields first, then the
+// card's Statistics rows in manifest order, and Game Text as the body.
+func writeCardPage(path string, p sitePage, jekyll bool) error {
+	c := p.card
+	set := c.Field("Set")
+	chars := c.Characters()
+	return writeSiteFile(path, func(w *bufio.Writer) {
+		fmt.Fprintln(w, "---")
+		fmt.Fprintf(w, "title: %s\n", yamlString(c.Name))
+		fmt.Fprintf(w, "id: %s\n", yamlString(c.ID))
+		if jekyll {
+			fmt.Fprintf(w, "permalink: %s\n", yamlString(p.url))
+		} else {
+			fmt.Fprintf(w, "url: %s\n", yamlString(p.url))
+		}
+		fmt.Fprintf(w, "set: %s\n", yamlString(set))
+		fmt.Fprintf(w, "set_name: %s\n", yamlString(setcode.Name(set)))
+		fmt.Fprintf(w, "type: %s\n", yamlString(c.Type()))
+		fmt.Fprintf(w, "rarity: %s\n", yamlString(c.Rarity()))
+		fmt.Fprintf(w, "printing: %s\n", yamlString(c.Printing()))
+		if cc := c.Field("Control"); cc != "" {
+			fmt.Fprintf(w, "control: %s\n", yamlString(cc))
+		}
+		fmt.Fprintf(w, "image: %s\n", yamlString(p.image))
+		fmt.Fprintf(w, "source: %s\n", yamlString(c.PageURL))
+		// Taxonomies: Hugo picks these up when listed in its config.
+		fmt.Fprintf(w, "sets: %s\n", yamlList([]string{set}))
+		fmt.Fprintf(w, "types: %s\n", yamlList([]string{c.Type()}))
+		fmt.Fprintf(w, "characters: %s\n", yamlList(chars))
+		fmt.Fprintln(w, "fields:")
+		for _, k := range c.OrderedKeys {
+			fmt.Fprintf(w, "  %s: %s\n", yamlString(k), yamlString(c.KV[k]))
+		}
+		fmt.Fprintln(w, "---")
+		if p.image != "" {
+			fmt.Fprintf(w, "\n![%s](%s)\n", c.Name, p.image)
+		}
+		if t := strings.TrimSpace(c.GameText()); t != "" {
+			fmt.Fprintf(w, "\n%s\n", t)
+		}
+	})
+}
+
+// ---------- Taxonomy listings ----------
+
+// writeListing writes a term page listing its cards, sorted by set then name.
+func writeListing(path, title, url string, pages []sitePage, jekyll bool) error {
+	sort.SliceStable(pages, func(i, j int) bool {
+		a, b := pages[i].card, pages[j].card
+		if a.Field("Set") != b.Field("Set") {
+			return a.Field("Set") < b.Field("Set")
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return writeSiteFile(path, func(w *bufio.Writer) {
+		fmt.Fprintln(w, "---")
+		fmt.Fprintf(w, "title: %s\n", yamlString(title))
+		if jekyll {
+			fmt.Fprintf(w, "permalink: %s\n", yamlString(url))
+		}
+		fmt.Fprintln(w, "---")
+		fmt.Fprintf(w, "\n_%d cards_\n\n", len(pages))
+		for _, p := range pages {
+			fmt.Fprintf(w, "- [%s](%s) — %s, %s\n", p.card.Name, p.url, p.card.Field("Set"), p.card.Type())
+		}
+	})
+}
+
+// siteTerm is a taxonomy term. Spellings that slugify alike ("Unknowns",
+// "unknowns") are one term, titled with its most used spelling.
+type siteTerm struct {
+	names map[string]int
+	pages []sitePage
+}
+
+func (t *siteTerm) title() string {
+	best := ""
+	for n, c := range t.names {
+		if c > t.names[best] || (c == t.names[best] && n < best) {
+			best = n
+		}
+	}
+	return best
+}
+
+// addTerm files p under name, once per card.
+func addTerm(terms map[string]*siteTerm, name string, p sitePage) {
+	slug := slugify(name)
+	t := terms[slug]
+	if t == nil {
+		t = &siteTerm{names: map[string]int{}}
+		terms[slug] = t
+	}
+	t.names[name]++
+	if n := len(t.pages); n == 0 || t.pages[n-1].card != p.card {
+		t.pages = append(t.pages, p)
+	}
+}
+
+// writeTermIndex lists every term of a taxonomy with its card count.
+func writeTermIndex(path, title, url string, terms map[string]*siteTerm, label func(*siteTerm) string, jekyll bool) error {
+	keys := make([]string, 0, len(terms))
+	for k := range terms {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return writeSiteFile(path, func(w *bufio.Writer) {
+		fmt.Fprintln(w, "---")
+		fmt.Fprintf(w, "title: %s\n", yamlString(title))
+		if jekyll {
+			fmt.Fprintf(w, "permalink: %s\n", yamlString(url))
+		}
+		fmt.Fprintln(w, "---")
+		fmt.Fprintln(w)
+		for _, k := range keys {
+			fmt.Fprintf(w, "- [%s](%s) — %d cards\n", label(terms[k]), url+k+"/", len(terms[k].pages))
+		}
+	})
+}
+
+func writeSiteFile(path string, fill func(w *bufio.Writer)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fill(w)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ---------- Command ----------
+
+func runSite(args []string) error {
+	var root, out, flavor, imagePrefix, staticDir string
+	fs := newFlagSet("site", &root)
+	fs.StringVar(&out, "out", "site/content", "Content directory; its cards, sets, types and characters directories are rewritten")
+	fs.StringVar(&flavor, "flavor", "hugo", "hugo (_index.md section pages) or jekyll (index.md, permalinks)")
+	fs.StringVar(&imagePrefix, "image-prefix", "/images", "Site path the images are served under")
+	fs.StringVar(&staticDir, "static", "", "Copy images here as <set>/<image>, e.g. site/static/images")
+	fs.Parse(args)
+
+	if flavor != "hugo" && flavor != "jekyll" {
+		return fmt.Errorf("unknown -flavor %q", flavor)
+	}
+	jekyll := flavor == "jekyll"
+	index := "_index.md"
+	if jekyll {
+		index = "index.md"
+	}
+
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+
+	var pages []sitePage
+	bySet := map[string]*siteTerm{}
+	byType := map[string]*siteTerm{}
+	byChar := map[string]*siteTerm{}
+	taxonomies := []struct {
+		dir, title string
+		terms      map[string]*siteTerm
+		label      func(*siteTerm) string
+	}{
+		{"sets", "Sets", bySet, func(t *siteTerm) string { return t.title() + " — " + setcode.Name(t.title()) }},
+		{"types", "Card types", byType, (*siteTerm).title},
+		{"characters", "Characters", byChar, (*siteTerm).title},
+	}
+	// Everything under these directories is generated; clear it so pages
+	// for cards gone from the manifest do not linger. Other files in -out
+	// are left alone.
+	stale := []string{"cards"}
+	for _, tx := range taxonomies {
+		stale = append(stale, tx.dir)
+	}
+	for _, dir := range stale {
+		if err := os.RemoveAll(filepath.Join(out, dir)); err != nil {
+			return err
+		}
+	}
+
+	copied := 0
+	for _, s := range cat.Sets {
+		used := map[string]bool{}
+		cards := s.OwnCards()
+		sort.SliceStable(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+		for _, c := range cards {
+			set := strings.ToLower(c.Field("Set"))
+			p := sitePage{card: c, slug: cardSlug(c)}
+			for n := 2; used[p.slug]; n++ {
+				p.slug = fmt.Sprintf("%s-%d", cardSlug(c), n)
+			}
+			used[p.slug] = true
+			p.url = "/cards/" + set + "/" + p.slug + "/"
+			if src := c.ImagePath(); src != "" {
+				p.image = path.Join(imagePrefix, set, c.ImageName)
+				if staticDir != "" {
+					if err := copyImage(src, filepath.Join(staticDir, set, c.ImageName)); err != nil {
+						return err
+					}
+					copied++
+				}
+			}
+			if err := writeCardPage(filepath.Join(out, "cards", set, p.slug+".md"), p, jekyll); err != nil {
+				return err
+			}
+			pages = append(pages, p)
+			addTerm(bySet, c.Field("Set"), p)
+			addTerm(byType, c.Type(), p)
+			for _, ch := range c.Characters() {
+				addTerm(byChar, ch, p)
+			}
+		}
+	}
+
+	for _, tx := range taxonomies {
+		for slug, t := range tx.terms {
+			p := filepath.Join(out, tx.dir, slug, index)
+			if err := writeListing(p, tx.label(t), "/"+tx.dir+"/"+slug+"/", t.pages, jekyll); err != nil {
+				return err
+			}
+		}
+		p := filepath.Join(out, tx.dir, index)
+		if err := writeTermIndex(p, tx.title, "/"+tx.dir+"/", tx.terms, tx.label, jekyll); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("[OK ] %d card pages, %d sets, %d types, %d characters -> %s\n",
+		len(pages), len(bySet), len(byType), len(byChar), out)
+	if staticDir != "" {
+		fmt.Printf("[OK ] %d images -> %s\n", copied, staticDir)
+	}
+	return nil
+}
+
+func copyImage(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
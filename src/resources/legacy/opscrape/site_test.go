@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSiteStableAndClean(t *testing.T) {
+	for _, flavor := range []string{"hugo", "jekyll"} {
+		t.Run(flavor, func(t *testing.T) {
+			out := t.TempDir()
+			run := func() map[string][]byte {
+				t.Helper()
+				if err := runSite([]string{"-root", filepath.Join("testdata", "legacy"), "-flavor", flavor, "-out", out}); err != nil {
+					t.Fatal(err)
+				}
+				return readTree(t, out)
+			}
+			first := run()
+			if _, ok := first["cards/dcop/batman.md"]; !ok {
+				t.Fatalf("no batman page in %d files", len(first))
+			}
+
+			// A page from a card since dropped, a term with no cards left,
+			// and a hand-written page that is not ours to remove.
+			for name, data := range map[string]string{
+				"cards/dcop/dropped.md":      "---\n---\n",
+				"characters/nobody/index.md": "---\n---\n",
+				"about.md":                   "hand-written\n",
+			} {
+				p := filepath.Join(out, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			second := run()
+			if string(second["about.md"]) != "hand-written\n" {
+				t.Error("about.md was removed")
+			}
+			delete(second, "about.md")
+			if len(second) != len(first) {
+				t.Errorf("second run left %d files, first wrote %d", len(second), len(first))
+			}
+			for name, data := range first {
+				if !bytes.Equal(data, second[name]) {
+					t.Errorf("%s differs between runs", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+)
+
+// ---------- Trade rules ----------
+
+// tradeRules weighs cards and says how many copies each side keeps. Weights
+// and keep counts are keyed by canonical rarity or by card ID; card IDs win.
+type tradeRules struct {
+	Weights map[string]int `json:"weights"`
+	Keep    map[string]int `json:"keep"`
+}
+
+// defaultRarityWeights doubles with each step of rarity.
+var defaultRarityWeights = map[string]int{
+	"Common":    1,
+	"Uncommon":  2,
+	"Rare":      4,
+	"Very Rare": 8,
+	"Promo":     6,
+	"Insert":    6,
+	"Silver":    10,
+}
+
+func loadTradeRules(path string, keep int) (*tradeRules, error) {
+	r := &tradeRules{Weights: map[string]int{}, Keep: map[string]int{}}
+	for k, v := range defaultRarityWeights {
+		r.Weights[k] = v
+	}
+	r.Keep["default"] = keep
+	if path == "" {
+		return r, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var custom tradeRules
+	if err := json.NewDecoder(f).Decode(&custom); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Rarity keys go through the catalog vocabulary so "very rare" or
+	// "Commoon" still land on the canonical rarity.
+	canon := func(k string) string {
+		if n := catalog.NormalizeRarity(k); catalog.RarityRank(n) < len(catalog.Rarities) {
+			return n
+		}
+		return k
+	}
+	for k, v := range custom.Weights {
+		if v < 0 {
+			return nil, fmt.Errorf("%s: negative weight for %q", path, k)
+		}
+		r.Weights[canon(k)] = v
+	}
+	for k, v := range custom.Keep {
+		if k != "default" {
+			k = canon(k)
+		}
+		r.Keep[k] = v
+	}
+	return r, nil
+}
+
+func (r *tradeRules) weight(c *catalog.Card) int {
+	if w, ok := r.Weights[c.ID]; ok {
+		return w
+	}
+	if w, ok := r.Weights[c.Rarity()]; ok {
+		return w
+	}
+	return 1
+}
+
+func (r *tradeRules) keep(c *catalog.Card) int {
+	if n, ok := r.Keep[c.ID]; ok {
+		return n
+	}
+	if n, ok := r.Keep[c.Rarity()]; ok {
+		return n
+	}
+	return r.Keep["default"]
+}
+
+// ---------- Matching ----------
+
+type tradeItem struct {
+	Card   *catalog.Card `json:"-"`
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Set    string        `json:"set"`
+	Rarity string        `json:"rarity"`
+	Weight int           `json:"weight"`
+}
+
+type tradeProposal struct {
+	AGives  []tradeItem `json:"a_gives"`
+	BGives  []tradeItem `json:"b_gives"`
+	AWeight int         `json:"a_weight"`
+	BWeight int         `json:"b_weight"`
+}
+
+// offers lists, one entry per copy, the cards giver can spare that taker wants.
+func offers(giver, takerWants *Collection, rules *tradeRules) []tradeItem {
+	var out []tradeItem
+	for c, have := range giver.Owned {
+		spare := have - rules.keep(c)
+		want := takerWants.Owned[c]
+		for n := 0; n < spare && n < want; n++ {
+			out = append(out, tradeItem{Card: c, ID: c.ID, Name: c.Name, Set: c.Set.Code, Rarity: c.Rarity(), Weight: rules.weight(c)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Weight != out[j].Weight {
+			return out[i].Weight > out[j].Weight
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// subsetSums returns, for every reachable total weight, one subset of items
+// (as indexes) that sums to it. Zero-weight items cost nothing, so they are
+// in every subset. Sums are extended in ascending order so the subset kept
+// for a total does not depend on map order.
+func subsetSums(items []tradeItem) map[int][]int {
+	var free []int
+	for i, it := range items {
+		if it.Weight == 0 {
+			free = append(free, i)
+		}
+	}
+	sums := map[int][]int{0: free}
+	for i, it := range items {
+		if it.Weight == 0 {
+			continue
+		}
+		keys := make([]int, 0, len(sums))
+		for s := range sums {
+			keys = append(keys, s)
+		}
+		sort.Ints(keys)
+		for _, s := range keys {
+			t := s + it.Weight
+			if _, ok := sums[t]; !ok {
+				sums[t] = append(append([]int{}, sums[s]...), i)
+			}
+		}
+	}
+	return sums
+}
+
+// matchTrades ranks the pairs of subsets whose totals differ by at most
+// tolerance and returns up to limit of them: the most weight traded first
+// (by the smaller side), then the closest balance, then the smaller trade.
+// Each proposal trades a different pair of totals.
+func matchTrades(aOffers, bOffers []tradeItem, tolerance, limit int) []tradeProposal {
+	aSums, bSums := subsetSums(aOffers), subsetSums(bOffers)
+
+	type pair struct{ a, b, lo, d int }
+	var pairs []pair
+	for sa, ai := range aSums {
+		for sb, bi := range bSums {
+			d := sa - sb
+			if d < 0 {
+				d = -d
+			}
+			if d > tolerance || len(ai) == 0 || len(bi) == 0 {
+				continue
+			}
+			pairs = append(pairs, pair{sa, sb, min(sa, sb), d})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		p, q := pairs[i], pairs[j]
+		switch {
+		case p.lo != q.lo:
+			return p.lo > q.lo
+		case p.d != q.d:
+			return p.d < q.d
+		case p.a+p.b != q.a+q.b:
+			return p.a+p.b < q.a+q.b
+		}
+		return p.a < q.a
+	})
+	if len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+
+	out := make([]tradeProposal, 0, len(pairs))
+	for _, pr := range pairs {
+		p := tradeProposal{AWeight: pr.a, BWeight: pr.b}
+		for _, i := range aSums[pr.a] {
+			p.AGives = append(p.AGives, aOffers[i])
+		}
+		for _, i := range bSums[pr.b] {
+			p.BGives = append(p.BGives, bOffers[i])
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// wantsFromMissing treats every card the owner lacks as wanted once.
+func wantsFromMissing(cat *catalog.Catalog, col *Collection) *Collection {
+	w := &Collection{Owned: map[*catalog.Card]int{}}
+	for _, s := range cat.Sets {
+		for _, c := range s.OwnCards() {
+			if col.Owned[c] == 0 {
+				w.Owned[c] = 1
+			}
+		}
+	}
+	return w
+}
+
+// ---------- Command ----------
+
+func runTrade(args []string) error {
+	var root, aPath, bPath, aWantPath, bWantPath, cardsPath, rulesPath, format string
+	var keep, tolerance, limit int
+	fs := newFlagSet("trade", &root)
+	fs.StringVar(&aPath, "a", "", "Collection of player A (required)")
+	fs.StringVar(&bPath, "b", "", "Collection of player B (required)")
+	fs.StringVar(&aWantPath, "a-wants", "", "Want-list of player A (default: everything A is missing)")
+	fs.StringVar(&bWantPath, "b-wants", "", "Want-list of player B (default: everything B is missing)")
+	fs.StringVar(&cardsPath, "cards", "", "Deckbuilder card table for collection_cards UUIDs (see collection -cards)")
+	fs.StringVar(&rulesPath, "rules", "", `JSON weight/keep table, e.g. {"weights":{"Rare":5},"keep":{"Very Rare":2}}`)
+	fs.IntVar(&keep, "keep", 1, "Copies of each card both players keep")
+	fs.IntVar(&tolerance, "tolerance", 2, "Largest allowed weight difference between the two sides")
+	fs.IntVar(&limit, "proposals", 3, "Alternative trades to propose, best first")
+	fs.StringVar(&format, "format", "md", "Output format: md or json")
+	fs.Parse(args)
+
+	if aPath == "" || bPath == "" {
+		return fmt.Errorf("missing -a or -b")
+	}
+	if limit < 1 {
+		return fmt.Errorf("-proposals must be at least 1")
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	rules, err := loadTradeRules(rulesPath, keep)
+	if err != nil {
+		return err
+	}
+	table, err := loadCardTable(cardsPath)
+	if err != nil {
+		return err
+	}
+
+	load := func(path string, owner *Collection) (*Collection, error) {
+		if path == "" {
+			return wantsFromMissing(cat, owner), nil
+		}
+		col, err := loadCollection(path, cat, table)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range col.Unmatched {
+			fmt.Fprintf(os.Stderr, "[WARN] %s line %d: no catalog match for %q\n", path, l.Line, firstNonEmpty(l.Name, l.CardID))
+		}
+		return col, nil
+	}
+	a, err := load(aPath, nil)
+	if err != nil {
+		return err
+	}
+	b, err := load(bPath, nil)
+	if err != nil {
+		return err
+	}
+	aWants, err := load(aWantPath, a)
+	if err != nil {
+		return err
+	}
+	bWants, err := load(bWantPath, b)
+	if err != nil {
+		return err
+	}
+
+	ps := matchTrades(offers(a, bWants, rules), offers(b, aWants, rules), tolerance, limit)
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"proposals": ps})
+	case "md":
+		w := bufio.NewWriter(os.Stdout)
+		writeTradeMarkdown(w, ps)
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown -format %q", format)
+}
+
+func writeTradeMarkdown(w io.Writer, ps []tradeProposal) {
+	fmt.Fprintln(w, "# OverPower — Trade Proposals")
+	fmt.Fprintln(w)
+	if len(ps) == 0 {
+		fmt.Fprintln(w, "_No balanced trade found._")
+		return
+	}
+	side := func(title string, items []tradeItem, total int) {
+		fmt.Fprintf(w, "### %s (weight %d)\n\n", title, total)
+		fmt.Fprintln(w, "| Card | Set | Rarity | Weight |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- |")
+		for _, it := range items {
+			fmt.Fprintf(w, "| %s | %s | %s | %d |\n", strings.ReplaceAll(it.Name, "|", "\\|"), it.Set, it.Rarity, it.Weight)
+		}
+		fmt.Fprintln(w)
+	}
+	for i, p := range ps {
+		fmt.Fprintf(w, "## Proposal %d\n\n", i+1)
+		side("A gives B", p.AGives, p.AWeight)
+		side("B gives A", p.BGives, p.BWeight)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"opscrape/catalog"
+)
+
+func weights(items []tradeItem) []int {
+	var out []int
+	for _, it := range items {
+		out = append(out, it.Weight)
+	}
+	return out
+}
+
+func items(ws ...int) []tradeItem {
+	out := make([]tradeItem, len(ws))
+	for i, w := range ws {
+		out[i] = tradeItem{ID: string(rune('a' + i)), Weight: w}
+	}
+	return out
+}
+
+func TestSubsetSumsKeepsZeroWeight(t *testing.T) {
+	sums := subsetSums(items(4, 2, 0))
+	want := map[int][]int{0: {2}, 2: {2, 1}, 4: {2, 0}, 6: {2, 0, 1}}
+	if !reflect.DeepEqual(sums, want) {
+		t.Errorf("sums = %v, want %v", sums, want)
+	}
+}
+
+func TestMatchTrades(t *testing.T) {
+	a, b := items(4, 2, 1), items(8, 2)
+	tests := []struct {
+		name      string
+		tolerance int
+		limit     int
+		want      [][2][]int // weights A gives, B gives
+	}{
+		{"exact only", 0, 5, [][2][]int{
+			{{2}, {2}},
+		}},
+		{"within one", 1, 5, [][2][]int{
+			{{4, 2, 1}, {8}},
+			{{2}, {2}},
+			{{2, 1}, {2}},
+			{{1}, {2}},
+		}},
+		{"limited", 1, 2, [][2][]int{
+			{{4, 2, 1}, {8}},
+			{{2}, {2}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := matchTrades(a, b, tt.tolerance, tt.limit)
+			var got [][2][]int
+			for _, p := range ps {
+				got = append(got, [2][]int{weights(p.AGives), weights(p.BGives)})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("proposals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTradesNeedsBothSides(t *testing.T) {
+	if ps := matchTrades(items(1, 1), nil, 2, 3); len(ps) != 0 {
+		t.Errorf("one-sided trade proposed: %+v", ps)
+	}
+	// Zero-weight cards are still worth proposing as a swap.
+	ps := matchTrades(items(0), items(0), 2, 3)
+	if len(ps) != 1 || len(ps[0].AGives) != 1 || len(ps[0].BGives) != 1 {
+		t.Errorf("proposals = %+v, want the two zero-weight cards swapped", ps)
+	}
+}
+
+func TestMatchTradesStable(t *testing.T) {
+	a, b := items(2, 2, 1, 1, 1), items(2, 1, 1, 1, 1)
+	first := matchTrades(a, b, 1, 5)
+	for i := 0; i < 20; i++ {
+		if got := matchTrades(a, b, 1, 5); !reflect.DeepEqual(got, first) {
+			t.Fatalf("run %d: %+v, want %+v", i, got, first)
+		}
+	}
+}
+
+func TestOffers(t *testing.T) {
+	cat := testCatalog(t)
+	batman, _ := cat.Card("Batman_(DCOP)")
+	flash, _ := cat.Card("Flash_(DCOP)")
+	cave, _ := cat.Card("Batcave_(DCOP)")
+	rules, err := loadTradeRules("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules.Keep["Very Rare"] = 2
+
+	giver := &Collection{Owned: map[*catalog.Card]int{batman: 4, flash: 1, cave: 2}}
+	wants := &Collection{Owned: map[*catalog.Card]int{batman: 2, flash: 1, cave: 1}}
+	got := offers(giver, wants, rules)
+	// Batman: 3 spare, 2 wanted. Flash: none spare. Batcave: kept.
+	if len(got) != 2 || got[0].ID != "Batman_(DCOP)" || got[1].ID != "Batman_(DCOP)" || got[0].Weight != 1 {
+		t.Errorf("offers = %+v", got)
+	}
+}
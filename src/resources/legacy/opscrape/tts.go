@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"opscrape/catalog"
+	"opscrape/tts"
+)
+
+func runTTS(args []string) error {
+	var root, deckPath, cardsPath, setCode, outDir, backPath, baseURL, name string
+	var width, quality int
+	fs := newFlagSet("tts", &root)
+	fs.StringVar(&deckPath, "deck", "", "Deck JSON in the deckbuilder format")
+	fs.StringVar(&cardsPath, "cards", "", "Deckbuilder card table for the deckbuilder's cardIds (see collection -cards)")
+	fs.StringVar(&setCode, "set", "", "Export a whole set instead, one of each card, e.g. CLOP")
+	fs.StringVar(&outDir, "out", "tts", "Directory for the sheets and saved object")
+	fs.IntVar(&width, "width", 300, "Card width in pixels on the sheets; height is 1.4x")
+	fs.IntVar(&quality, "quality", 90, "JPEG quality of the sheets")
+	fs.StringVar(&backPath, "back", "", "Card back image (default: a generated plain back)")
+	fs.StringVar(&baseURL, "base-url", "", "URL the sheets will be served from (default: file URLs into -out)")
+	fs.StringVar(&name, "name", "", "Deck name in TTS (default: the deck or set name)")
+	fs.Parse(args)
+
+	if (deckPath == "") == (setCode == "") {
+		return fmt.Errorf("need exactly one of -deck or -set")
+	}
+	if width < 50 || width*tts.Columns > 10000 {
+		return fmt.Errorf("-width %d: want 50-%d so sheets stay within TTS's 10000px limit", width, 10000/tts.Columns)
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+
+	// The deck in order, one entry per distinct card.
+	var items []deckItem
+	var id string
+	if deckPath != "" {
+		table, err := loadCardTable(cardsPath)
+		if err != nil {
+			return err
+		}
+		d, its, err := readDeckCards(cat, table, deckPath)
+		if err != nil {
+			return err
+		}
+		items, id = its, firstNonEmpty(d.ID, strings.TrimSuffix(filepath.Base(deckPath), filepath.Ext(deckPath)))
+		name = firstNonEmpty(name, d.Name, id)
+	} else {
+		s, ok := cat.Set(setCode)
+		if !ok {
+			return fmt.Errorf("unknown -set %q", setCode)
+		}
+		cards := s.OwnCards()
+		sort.SliceStable(cards, func(i, j int) bool { return cards[i].Name < cards[j].Name })
+		for _, c := range cards {
+			items = append(items, deckItem{c, 1})
+		}
+		id, name = strings.ToLower(s.Code), firstNonEmpty(name, s.Name)
+	}
+
+	w, h := tts.CardSize(width)
+	back := tts.DefaultBack(w, h)
+	if backPath != "" {
+		if back, err = tts.LoadImage(backPath); err != nil {
+			return err
+		}
+	}
+
+	faces := make([]tts.Face, len(items))
+	for i, it := range items {
+		faces[i] = tts.Face{Name: it.card.Name, Image: it.card.ImagePath()}
+	}
+	sheets, slots, missing := tts.BuildSheets(faces, back, width)
+	for _, m := range missing {
+		fmt.Printf("[WARN] no usable image for %s; drew a placeholder\n", m)
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	sheetURL, err := sheetURLFunc(outDir, baseURL)
+	if err != nil {
+		return err
+	}
+	backFile := id + "_back.jpg"
+	if err := tts.WriteJPEG(filepath.Join(outDir, backFile), back, quality); err != nil {
+		return err
+	}
+	var faceURLs []string
+	for i, s := range sheets {
+		file := fmt.Sprintf("%s_sheet_%d.jpg", id, i+1)
+		if err := tts.WriteJPEG(filepath.Join(outDir, file), image.Image(s), quality); err != nil {
+			return err
+		}
+		faceURLs = append(faceURLs, sheetURL(file))
+		fmt.Printf("[OK ] sheet %d -> %s\n", i+1, filepath.Join(outDir, file))
+	}
+
+	var cards []tts.DeckCard
+	for i, it := range items {
+		for n := 0; n < it.qty; n++ {
+			cards = append(cards, tts.DeckCard{Name: it.card.Name, Description: it.card.GameText(), Slot: slots[i]})
+		}
+	}
+	obj := tts.NewSavedObject(name, cards, faceURLs, sheetURL(backFile))
+	objPath := filepath.Join(outDir, id+".json")
+	if err := writeJSONFile(objPath, obj); err != nil {
+		return err
+	}
+	fmt.Printf("[OK ] %d cards on %d sheets -> %s\n", len(cards), len(sheets), objPath)
+	return nil
+}
+
+// sheetURLFunc returns how the saved object refers to a file in outDir:
+// under baseURL when given, else as a file URL.
+func sheetURLFunc(outDir, baseURL string) (func(string) string, error) {
+	if baseURL != "" {
+		base := strings.TrimSuffix(baseURL, "/")
+		return func(file string) string { return base + "/" + url.PathEscape(file) }, nil
+	}
+	abs, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, err
+	}
+	return func(file string) string {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(abs, file))}).String()
+	}, nil
+}
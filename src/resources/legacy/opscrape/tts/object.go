@@ -0,0 +1,99 @@
+package tts
+
+import (
+	"strconv"
+)
+
+// ---------- Saved object ----------
+
+// SavedObject is a TTS "Saved Objects" file: drop it in
+// Documents/My Games/Tabletop Simulator/Saves/Saved Objects.
+type SavedObject struct {
+	SaveName     string        `json:"SaveName"`
+	GameMode     string        `json:"GameMode"`
+	Date         string        `json:"Date"`
+	Table        string        `json:"Table"`
+	Sky          string        `json:"Sky"`
+	Note         string        `json:"Note"`
+	Rules        string        `json:"Rules"`
+	XMLUI        string        `json:"XmlUI"`
+	LuaScript    string        `json:"LuaScript"`
+	ObjectStates []ObjectState `json:"ObjectStates"`
+	TabStates    struct{}      `json:"TabStates"`
+	VersionNum   string        `json:"VersionNumber"`
+}
+
+// ObjectState is a deck ("DeckCustom") or a single card ("Card").
+type ObjectState struct {
+	Name             string                `json:"Name"`
+	Transform        Transform             `json:"Transform"`
+	Nickname         string                `json:"Nickname"`
+	Description      string                `json:"Description"`
+	CardID           int                   `json:"CardID,omitempty"`
+	DeckIDs          []int                 `json:"DeckIDs,omitempty"`
+	CustomDeck       map[string]CustomDeck `json:"CustomDeck"`
+	ContainedObjects []ObjectState         `json:"ContainedObjects,omitempty"`
+	HideWhenFaceDown bool                  `json:"HideWhenFaceDown"`
+}
+
+type Transform struct {
+	PosX   float64 `json:"posX"`
+	PosY   float64 `json:"posY"`
+	PosZ   float64 `json:"posZ"`
+	RotX   float64 `json:"rotX"`
+	RotY   float64 `json:"rotY"`
+	RotZ   float64 `json:"rotZ"`
+	ScaleX float64 `json:"scaleX"`
+	ScaleY float64 `json:"scaleY"`
+	ScaleZ float64 `json:"scaleZ"`
+}
+
+// CustomDeck is one sheet: FaceURL holds the faces, BackURL the shared back.
+type CustomDeck struct {
+	FaceURL      string `json:"FaceURL"`
+	BackURL      string `json:"BackURL"`
+	NumWidth     int    `json:"NumWidth"`
+	NumHeight    int    `json:"NumHeight"`
+	BackIsHidden bool   `json:"BackIsHidden"`
+	UniqueBack   bool   `json:"UniqueBack"`
+	Type         int    `json:"Type"`
+}
+
+// faceDown is how TTS spawns a deck: face down, cards toward the table.
+var faceDown = Transform{PosY: 1, RotY: 180, RotZ: 180, ScaleX: 1, ScaleY: 1, ScaleZ: 1}
+
+// DeckCard is one copy in the deck.
+type DeckCard struct {
+	Name, Description string
+	Slot              Slot
+}
+
+// CardID is TTS's id for a slot: sheet number (from 1) times 100 plus the
+// index on the sheet.
+func CardID(s Slot) int { return (s.Sheet+1)*100 + s.Index }
+
+// NewSavedObject builds a deck of cards, in order, over the given sheet
+// URLs. A one-card deck is saved as a single Card, as TTS requires.
+func NewSavedObject(name string, cards []DeckCard, faceURLs []string, backURL string) SavedObject {
+	decks := map[string]CustomDeck{}
+	for i, u := range faceURLs {
+		decks[strconv.Itoa(i+1)] = CustomDeck{
+			FaceURL: u, BackURL: backURL, NumWidth: Columns, NumHeight: Rows, BackIsHidden: true,
+		}
+	}
+	deck := ObjectState{Name: "DeckCustom", Transform: faceDown, Nickname: name, CustomDeck: decks}
+	for _, c := range cards {
+		id := CardID(c.Slot)
+		sheet := strconv.Itoa(c.Slot.Sheet + 1)
+		deck.DeckIDs = append(deck.DeckIDs, id)
+		deck.ContainedObjects = append(deck.ContainedObjects, ObjectState{
+			Name: "Card", Transform: faceDown, Nickname: c.Name, Description: c.Description, CardID: id,
+			CustomDeck: map[string]CustomDeck{sheet: decks[sheet]}, HideWhenFaceDown: true,
+		})
+	}
+	obj := deck
+	if len(deck.ContainedObjects) == 1 {
+		obj = deck.ContainedObjects[0]
+	}
+	return SavedObject{SaveName: name, ObjectStates: []ObjectState{obj}}
+}
@@ -0,0 +1,167 @@
+// Package tts builds Tabletop Simulator custom decks: card-image sprite
+// sheets and the saved-object JSON that points at them.
+package tts
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // image.Decode formats
+	"os"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp" // the wiki serves most ".jpg" files as WebP
+)
+
+// ---------- Sheets ----------
+
+// Sheet geometry. TTS shows the last slot of a face sheet for cards hidden
+// in other players' hands, so each sheet holds PerSheet cards plus that
+// hidden image.
+const (
+	Columns  = 10
+	Rows     = 7
+	PerSheet = Columns*Rows - 1
+)
+
+// Face is one unique card image to place on a sheet.
+type Face struct {
+	Name  string
+	Image string // path to the downloaded image; "" draws a placeholder
+}
+
+// Slot is where a face landed: sheet index and position on it.
+type Slot struct {
+	Sheet, Index int
+}
+
+// CardSize returns the slot size for a card width; OverPower cards are
+// 2.5 x 3.5 inches.
+func CardSize(width int) (int, int) {
+	return width, width * 7 / 5
+}
+
+// LoadImage decodes a JPEG, PNG or WebP file, whatever its extension says.
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+// BuildSheets lays faces out in order, PerSheet to a sheet, and puts hidden
+// in the last slot of every sheet. Images that fail to load are drawn as a
+// named placeholder and reported in missing.
+func BuildSheets(faces []Face, hidden image.Image, cardWidth int) (sheets []*image.RGBA, slots []Slot, missing []string) {
+	w, h := CardSize(cardWidth)
+	for i, f := range faces {
+		si, idx := i/PerSheet, i%PerSheet
+		if si == len(sheets) {
+			sheets = append(sheets, newSheet(w, h, hidden))
+		}
+		img, err := loadFace(f)
+		if err != nil {
+			missing = append(missing, f.Name)
+			img = Placeholder(f.Name, w, h)
+		}
+		draw.CatmullRom.Scale(sheets[si], slotRect(idx, w, h), img, img.Bounds(), draw.Src, nil)
+		slots = append(slots, Slot{Sheet: si, Index: idx})
+	}
+	return sheets, slots, missing
+}
+
+func loadFace(f Face) (image.Image, error) {
+	if f.Image == "" {
+		return nil, fmt.Errorf("%s: no image", f.Name)
+	}
+	return LoadImage(f.Image)
+}
+
+func newSheet(w, h int, hidden image.Image) *image.RGBA {
+	s := image.NewRGBA(image.Rect(0, 0, Columns*w, Rows*h))
+	draw.Draw(s, s.Bounds(), image.Black, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(s, slotRect(Columns*Rows-1, w, h), hidden, hidden.Bounds(), draw.Src, nil)
+	return s
+}
+
+func slotRect(i, w, h int) image.Rectangle {
+	x, y := (i%Columns)*w, (i/Columns)*h
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// ---------- Generated images ----------
+
+var (
+	backFill   = color.RGBA{0x1d, 0x2b, 0x53, 0xff}
+	backBorder = color.RGBA{0xc8, 0xa2, 0x3c, 0xff}
+)
+
+// DefaultBack draws a plain card back for when none is given.
+func DefaultBack(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(backBorder), image.Point{}, draw.Src)
+	inset := max(w/20, 2)
+	draw.Draw(img, img.Bounds().Inset(inset), image.NewUniform(backFill), image.Point{}, draw.Src)
+	drawCentered(img, "OverPower", h/2, backBorder)
+	return img
+}
+
+// Placeholder is a blank card with its name, for cards without an image.
+func Placeholder(name string, w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0xee, 0xee, 0xee, 0xff}), image.Point{}, draw.Src)
+	y := h / 3
+	for _, line := range wrap(name, max((w-10)/7, 1)) {
+		drawCentered(img, line, y, color.Black)
+		y += 16
+	}
+	return img
+}
+
+func drawCentered(img *image.RGBA, s string, y int, c color.Color) {
+	face := basicfont.Face7x13
+	x := (img.Bounds().Dx() - font.MeasureString(face, s).Ceil()) / 2
+	d := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(max(x, 2), y)}
+	d.DrawString(s)
+}
+
+// wrap breaks s into lines of at most n characters on spaces.
+func wrap(s string, n int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > n {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// WriteJPEG saves a sheet or the card back.
+func WriteJPEG(path string, img image.Image, quality int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: quality}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,106 @@
+package tts
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func uniform(c color.Color, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// center is the colour in the middle of slot i.
+func center(s *image.RGBA, i, w, h int) color.RGBA {
+	r := slotRect(i, w, h)
+	return s.RGBAAt((r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2)
+}
+
+func TestBuildSheetsLayout(t *testing.T) {
+	green := color.RGBA{0, 0xff, 0, 0xff}
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	path := filepath.Join(t.TempDir(), "green.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, uniform(green, 20, 28)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// 70 faces: a full sheet of 69 and one on a second sheet. The last of
+	// them has no image.
+	faces := make([]Face, 70)
+	for i := range faces {
+		faces[i] = Face{Name: "Card", Image: path}
+	}
+	faces[69] = Face{Name: "No Image"}
+	const width = 10
+	w, h := CardSize(width)
+	sheets, slots, missing := BuildSheets(faces, uniform(red, w, h), width)
+
+	if PerSheet != 69 || len(sheets) != 2 {
+		t.Fatalf("PerSheet = %d, %d sheets", PerSheet, len(sheets))
+	}
+	for _, s := range sheets {
+		if b := s.Bounds(); b.Dx() != 10*w || b.Dy() != 7*h {
+			t.Errorf("sheet is %v, want 10x7 slots of %dx%d", b, w, h)
+		}
+		if got := center(s, 69, w, h); got != red {
+			t.Errorf("last slot = %v, want the hidden image", got)
+		}
+	}
+	if got := center(sheets[0], 68, w, h); got != green {
+		t.Errorf("slot 68 = %v, want the face", got)
+	}
+	if got := center(sheets[1], 1, w, h); got != (color.RGBA{0, 0, 0, 0xff}) {
+		t.Errorf("unused slot = %v, want black", got)
+	}
+	want := []Slot{{0, 0}, {0, 68}, {1, 0}}
+	if got := []Slot{slots[0], slots[68], slots[69]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("slots = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(missing, []string{"No Image"}) {
+		t.Errorf("missing = %v", missing)
+	}
+}
+
+func TestCardID(t *testing.T) {
+	for s, want := range map[Slot]int{{0, 0}: 100, {0, 68}: 168, {1, 0}: 200, {2, 5}: 305} {
+		if got := CardID(s); got != want {
+			t.Errorf("CardID(%v) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestNewSavedObject(t *testing.T) {
+	cards := []DeckCard{{Name: "A", Slot: Slot{0, 0}}, {Name: "A", Slot: Slot{0, 0}}, {Name: "B", Slot: Slot{1, 3}}}
+	obj := NewSavedObject("Deck", cards, []string{"face1.jpg", "face2.jpg"}, "back.jpg")
+	deck := obj.ObjectStates[0]
+	if deck.Name != "DeckCustom" || !reflect.DeepEqual(deck.DeckIDs, []int{100, 100, 203}) {
+		t.Fatalf("deck = %s %v", deck.Name, deck.DeckIDs)
+	}
+	if cd := deck.CustomDeck["2"]; cd.FaceURL != "face2.jpg" || cd.NumWidth != Columns || cd.NumHeight != Rows || !cd.BackIsHidden {
+		t.Errorf("sheet 2 = %+v", cd)
+	}
+	b := deck.ContainedObjects[2]
+	if b.CardID != 203 || len(b.CustomDeck) != 1 || b.CustomDeck["2"].FaceURL != "face2.jpg" {
+		t.Errorf("card B = %+v", b)
+	}
+
+	single := NewSavedObject("One", cards[:1], []string{"face1.jpg"}, "back.jpg").ObjectStates[0]
+	if single.Name != "Card" || single.CardID != 100 {
+		t.Errorf("one-card deck = %s %d, want a single Card", single.Name, single.CardID)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"opscrape/catalog"
+	"opscrape/tui"
+)
+
+func runTUI(args []string) error {
+	var root, overrides, images, cell string
+	fs := newFlagSet("tui", &root)
+	fs.StringVar(&overrides, "overrides", "", "Write every override here (default: <set dir>/overrides.yaml, which the scrapers take with -overrides)")
+	fs.StringVar(&images, "images", "auto", "Image previews: auto, kitty, sixel or none")
+	fs.StringVar(&cell, "cell", "10x20", "Terminal cell size in pixels, for sizing previews")
+	fs.Parse(args)
+
+	proto, err := tui.ParseProtocol(images)
+	if err != nil {
+		return err
+	}
+	var cw, ch int
+	if _, err := fmt.Sscanf(cell, "%dx%d", &cw, &ch); err != nil || cw <= 0 || ch <= 0 {
+		return fmt.Errorf("-cell %q: want WIDTHxHEIGHT, e.g. 10x20", cell)
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		return err
+	}
+	return tui.Run(cat, tui.Options{Overrides: overrides, Images: proto, CellW: cw, CellH: ch})
+}
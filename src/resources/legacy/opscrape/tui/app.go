@@ -0,0 +1,743 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"opscrape/catalog"
+	"opscrape/curate"
+	"opscrape/query"
+	"scraper/setcode"
+)
+
+// Options configure the browser.
+type Options struct {
+	Overrides    string // one overrides file for every edit; "" writes <set dir>/overrides.yaml
+	Images       Protocol
+	CellW, CellH int // terminal cell size in pixels, used to size previews
+}
+
+type view int
+
+const (
+	setsView view = iota
+	cardsView
+	cardView
+)
+
+// prompt is the one-line editor shown in the status row.
+type prompt struct {
+	label, value string
+	done         func(string)
+}
+
+// App is the browser state; render draws it and handle applies one key.
+type App struct {
+	cat  *catalog.Catalog
+	opts Options
+
+	view      view
+	set       *catalog.Set              // nil: every set
+	all, list []*catalog.Card           // the set's cards, and those passing the filters
+	cur, top  [3]int                    // cursor and scroll offset per view
+	pending   map[string][]curate.Entry // overrides on file, by card ID
+
+	query             string
+	expr              query.Expr
+	typ, rarity, char string
+
+	prompt   *prompt
+	status   string
+	quit     bool
+	previews map[string]string
+}
+
+// New opens the browser on the set list and reads the overrides already on
+// file so pending corrections show up.
+func New(cat *catalog.Catalog, opts Options) *App {
+	if opts.CellW <= 0 || opts.CellH <= 0 {
+		opts.CellW, opts.CellH = 10, 20
+	}
+	a := &App{cat: cat, opts: opts, expr: mustParse(""), pending: map[string][]curate.Entry{}, previews: map[string]string{}}
+	var files []string
+	if opts.Overrides != "" {
+		files = []string{opts.Overrides}
+	} else {
+		for _, s := range cat.Sets {
+			files = append(files, filepath.Join(s.Dir, curate.DefaultFile))
+		}
+	}
+	for _, f := range files {
+		entries, err := curate.Load(f)
+		if err != nil {
+			a.status = "[WARN] " + err.Error()
+			continue
+		}
+		for _, e := range entries {
+			id := curate.MatchID(e.Match)
+			a.pending[id] = append(a.pending[id], e)
+		}
+	}
+	return a
+}
+
+func mustParse(q string) query.Expr {
+	e, err := query.Parse(q)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// ---------- State changes ----------
+
+func (a *App) enterSet(s *catalog.Set) {
+	a.set, a.all = s, nil
+	if s != nil {
+		a.all = s.OwnCards()
+	} else {
+		for _, c := range a.cat.Cards {
+			if c.IsOwn() {
+				a.all = append(a.all, c)
+			}
+		}
+	}
+	sort.SliceStable(a.all, func(i, j int) bool {
+		if a.all[i].Name != a.all[j].Name {
+			return a.all[i].Name < a.all[j].Name
+		}
+		return a.all[i].ID < a.all[j].ID
+	})
+	a.typ, a.rarity = "", ""
+	a.cur[cardsView], a.top[cardsView] = 0, 0
+	a.filter()
+	a.view = cardsView
+}
+
+func (a *App) filter() {
+	a.list = a.list[:0]
+	for _, c := range a.all {
+		if !a.expr.Match(c) || (a.typ != "" && c.Type() != a.typ) || (a.rarity != "" && c.Rarity() != a.rarity) {
+			continue
+		}
+		if a.char != "" && !hasCharacter(c, a.char) {
+			continue
+		}
+		a.list = append(a.list, c)
+	}
+	a.cur[cardsView] = min(a.cur[cardsView], max(len(a.list)-1, 0))
+}
+
+func hasCharacter(c *catalog.Card, sub string) bool {
+	sub = strings.ToLower(sub)
+	for _, ch := range c.Characters() {
+		if strings.Contains(strings.ToLower(ch), sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// cycle steps cur through "" and then each of vals.
+func cycle(vals []string, cur string) string {
+	for i, v := range vals {
+		if v == cur {
+			if i+1 < len(vals) {
+				return vals[i+1]
+			}
+			return ""
+		}
+	}
+	if len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+func (a *App) types() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, c := range a.all {
+		if t := c.Type(); t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (a *App) rarities() []string {
+	seen := map[string]bool{}
+	for _, c := range a.all {
+		seen[c.Rarity()] = true
+	}
+	var out []string
+	for _, r := range catalog.Rarities {
+		if seen[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (a *App) card() *catalog.Card {
+	if len(a.list) == 0 {
+		return nil
+	}
+	return a.list[a.cur[cardsView]]
+}
+
+// fieldKeys are the card's rows plus rows only an override adds.
+func (a *App) fieldKeys(c *catalog.Card) []string {
+	keys := append([]string(nil), c.OrderedKeys...)
+	for _, e := range a.pending[c.ID] {
+		for k := range e.Fields {
+			if _, ok := c.KV[k]; !ok && !contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// pendingValue is the last value an override on file gives field k.
+func (a *App) pendingValue(c *catalog.Card, k string) (string, bool) {
+	v, ok := "", false
+	for _, e := range a.pending[c.ID] {
+		if nv, has := e.Fields[k]; has {
+			v, ok = nv, true
+		}
+	}
+	return v, ok
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ask opens a prompt; done runs with the entered text on Enter.
+func (a *App) ask(label, value string, done func(string)) {
+	a.prompt = &prompt{label: label, value: value, done: done}
+}
+
+// addOverride asks for a note and appends e for c to its overrides file.
+// The match is c.ID, which names one printing ("Flash_(DCOP)#silver"), so
+// the scraper applies the override to that printing only.
+func (a *App) addOverride(c *catalog.Card, e curate.Entry) {
+	a.ask("Note (optional): ", "", func(note string) {
+		e.Match, e.Note = c.ID, strings.TrimSpace(note)
+		path := curate.PathFor(c, a.opts.Overrides)
+		if err := curate.Append(path, e); err != nil {
+			a.status = "[ERR] " + err.Error()
+			return
+		}
+		a.pending[c.ID] = append(a.pending[c.ID], e)
+		a.status = fmt.Sprintf("[OK ] override for %s written to %s; it applies on the next scrape", c.ID, path)
+	})
+}
+
+// ---------- Keys ----------
+
+func (a *App) handle(k key) {
+	if k.code == keyCtrlC {
+		a.quit = true
+		return
+	}
+	if a.prompt != nil {
+		a.handlePrompt(k)
+		return
+	}
+	a.status = ""
+	n := [3]int{len(a.cat.Sets) + 1, len(a.list), 0}
+	if c := a.card(); a.view == cardView && c != nil {
+		n[cardView] = len(a.fieldKeys(c))
+	}
+	if a.move(k, n[a.view]) {
+		return
+	}
+	switch a.view {
+	case setsView:
+		a.handleSets(k)
+	case cardsView:
+		a.handleCards(k)
+	case cardView:
+		a.handleCard(k)
+	}
+}
+
+func (a *App) handlePrompt(k key) {
+	p := a.prompt
+	switch k.code {
+	case keyEnter:
+		a.prompt = nil
+		p.done(p.value)
+	case keyEsc:
+		a.prompt = nil
+	case keyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(p.value); size > 0 {
+			p.value = p.value[:len(p.value)-size]
+		}
+	case keyCtrlU:
+		p.value = ""
+	case keyRune:
+		p.value += string(k.r)
+	}
+}
+
+// move handles cursor keys for the current view's list of n rows.
+func (a *App) move(k key, n int) bool {
+	cur := &a.cur[a.view]
+	page := 10
+	switch {
+	case k.code == keyUp || k.r == 'k':
+		*cur--
+	case k.code == keyDown || k.r == 'j':
+		*cur++
+	case k.code == keyPgUp:
+		*cur -= page
+	case k.code == keyPgDn || k.r == ' ':
+		*cur += page
+	case k.code == keyHome || k.r == 'g':
+		*cur = 0
+	case k.code == keyEnd || k.r == 'G':
+		*cur = n - 1
+	default:
+		return false
+	}
+	*cur = max(min(*cur, n-1), 0)
+	return true
+}
+
+func isBack(k key) bool {
+	return k.code == keyEsc || k.code == keyLeft || k.code == keyBackspace || k.r == 'h'
+}
+
+func isOpen(k key) bool {
+	return k.code == keyEnter || k.code == keyRight || k.r == 'l'
+}
+
+func (a *App) handleSets(k key) {
+	switch {
+	case k.r == 'q' || k.code == keyEsc:
+		a.quit = true
+	case isOpen(k):
+		if i := a.cur[setsView]; i == 0 {
+			a.enterSet(nil)
+		} else {
+			a.enterSet(a.cat.Sets[i-1])
+		}
+	}
+}
+
+func (a *App) handleCards(k key) {
+	switch {
+	case k.r == 'q':
+		a.quit = true
+	case isBack(k):
+		a.view = setsView
+	case isOpen(k):
+		if a.card() != nil {
+			a.view, a.cur[cardView], a.top[cardView] = cardView, 0, 0
+		}
+	case k.r == '/':
+		a.ask("Filter: ", a.query, func(q string) {
+			e, err := query.Parse(q)
+			if err != nil {
+				a.status = "[ERR] " + err.Error()
+				return
+			}
+			a.query, a.expr = strings.TrimSpace(q), e
+			a.filter()
+		})
+	case k.r == 't':
+		a.typ = cycle(a.types(), a.typ)
+		a.filter()
+	case k.r == 'r':
+		a.rarity = cycle(a.rarities(), a.rarity)
+		a.filter()
+	case k.r == 'c':
+		a.ask("Character: ", a.char, func(s string) {
+			a.char = strings.TrimSpace(s)
+			a.filter()
+		})
+	case k.r == 'x':
+		a.query, a.expr, a.typ, a.rarity, a.char = "", mustParse(""), "", "", ""
+		a.filter()
+	}
+}
+
+func (a *App) handleCard(k key) {
+	c := a.card()
+	if c == nil {
+		a.view = cardsView
+		return
+	}
+	keys := a.fieldKeys(c)
+	field := ""
+	if i := a.cur[cardView]; i < len(keys) {
+		field = keys[i]
+	}
+	switch {
+	case k.r == 'q':
+		a.quit = true
+	case isBack(k):
+		a.view = cardsView
+	case k.r == ']' || k.code == keyTab:
+		a.cur[cardsView] = min(a.cur[cardsView]+1, len(a.list)-1)
+		a.cur[cardView], a.top[cardView] = 0, 0
+	case k.r == '[':
+		a.cur[cardsView] = max(a.cur[cardsView]-1, 0)
+		a.cur[cardView], a.top[cardView] = 0, 0
+	case k.r == 'e' && field != "":
+		old := c.KV[field]
+		if v, ok := a.pendingValue(c, field); ok {
+			old = v
+		}
+		a.ask(field+" = ", old, func(v string) {
+			a.addOverride(c, curate.Entry{Fields: map[string]string{field: strings.TrimSpace(v)}})
+		})
+	case k.r == 'a':
+		a.ask("New field name: ", "", func(name string) {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return
+			}
+			a.ask(name+" = ", "", func(v string) {
+				a.addOverride(c, curate.Entry{Fields: map[string]string{name: strings.TrimSpace(v)}})
+			})
+		})
+	case k.r == 'd' && field != "":
+		a.ask(fmt.Sprintf("Remove %s? (y/n) ", field), "", func(yes string) {
+			if strings.HasPrefix(strings.ToLower(yes), "y") {
+				a.addOverride(c, curate.Entry{Fields: map[string]string{field: ""}})
+			}
+		})
+	case k.r == 'N':
+		a.ask("Name = ", c.Name, func(v string) {
+			if v = strings.TrimSpace(v); v != "" && v != c.Name {
+				a.addOverride(c, curate.Entry{Name: v})
+			}
+		})
+	case k.r == 'i':
+		a.ask("Image URL = ", "", func(v string) {
+			if v = strings.TrimSpace(v); v != "" {
+				a.addOverride(c, curate.Entry{Image: v})
+			}
+		})
+	case k.r == 'S':
+		a.ask(fmt.Sprintf("Suppress %s from future scrapes? (y/n) ", c.ID), "", func(yes string) {
+			if strings.HasPrefix(strings.ToLower(yes), "y") {
+				a.addOverride(c, curate.Entry{Suppress: true})
+			}
+		})
+	}
+}
+
+// ---------- Rendering ----------
+
+const (
+	reverse = "\x1b[7m"
+	bold    = "\x1b[1m"
+	dim     = "\x1b[2m"
+	yellow  = "\x1b[33m"
+	reset   = "\x1b[0m"
+)
+
+var help = [3]string{
+	"↑↓ move  enter open  q quit",
+	"↑↓ move  enter open  / query  t type  r rarity  c character  x clear  esc back  q quit",
+	"↑↓ field  [ ] prev/next card  e edit  a add  d remove  N rename  i image  S suppress  esc back",
+}
+
+func (a *App) render(w io.Writer, cols, rows int) {
+	var sb strings.Builder
+	if a.opts.Images == Kitty {
+		sb.WriteString(kittyDeleteAll)
+	}
+	sb.WriteString("\x1b[H\x1b[2J")
+	line := func(row int, s string) { fmt.Fprintf(&sb, "\x1b[%d;1H%s", row+1, s) }
+
+	line(0, reverse+fit(a.header(), cols)+reset)
+	body := rows - 3
+	var image func()
+	switch a.view {
+	case setsView:
+		a.renderSets(line, cols, body)
+	case cardsView:
+		a.renderCards(line, cols, body)
+	case cardView:
+		image = a.renderCard(&sb, line, cols, body)
+	}
+	if p := a.prompt; p != nil {
+		line(rows-2, fit(p.label+p.value+"_", cols))
+	} else {
+		line(rows-2, fit(a.status, cols))
+	}
+	line(rows-1, dim+fit(help[a.view], cols)+reset)
+	if image != nil {
+		image()
+	}
+	io.WriteString(w, sb.String())
+}
+
+func (a *App) header() string {
+	h := "OverPower catalog"
+	if a.view == setsView {
+		return fmt.Sprintf("%s — %d sets, %d cards", h, len(a.cat.Sets), len(a.cat.Cards))
+	}
+	name := "All sets"
+	if a.set != nil {
+		name = a.set.Code + " — " + a.set.Name
+	}
+	h = fmt.Sprintf("%s — %s — %d of %d cards", h, name, len(a.list), len(a.all))
+	var f []string
+	for _, p := range [][2]string{{"query", a.query}, {"type", a.typ}, {"rarity", a.rarity}, {"character", a.char}} {
+		if p[1] != "" {
+			f = append(f, p[0]+": "+p[1])
+		}
+	}
+	if len(f) > 0 {
+		h += " — " + strings.Join(f, ", ")
+	}
+	return h
+}
+
+// scroll keeps the cursor of the current view inside a window of n rows
+// and returns the first visible index.
+func (a *App) scroll(n int) int {
+	cur, top := a.cur[a.view], &a.top[a.view]
+	if cur < *top {
+		*top = cur
+	}
+	if cur >= *top+n {
+		*top = cur - n + 1
+	}
+	return *top
+}
+
+func (a *App) renderSets(line func(int, string), cols, body int) {
+	rows := []string{fmt.Sprintf("%-6s %-24s %5d cards", "*", "All sets", a.countOwn(nil))}
+	for _, s := range a.cat.Sets {
+		rows = append(rows, fmt.Sprintf("%-6s %-24s %5d cards", s.Code, s.Name, a.countOwn(s)))
+	}
+	top := a.scroll(body)
+	for i := top; i < len(rows) && i < top+body; i++ {
+		s := fit(" "+rows[i], cols)
+		if i == a.cur[setsView] {
+			s = reverse + s + reset
+		}
+		line(1+i-top, s)
+	}
+}
+
+func (a *App) countOwn(s *catalog.Set) int {
+	if s != nil {
+		return len(s.OwnCards())
+	}
+	n := 0
+	for _, c := range a.cat.Cards {
+		if c.IsOwn() {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *App) renderCards(line func(int, string), cols, body int) {
+	if len(a.list) == 0 {
+		line(2, fit("  No cards match; x clears the filters.", cols))
+		return
+	}
+	nameW := max(cols-2-6-16-10-3, 10)
+	top := a.scroll(body)
+	for i := top; i < len(a.list) && i < top+body; i++ {
+		c := a.list[i]
+		mark := " "
+		if len(a.pending[c.ID]) > 0 {
+			mark = "*"
+		}
+		s := fit(fmt.Sprintf("%s%s %s %s %s", mark, fit(c.Name, nameW), fit(c.Field("Set"), 6), fit(c.Type(), 16), fit(c.Rarity(), 10)), cols)
+		if i == a.cur[cardsView] {
+			s = reverse + s + reset
+		}
+		line(1+i-top, s)
+	}
+}
+
+// renderCard draws the fields and returns a func that draws the preview
+// once the text is down, or nil.
+func (a *App) renderCard(sb *strings.Builder, line func(int, string), cols, body int) func() {
+	c := a.card()
+	if c == nil {
+		return nil
+	}
+
+	textW, image := cols, (func())(nil)
+	if path := c.ImagePath(); a.opts.Images != NoImages && path != "" && cols >= 60 {
+		pcols, prows := a.previewCells(cols, body-1)
+		textW = cols - pcols - 2
+		image = func() {
+			seq, err := a.preview(c, path, pcols, prows)
+			if err != nil {
+				fmt.Fprintf(sb, "\x1b[%d;1H%s", body+2, fit("[WARN] preview: "+err.Error(), cols))
+				return
+			}
+			fmt.Fprintf(sb, "\x1b[2;%dH%s", textW+3, seq)
+		}
+	}
+
+	img := c.ImageName
+	if c.ImagePath() == "" {
+		img += " (not downloaded)"
+	}
+	head := []string{
+		bold + fit(c.Name, textW) + reset,
+		fit(fmt.Sprintf("%s · %s — %s · %s", c.ID, c.Field("Set"), setcode.Name(c.Field("Set")), c.Rarity()), textW),
+		fit("Image: "+img, textW),
+	}
+	if n := len(a.pending[c.ID]); n > 0 {
+		head = append(head, yellow+fit(fmt.Sprintf("%d override(s) on file, applied on the next scrape", n), textW)+reset)
+	}
+	for i, h := range head {
+		line(1+i, h)
+	}
+
+	// Fields, wrapped; scroll so the selected one is fully visible.
+	keys := a.fieldKeys(c)
+	room := body - len(head) - 1
+	keyW := 14
+	valW := max(textW-keyW-2, 10)
+	render := func(k string) []string {
+		v := c.KV[k]
+		out := wrap(v, valW)
+		if nv, ok := a.pendingValue(c, k); ok && nv != v {
+			if nv == "" {
+				out = append(out, yellow+"→ (removed)"+reset)
+			} else {
+				for _, l := range wrap("→ "+nv, valW) {
+					out = append(out, yellow+l+reset)
+				}
+			}
+		}
+		return out
+	}
+	top := &a.top[cardView]
+	cur := a.cur[cardView]
+	*top = min(*top, cur)
+	for {
+		used := 0
+		for i := *top; i <= cur && i < len(keys); i++ {
+			used += len(render(keys[i]))
+		}
+		if used <= room || *top >= cur {
+			break
+		}
+		*top++
+	}
+	row := 1 + len(head) + 1
+	for i := *top; i < len(keys) && row < 1+body; i++ {
+		for j, l := range render(keys[i]) {
+			if row >= 1+body {
+				break
+			}
+			label := ""
+			if j == 0 {
+				label = keys[i]
+			}
+			lbl := fit(label, keyW)
+			if i == cur && j == 0 {
+				lbl = reverse + lbl + reset
+			}
+			line(row, " "+lbl+" "+l)
+			row++
+		}
+	}
+	return image
+}
+
+// previewCells sizes the preview: as tall as the body allows at the card's
+// 5:7 aspect, but no wider than half the screen.
+func (a *App) previewCells(cols, rows int) (int, int) {
+	ph := rows * a.opts.CellH
+	pw := ph * 5 / 7
+	pcols := (pw + a.opts.CellW - 1) / a.opts.CellW
+	if pcols > cols/2 {
+		pcols = cols / 2
+		ph = pcols * a.opts.CellW * 7 / 5
+		rows = (ph + a.opts.CellH - 1) / a.opts.CellH
+	}
+	return pcols, rows
+}
+
+func (a *App) preview(c *catalog.Card, path string, pcols, prows int) (string, error) {
+	key := fmt.Sprintf("%s@%dx%d", c.ID, pcols, prows)
+	if s, ok := a.previews[key]; ok {
+		return s, nil
+	}
+	img, err := loadPreview(path, pcols*a.opts.CellW, prows*a.opts.CellH)
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if a.opts.Images == Kitty {
+		if s, err = kittyImage(img, pcols, prows); err != nil {
+			return "", err
+		}
+	} else {
+		s = sixelImage(img)
+	}
+	a.previews[key] = s
+	return s, nil
+}
+
+// fit truncates or pads s to exactly w columns.
+func fit(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	n := utf8.RuneCountInString(s)
+	if n > w {
+		r := []rune(s)
+		return string(r[:w-1]) + "…"
+	}
+	return s + strings.Repeat(" ", w-n)
+}
+
+// wrap breaks s into lines of at most w runes, on spaces where it can.
+func wrap(s string, w int) []string {
+	var out []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for utf8.RuneCountInString(word) > w {
+				if line != "" {
+					out = append(out, line)
+					line = ""
+				}
+				r := []rune(word)
+				out = append(out, string(r[:w]))
+				word = string(r[w:])
+			}
+			if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > w {
+				out = append(out, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		out = append(out, line)
+	}
+	return out
+}
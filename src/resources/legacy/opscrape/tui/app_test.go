@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"opscrape/catalog"
+	"opscrape/curate"
+)
+
+func TestOverrideNamesOnePrinting(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "dcop")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "manifest.csv"), []byte(`Name,ImageName,PageURL,SetCode,Printing,Rarity,Type
+Flash,Flash-DCOP.jpg,https://cardguide.fandom.com/wiki/Flash_(DCOP),DCOP,Normal,Rare,Character
+Flash,FlashSilver-DCOP.jpg,https://cardguide.fandom.com/wiki/Flash_(DCOP)#silver,DCOP,Silver,Rare,Character
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := catalog.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	silver, _ := cat.Card("Flash_(DCOP)#silver")
+
+	a := New(cat, Options{})
+	a.addOverride(silver, curate.Entry{Fields: map[string]string{"Rarity": "Silver"}})
+	a.prompt.done(" fixed rarity ")
+
+	path := filepath.Join(dir, curate.DefaultFile)
+	entries, err := curate.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Match != "Flash_(DCOP)#silver" || entries[0].Note != "fixed rarity" {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	// Read back, the override is pending on the Silver printing only.
+	b := New(cat, Options{})
+	if len(b.pending["Flash_(DCOP)#silver"]) != 1 || len(b.pending["Flash_(DCOP)"]) != 0 {
+		t.Errorf("pending = %v", b.pending)
+	}
+}
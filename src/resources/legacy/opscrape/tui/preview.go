@@ -0,0 +1,179 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/jpeg" // image.Decode formats
+	"image/png"
+	"os"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // the wiki serves most ".jpg" files as WebP
+)
+
+// ---------- Image protocols ----------
+
+// Protocol is how card images are drawn in the terminal.
+type Protocol int
+
+const (
+	NoImages Protocol = iota
+	Kitty             // kitty graphics protocol: kitty, WezTerm, Ghostty
+	Sixel             // DEC sixel: foot, mlterm, xterm -ti vt340, iTerm2 3.5+
+)
+
+// ParseProtocol reads the -images flag; "auto" asks DetectProtocol.
+func ParseProtocol(s string) (Protocol, error) {
+	switch s {
+	case "auto":
+		return DetectProtocol(), nil
+	case "kitty":
+		return Kitty, nil
+	case "sixel":
+		return Sixel, nil
+	case "none":
+		return NoImages, nil
+	}
+	return NoImages, fmt.Errorf("unknown -images %q; want auto, kitty, sixel or none", s)
+}
+
+// DetectProtocol guesses from the environment; terminals do not advertise
+// graphics support in a way that can be read without a round trip.
+func DetectProtocol() Protocol {
+	termName, prog := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", strings.Contains(termName, "kitty"),
+		prog == "WezTerm", prog == "ghostty", strings.Contains(termName, "ghostty"):
+		return Kitty
+	case strings.Contains(termName, "sixel"), strings.HasPrefix(termName, "foot"),
+		strings.HasPrefix(termName, "mlterm"), prog == "iTerm.app", prog == "mlterm":
+		return Sixel
+	}
+	return NoImages
+}
+
+const kittyDeleteAll = "\x1b_Ga=d,d=A,q=2\x1b\\"
+
+// loadPreview decodes path and scales it to fit pw x ph pixels, keeping the
+// aspect ratio.
+func loadPreview(path string, pw, ph int) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	b := src.Bounds()
+	w, h := pw, pw*b.Dy()/max(b.Dx(), 1)
+	if h > ph {
+		w, h = ph*b.Dx()/max(b.Dy(), 1), ph
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst, nil
+}
+
+// kittyImage transmits img as PNG and displays it over cols x rows cells at
+// the cursor. Payloads are sent in 4096-byte chunks as the protocol requires.
+func kittyImage(img image.Image, cols, rows int) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var sb strings.Builder
+	for first := true; first || data != ""; first = false {
+		chunk := data[:min(4096, len(data))]
+		data = data[len(chunk):]
+		more := 0
+		if data != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return sb.String(), nil
+}
+
+// sixelImage encodes img as DEC sixel with a 6x6x6 colour cube, which is
+// plenty for a thumbnail and keeps every colour register fixed.
+func sixelImage(img image.Image) string {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	idx := make([]uint8, w*h)
+	var used [216]bool
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			c := uint8(cube(r)*36 + cube(g)*6 + cube(bl))
+			idx[y*w+x] = c
+			used[c] = true
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\x1bPq\"1;1;%d;%d", w, h)
+	for c, ok := range used {
+		if ok {
+			fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", c, c/36*20, c/6%6*20, c%6*20)
+		}
+	}
+	for y0 := 0; y0 < h; y0 += 6 {
+		var band [216]bool
+		for y := y0; y < min(y0+6, h); y++ {
+			for x := 0; x < w; x++ {
+				band[idx[y*w+x]] = true
+			}
+		}
+		first := true
+		for c, ok := range band {
+			if !ok {
+				continue
+			}
+			if !first {
+				sb.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&sb, "#%d", c)
+			run, prev := 0, byte(0)
+			flush := func() {
+				switch {
+				case run > 3:
+					fmt.Fprintf(&sb, "!%d%c", run, prev)
+				case run > 0:
+					sb.WriteString(strings.Repeat(string(prev), run))
+				}
+			}
+			for x := 0; x < w; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && y0+dy < h; dy++ {
+					if idx[(y0+dy)*w+x] == uint8(c) {
+						bits |= 1 << dy
+					}
+				}
+				ch := 63 + bits
+				if ch != prev {
+					flush()
+					run, prev = 0, ch
+				}
+				run++
+			}
+			flush()
+		}
+		sb.WriteByte('-')
+	}
+	sb.WriteString("\x1b\\")
+	return sb.String()
+}
+
+// cube maps a 16-bit channel to one of six levels.
+func cube(v uint32) int { return int((v>>8)*5+127) / 255 }
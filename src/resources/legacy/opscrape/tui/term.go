@@ -0,0 +1,151 @@
+// Package tui is a full-screen terminal browser for the catalog: sets,
+// filtered card lists, every field of a card with an image preview, and
+// curation edits written as scraper overrides.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"opscrape/catalog"
+)
+
+// ---------- Keys ----------
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPgUp
+	keyPgDn
+	keyHome
+	keyEnd
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyTab
+	keyCtrlC
+	keyCtrlU
+	keyUnknown
+)
+
+type key struct {
+	code keyCode
+	r    rune // for keyRune
+}
+
+var escKeys = map[string]keyCode{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[5~": keyPgUp, "[6~": keyPgDn,
+	"[H": keyHome, "[1~": keyHome, "OH": keyHome,
+	"[F": keyEnd, "[4~": keyEnd, "OF": keyEnd,
+}
+
+// parseKeys splits one read from a raw-mode terminal into keys. A lone ESC
+// is the Escape key; ESC followed by a known sequence is a cursor key.
+func parseKeys(b []byte) []key {
+	var out []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 || b[1] == 0x1b {
+				out = append(out, key{code: keyEsc})
+				b = b[1:]
+				continue
+			}
+			n := 2 // ESC and '[' or 'O', then up to the final byte
+			for n < len(b) && n < 8 {
+				c := b[n]
+				n++
+				if c >= 'A' && c <= 'Z' || c == '~' {
+					break
+				}
+			}
+			code, ok := escKeys[string(b[1:n])]
+			if !ok {
+				code = keyUnknown
+			}
+			out = append(out, key{code: code})
+			b = b[n:]
+		case c == '\r' || c == '\n':
+			out = append(out, key{code: keyEnter})
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			out = append(out, key{code: keyBackspace})
+			b = b[1:]
+		case c == '\t':
+			out = append(out, key{code: keyTab})
+			b = b[1:]
+		case c == 0x03:
+			out = append(out, key{code: keyCtrlC})
+			b = b[1:]
+		case c == 0x15:
+			out = append(out, key{code: keyCtrlU})
+			b = b[1:]
+		case c < 0x20:
+			out = append(out, key{code: keyUnknown})
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			out = append(out, key{code: keyRune, r: r})
+			b = b[size:]
+		}
+	}
+	return out
+}
+
+// ---------- Terminal loop ----------
+
+// Run takes over the terminal until the user quits. Stdin and stdout must
+// be a terminal.
+func Run(cat *catalog.Catalog, opts Options) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return fmt.Errorf("tui needs an interactive terminal")
+	}
+	old, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, old)
+
+	w := bufio.NewWriterSize(os.Stdout, 1<<16)
+	fmt.Fprint(w, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer func() {
+		if opts.Images == Kitty {
+			fmt.Fprint(w, kittyDeleteAll)
+		}
+		fmt.Fprint(w, "\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+
+	a := New(cat, opts)
+	buf := make([]byte, 256)
+	for !a.quit {
+		cols, rows, err := term.GetSize(out)
+		if err != nil || cols < 20 || rows < 6 {
+			cols, rows = 80, 24
+		}
+		a.render(w, cols, rows)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			a.handle(k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []key
+	}{
+		{"runes", "aé", []key{{code: keyRune, r: 'a'}, {code: keyRune, r: 'é'}}},
+		{"cursor CSI", "\x1b[A\x1b[B\x1b[C\x1b[D", []key{{code: keyUp}, {code: keyDown}, {code: keyRight}, {code: keyLeft}}},
+		{"cursor SS3", "\x1bOA\x1bOD", []key{{code: keyUp}, {code: keyLeft}}},
+		{"paging", "\x1b[5~\x1b[6~", []key{{code: keyPgUp}, {code: keyPgDn}}},
+		{"home and end", "\x1b[H\x1b[1~\x1bOH\x1b[F\x1b[4~\x1bOF", []key{
+			{code: keyHome}, {code: keyHome}, {code: keyHome}, {code: keyEnd}, {code: keyEnd}, {code: keyEnd},
+		}},
+		{"lone escape", "\x1b", []key{{code: keyEsc}}},
+		{"escape then cursor", "\x1b\x1b[A", []key{{code: keyEsc}, {code: keyUp}}},
+		{"unknown sequence", "\x1b[15~x", []key{{code: keyUnknown}, {code: keyRune, r: 'x'}}},
+		{"controls", "\r\n\x7f\x08\t\x03\x15\x01", []key{
+			{code: keyEnter}, {code: keyEnter}, {code: keyBackspace}, {code: keyBackspace},
+			{code: keyTab}, {code: keyCtrlC}, {code: keyCtrlU}, {code: keyUnknown},
+		}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeys([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeys(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
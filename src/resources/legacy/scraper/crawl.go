@@ -0,0 +1,213 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ---------- Crawl ----------
+
+// CrawlRules bound the crawl that collects card pages. Depth 0 reads the
+// index page and its "next page" listings only, which is what the scraper
+// always did; each extra level follows card links, sub-index pages and
+// category links found one level up.
+type CrawlRules struct {
+	Depth    int
+	MaxPages int            // pages fetched while crawling, listings included
+	Include  *regexp.Regexp // nil allows every link
+	Exclude  *regexp.Regexp // nil excludes none
+}
+
+var crawl = CrawlRules{MaxPages: 500}
+
+// allows applies -include/-exclude to a resolved link.
+func (c CrawlRules) allows(u string) bool {
+	if c.Include != nil && !c.Include.MatchString(u) {
+		return false
+	}
+	return c.Exclude == nil || !c.Exclude.MatchString(u)
+}
+
+// collectCardPages crawls breadth-first from index and returns the card
+// pages it links to, sorted and deduplicated on canonical URL. Pagination
+// links ("pagefrom=") stay at the depth of the listing they continue.
+func collectCardPages(index string) ([]string, error) {
+	type visit struct {
+		url   string
+		depth int
+	}
+	start, err := url.Parse(index)
+	if err != nil {
+		return nil, err
+	}
+	queue := []visit{{index, 0}}
+	queued := map[string]bool{canonicalURL(start): true}
+	fetched := map[string]bool{} // by where the page really lives
+	alias := map[string]string{} // link -> canonical, for pages fetched here
+	cards := map[string]bool{}   // links that passed the card rules
+	enqueue := func(u string, depth int) {
+		if !queued[u] {
+			queued[u] = true
+			queue = append(queue, visit{u, depth})
+		}
+	}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if len(fetched) >= crawl.MaxPages {
+			logger.Warn("crawl stopped at -max-pages", "max_pages", crawl.MaxPages, "not_visited", len(queue)+1)
+			break
+		}
+		doc, canonical, err := fetchPage(v.url)
+		if err != nil {
+			if v.url == index {
+				return nil, err
+			}
+			logger.Warn("crawl", "url", v.url, "err", err)
+			continue
+		}
+		alias[v.url] = canonical
+		if fetched[canonical] {
+			continue
+		}
+		fetched[canonical] = true
+		base, _ := url.Parse(canonical)
+
+		if profile.Links.Next != "" {
+			doc.Find(profile.Links.Next).Each(func(_ int, s *goquery.Selection) {
+				href, _ := s.Attr("href")
+				if u, ok := resolveLink(base, start, href); ok {
+					enqueue(u, v.depth)
+				}
+			})
+		}
+		doc.Find(profile.Links.Selector).Each(func(_ int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			u, ok := resolveLink(base, start, href)
+			if !ok || !crawl.allows(u) {
+				return
+			}
+			switch {
+			case profile.follows(href):
+			case profile.wantLink(href, s.Text()):
+				cards[u] = true
+			default:
+				return
+			}
+			if v.depth < crawl.Depth {
+				enqueue(u, v.depth+1)
+			}
+		})
+	}
+
+	set := map[string]bool{}
+	for u := range cards {
+		if c, ok := alias[u]; ok {
+			u = c
+		}
+		set[u] = true
+	}
+	out := make([]string, 0, len(set))
+	for u := range set {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	if len(fetched) > 1 {
+		logger.Info("crawled", "pages", len(fetched))
+	}
+	return out, nil
+}
+
+// resolveLink resolves href against base and keeps it only if it stays on
+// the start page's host.
+func resolveLink(base, start *url.URL, href string) (string, bool) {
+	if href == "" {
+		return "", false
+	}
+	u, err := base.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, start.Host) {
+		return "", false
+	}
+	return canonicalURL(u), true
+}
+
+// keepEscaped are the characters wiki URLs carry literally; re-escaping
+// them would make "Batman_(DCOP)" and "Batman_%28DCOP%29" two pages.
+var keepEscaped = strings.NewReplacer("%28", "(", "%29", ")", "%27", "'", "%21", "!", "%2C", ",", "%3A", ":")
+
+// canonicalURL spells a wiki URL one way: no fragment, lower-case host,
+// spaces as underscores and only the escapes a URL needs.
+func canonicalURL(u *url.URL) string {
+	c := *u
+	c.Fragment, c.RawFragment = "", ""
+	c.Host = strings.ToLower(c.Host)
+	c.Path = strings.ReplaceAll(c.Path, " ", "_")
+	c.RawPath = ""
+	c.RawPath = keepEscaped.Replace(c.EscapedPath())
+	return c.String()
+}
+
+// fetchPage fetches and parses a page and says where it lives: its
+// <link rel="canonical">, else the URL redirects ended at. MediaWiki points
+// paginated listings' canonical link at the first page, so a canonical
+// link is ignored when the fetched URL has a query string.
+func fetchPage(raw string) (*goquery.Document, string, error) {
+	var doc *goquery.Document
+	final, _ := url.Parse(raw)
+	err := retry.Do(context.Background(), "page "+raw, func() error {
+		resp, err := httpGetWithUA(context.Background(), raw)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return newStatusError(raw, resp)
+		}
+		if doc, err = goquery.NewDocumentFromReader(bufio.NewReader(resp.Body)); err != nil {
+			return err
+		}
+		if resp.Request != nil && resp.Request.URL != nil {
+			final = resp.Request.URL
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if href, ok := doc.Find(`link[rel="canonical"]`).First().Attr("href"); ok && final.RawQuery == "" {
+		if c, err := final.Parse(href); err == nil {
+			return doc, canonicalURL(c), nil
+		}
+	}
+	return doc, canonicalURL(final), nil
+}
+
+// pageSet remembers which canonical pages the workers have scraped, so two
+// links to one page (a redirect, an old title) give one set of records.
+type pageSet struct {
+	mu   sync.Mutex
+	seen map[string]string // canonical -> link first scraped
+}
+
+// claim reports the link that already scraped canonical, or "" if link is
+// the first.
+func (s *pageSet) claim(canonical, link string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = map[string]string{}
+	}
+	if first, ok := s.seen[canonical]; ok {
+		return first
+	}
+	s.seen[canonical] = link
+	return ""
+}
@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// crawlSite serves a paginated category with a sub-category, a redirect and
+// a card reachable under two titles.
+func crawlSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	card := func(name string) string {
+		return `<h1 id="firstHeading">` + name + `</h1><div id="mw-content-text">
+<table><tr><th>Statistics</th><th>` + name + `</th></tr><tr><th>Type</th><td>Character</td></tr></table></div>`
+	}
+	pages := map[string]string{
+		"/wiki/Category:X-Men_cards": `<link rel="canonical" href="/wiki/Category:X-Men_cards">
+<div id="mw-content-text"><a href="/wiki/Angel_(XMOP)">Angel</a>
+<a href="/wiki/Acolytes_-_Rusty_Collins_(XMOP)">Acolytes - Rusty Collins</a>
+<a href="/wiki/Category:X-Men_cards?pagefrom=R">next page</a></div>`,
+		"/wiki/Category:X-Men_cards?pagefrom=R": `<link rel="canonical" href="/wiki/Category:X-Men_cards">
+<div id="mw-content-text"><a href="/wiki/Rusty_Collins_(XMOP)">Rusty Collins</a>
+<a href="/wiki/Storm_(CLOP)">Storm</a>
+<a href="/wiki/Category:Acolytes_(XMOP)">Acolytes</a></div>`,
+		"/wiki/Category:Acolytes_(XMOP)": `<div id="mw-content-text"><a href="/wiki/Acolytes_-_Scanner_(XMOP)">Scanner</a></div>`,
+		"/wiki/Angel_(XMOP)":             card("Angel"),
+		"/wiki/Acolytes_-_Rusty_Collins_(XMOP)": `<link rel="canonical" href="/wiki/Acolytes_-_Rusty_Collins_(XMOP)">` +
+			card("Acolytes - Rusty Collins"),
+		"/wiki/Acolytes_-_Scanner_(XMOP)": card("Acolytes - Scanner"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/wiki/Rusty_Collins_(XMOP)" {
+			http.Redirect(w, r, "/wiki/Acolytes_-_Rusty_Collins_(XMOP)", http.StatusMovedPermanently)
+			return
+		}
+		body, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	useReplay(t, filepath.Join("testdata", "site.har"))
+	oldCrawl, oldProfile := crawl, profile
+	t.Cleanup(func() { crawl, profile = oldCrawl, oldProfile })
+	fetcher = srv.Client()
+	p := *profile
+	p.Links.SetTags = []string{"XMOP"}
+	profile = &p
+	return srv
+}
+
+func TestCrawlFollowsPagination(t *testing.T) {
+	srv := crawlSite(t)
+	crawl = CrawlRules{MaxPages: 50}
+
+	pages, err := collectCardPages(srv.URL + "/wiki/Category:X-Men_cards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.ReplaceAll(strings.Join(pages, " "), srv.URL, "")
+	if want := "/wiki/Acolytes_-_Rusty_Collins_(XMOP) /wiki/Angel_(XMOP) /wiki/Rusty_Collins_(XMOP)"; got != want {
+		t.Fatalf("depth 0 pages = %s, want %s", got, want)
+	}
+
+	// The redirect is only found when scraping; it must not give a second card.
+	recs := scrapeAndDownloadAll(pages, &Overrides{})
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2: %+v", len(recs), recs)
+	}
+	for _, r := range recs {
+		if r.Error != nil || strings.Contains(r.PageURL, "/Rusty_Collins") {
+			t.Errorf("rec = %+v", r)
+		}
+	}
+}
+
+func TestCrawlDepthAndFilters(t *testing.T) {
+	srv := crawlSite(t)
+	crawl = CrawlRules{Depth: 1, MaxPages: 50, Exclude: regexp.MustCompile(`Angel`)}
+
+	pages, err := collectCardPages(srv.URL + "/wiki/Category:X-Men_cards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.ReplaceAll(strings.Join(pages, " "), srv.URL, "")
+	if want := "/wiki/Acolytes_-_Rusty_Collins_(XMOP) /wiki/Acolytes_-_Scanner_(XMOP)"; got != want {
+		t.Fatalf("depth 1 pages = %s, want %s", got, want)
+	}
+
+	crawl = CrawlRules{Depth: 1, MaxPages: 1}
+	if pages, _ := collectCardPages(srv.URL + "/wiki/Category:X-Men_cards"); len(pages) != 2 {
+		t.Errorf("-max-pages 1 pages = %v", pages)
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	base, err := url.Parse("https://CardGuide.fandom.com/wiki/X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, href := range []string{
+		"/wiki/Batman_(DCOP)",
+		"/wiki/Batman_%28DCOP%29",
+		"/wiki/Batman (DCOP)#Trivia",
+	} {
+		if got, ok := resolveLink(base, base, href); !ok || got != "https://cardguide.fandom.com/wiki/Batman_(DCOP)" {
+			t.Errorf("resolveLink(%q) = %q, %v", href, got, ok)
+		}
+	}
+	if _, ok := resolveLink(base, base, "https://example.org/wiki/Batman_(DCOP)"); ok {
+		t.Error("off-site link kept")
+	}
+}
@@ -0,0 +1,297 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif" // image.DecodeConfig formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ---------- Download ----------
+
+// downloading serialises downloads of one destination, which several
+// records (split overrides, shared artwork) can name.
+var downloading sync.Map // dest path -> *sync.Mutex
+
+// downloadImage fetches srcURL to destPath through destPath+".part", which
+// survives failures so the next attempt, or the next run, resumes it with a
+// Range request. A file only takes its final name once it has its full
+// Content-Length, decodes as the format its extension names, and matches
+// the wiki's SHA1 when the imageinfo API knows the file. An existing file is
+// kept if it passes the local checks and fetched again otherwise. Attempts
+// follow the shared retry policy.
+func downloadImage(srcURL, destPath string) error {
+	mu, _ := downloading.LoadOrStore(destPath, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	var want imageInfo
+	looked := false
+	if _, err := os.Stat(destPath); err == nil {
+		err := verifyImage(destPath, destPath, imageInfo{})
+		if err == nil {
+			return nil
+		}
+		// The local checks can be wrong, e.g. about a JPEG with bytes after
+		// its end marker; the wiki's SHA1 overrules them.
+		want, looked = lookupImageInfo(srcURL), true
+		if want.SHA1 != "" && verifyImage(destPath, destPath, want) == nil {
+			return nil
+		}
+		logger.Warn("bad image; downloading again", "file", destPath, "err", err)
+		if err := os.Remove(destPath); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return permanentError{err}
+	}
+
+	part := destPath + ".part"
+	if !looked {
+		want = lookupImageInfo(srcURL)
+	}
+	err := retry.Do(context.Background(), "image "+srcURL, func() error {
+		if err := fetchToPart(srcURL, part); err != nil {
+			return err
+		}
+		if err := verifyImage(part, destPath, want); err != nil {
+			// Whole but wrong: resuming it would not help.
+			_ = os.Remove(part)
+			return contentError{err}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, retryable := classifyError(err); !retryable {
+			_ = os.Remove(part)
+		}
+		return err
+	}
+	return os.Rename(part, destPath)
+}
+
+// fetchToPart appends the rest of srcURL to part, starting over when the
+// server ignores the Range header. A short body is an error that leaves the
+// part in place for the next attempt.
+func fetchToPart(srcURL, part string) error {
+	var have int64
+	if fi, err := os.Stat(part); err == nil {
+		have = fi.Size()
+	}
+	req, err := newGetRequest(context.Background(), srcURL)
+	if err != nil {
+		return permanentError{err}
+	}
+	// The image CDN converts to WebP when asked for image/*; ask for the
+	// uploaded file so it matches its extension and the wiki's SHA1.
+	req.Header.Set("Accept", "image/jpeg,image/png,image/gif,*/*;q=0.5")
+	if have > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+	}
+	resp, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && have > 0:
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != have {
+			_ = os.Remove(part)
+			return contentError{fmt.Errorf("Content-Range %q does not resume at byte %d", resp.Header.Get("Content-Range"), have)}
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && have > 0:
+		// Nothing past the end: the part is complete, or stale, which the
+		// verification that follows finds out.
+		return nil
+	default:
+		return newStatusError(srcURL, resp)
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return permanentError{err}
+	}
+	bw := bufio.NewWriterSize(f, 1<<20)
+	n, copyErr := io.Copy(bw, resp.Body)
+	flushErr := bw.Flush()
+	closeErr := f.Close()
+	for _, err := range []error{copyErr, flushErr, closeErr} {
+		if err != nil {
+			return err
+		}
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return contentError{fmt.Errorf("short body: %d of %d bytes", n, resp.ContentLength)}
+	}
+	return nil
+}
+
+// contentRangeStart reads the first byte position of "bytes 100-199/200".
+func contentRangeStart(h string) int64 {
+	h = strings.TrimPrefix(h, "bytes ")
+	start, _, ok := strings.Cut(h, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// ---------- Verification ----------
+
+// extFormats maps an extension to the format image.DecodeConfig reports.
+var extFormats = map[string]string{
+	".jpg": "jpeg", ".jpeg": "jpeg", ".png": "png", ".gif": "gif", ".webp": "webp",
+}
+
+// verifyImage checks file, which will be called name: it must decode as the
+// format name's extension says, and then match want's SHA1 when the wiki
+// knows it. Without a SHA1 the file must not stop short of the format's end
+// marker and must have want's size where that is known. A matching SHA1 is
+// trusted over the end marker: some uploads carry bytes after it.
+func verifyImage(file, name string, want imageInfo) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("empty file")
+	}
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("not an image (%s): %w", http.DetectContentType(data), err)
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if f, ok := extFormats[ext]; ok && f != format {
+		return fmt.Errorf("%s data in a %s file", format, ext)
+	}
+	if want.SHA1 != "" {
+		sum := sha1.Sum(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want.SHA1) {
+			return fmt.Errorf("sha1 %s, wiki says %s", got, want.SHA1)
+		}
+		return nil
+	}
+	if !hasEndMarker(format, data) {
+		return fmt.Errorf("truncated %s (%d bytes)", format, len(data))
+	}
+	if want.Size > 0 && int64(len(data)) != want.Size {
+		return fmt.Errorf("%d bytes, wiki says %d", len(data), want.Size)
+	}
+	return nil
+}
+
+// hasEndMarker catches files cut short, which still decode a header.
+func hasEndMarker(format string, data []byte) bool {
+	switch format {
+	case "jpeg":
+		return bytes.HasSuffix(bytes.TrimRight(data, "\x00"), []byte{0xff, 0xd9})
+	case "png":
+		return bytes.HasSuffix(data, []byte("IEND\xae\x42\x60\x82"))
+	case "gif":
+		return bytes.HasSuffix(data, []byte{0x3b})
+	case "webp":
+		return len(data) >= 12 && int(binary.LittleEndian.Uint32(data[4:8])) == len(data)-8
+	}
+	return true
+}
+
+// ---------- MediaWiki imageinfo ----------
+
+// imageInfo is what the wiki records about an uploaded file; zero fields
+// are unknown and not checked.
+type imageInfo struct {
+	Size int64  `json:"size"`
+	SHA1 string `json:"sha1"`
+}
+
+// imageInfoAPI is the wiki's api.php, from the profile's api path and -url;
+// "" skips the SHA1 check.
+var imageInfoAPI string
+
+// lookupImageInfo asks the wiki for the file's size and SHA1. Any failure
+// just means the download is checked locally only.
+func lookupImageInfo(srcURL string) imageInfo {
+	title := fileTitle(srcURL)
+	if imageInfoAPI == "" || title == "" {
+		return imageInfo{}
+	}
+	q := url.Values{
+		"action": {"query"}, "prop": {"imageinfo"}, "iiprop": {"size|sha1"},
+		"titles": {"File:" + title}, "format": {"json"},
+	}
+	resp, err := httpGetWithUA(context.Background(), imageInfoAPI+"?"+q.Encode())
+	if err != nil {
+		return imageInfo{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return imageInfo{}
+	}
+	var r struct {
+		Query struct {
+			Pages map[string]struct {
+				ImageInfo []imageInfo `json:"imageinfo"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return imageInfo{}
+	}
+	for _, p := range r.Query.Pages {
+		if len(p.ImageInfo) > 0 {
+			return p.ImageInfo[0]
+		}
+	}
+	return imageInfo{}
+}
+
+// fileTitle returns the wiki file name of an original-size image URL, e.g.
+// ".../images/1/1a/Batman-DCOP.jpg/revision/latest?cb=..." -> "Batman-DCOP.jpg".
+// Scaled renditions have no SHA1 on the wiki and give "".
+func fileTitle(srcURL string) string {
+	u, err := url.Parse(srcURL)
+	if err != nil {
+		return ""
+	}
+	p := u.Path
+	if i := strings.Index(p, "/revision/"); i >= 0 {
+		if rest := p[i+len("/revision/"):]; strings.Contains(rest, "/") {
+			return ""
+		}
+		p = p[:i]
+	}
+	name := path.Base(p)
+	if _, ok := extFormats[strings.ToLower(filepath.Ext(name))]; !ok {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{0, 0, 255, 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// imageSite serves /card.png with Range support, /error.jpg as an HTML page
+// and /api.php answering imageinfo with sha1.
+func imageSite(t *testing.T, img []byte, sha string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/card.png":
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mu.Unlock()
+			http.ServeContent(w, r, "card.png", time.Time{}, bytes.NewReader(img))
+		case "/images/error.jpg":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body>Something went wrong</body></html>")
+		case "/api.php":
+			if !strings.HasPrefix(r.URL.Query().Get("titles"), "File:") {
+				t.Errorf("imageinfo titles = %q", r.URL.Query().Get("titles"))
+			}
+			fmt.Fprintf(w, `{"query":{"pages":{"7":{"imageinfo":[{"size":%d,"sha1":%q}]}}}}`, len(img), sha)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	oldFetcher, oldAPI, oldRetry := fetcher, imageInfoAPI, retry
+	t.Cleanup(func() { fetcher, imageInfoAPI, retry = oldFetcher, oldAPI, oldRetry })
+	fetcher, imageInfoAPI = srv.Client(), srv.URL+"/api.php"
+	retry = RetryPolicy{Attempts: 3, Base: time.Millisecond, Max: time.Millisecond}
+	return srv, &ranges
+}
+
+func sha1Hex(b []byte) string {
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadResumesPart(t *testing.T) {
+	img := testPNG(t)
+	srv, ranges := imageSite(t, img, sha1Hex(img))
+	dest := filepath.Join(t.TempDir(), "card.png")
+	os.WriteFile(dest+".part", img[:10], 0o644)
+
+	if err := downloadImage(srv.URL+"/images/card.png", dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, img) {
+		t.Errorf("file = %x, want %x", got, img)
+	}
+	if len(*ranges) != 1 || (*ranges)[0] != "bytes=10-" {
+		t.Errorf("Range headers = %q", *ranges)
+	}
+	if _, err := os.Stat(dest + ".part"); err == nil {
+		t.Error(".part left behind")
+	}
+}
+
+func TestDownloadRejectsBadContent(t *testing.T) {
+	img := testPNG(t)
+	srv, _ := imageSite(t, img, strings.Repeat("0", 40))
+	dir := t.TempDir()
+
+	err := downloadImage(srv.URL+"/images/error.jpg", filepath.Join(dir, "error.jpg"))
+	if err == nil || !strings.Contains(err.Error(), "not an image (text/html") {
+		t.Errorf("HTML page: err = %v", err)
+	}
+	err = downloadImage(srv.URL+"/images/card.png", filepath.Join(dir, "card.png"))
+	if err == nil || !strings.Contains(err.Error(), "wiki says 0000") {
+		t.Errorf("SHA1 mismatch: err = %v", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("bad downloads kept: %v", files)
+	}
+
+	// PNG bytes under a .jpg name, and a PNG cut short.
+	os.WriteFile(filepath.Join(dir, "x.jpg"), img, 0o644)
+	if err := verifyImage(filepath.Join(dir, "x.jpg"), "x.jpg", imageInfo{}); err == nil || err.Error() != "png data in a .jpg file" {
+		t.Errorf("format mismatch: err = %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "y.png"), img[:len(img)-4], 0o644)
+	if err := verifyImage(filepath.Join(dir, "y.png"), "y.png", imageInfo{}); err == nil || !strings.Contains(err.Error(), "truncated png") {
+		t.Errorf("truncated: err = %v", err)
+	}
+}
+
+func TestVerifyRepairsImages(t *testing.T) {
+	img := testPNG(t)
+	srv, _ := imageSite(t, img, sha1Hex(img))
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.csv")
+	images := filepath.Join(dir, "images")
+	os.MkdirAll(images, 0o755)
+	os.WriteFile(manifest, []byte("Name,ImageName,ImageURL,PageURL\n"+
+		"Card,card.png,"+srv.URL+"/images/card.png,"+srv.URL+"/wiki/Card\n"), 0o644)
+	os.WriteFile(filepath.Join(images, "card.png"), []byte("<html>oops</html>"), 0o644)
+	os.WriteFile(filepath.Join(images, "stray.jpg"), []byte("<html>oops</html>"), 0o644)
+
+	err := runVerify(manifest, images)
+	if err == nil || err.Error() != "1 images are still bad" {
+		t.Errorf("err = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(images, "card.png")); !bytes.Equal(got, img) {
+		t.Errorf("card.png not repaired: %q", got)
+	}
+}
+
+func TestDownloadTrustsWikiSHA1(t *testing.T) {
+	// Bytes after the end marker fail the local check, but the wiki's SHA1
+	// says this is the uploaded file.
+	img := append(testPNG(t), "trailer"...)
+	srv, ranges := imageSite(t, img, sha1Hex(img))
+	dir := t.TempDir()
+	images := filepath.Join(dir, "images")
+	dest := filepath.Join(images, "card.png")
+
+	if err := downloadImage(srv.URL+"/images/card.png", dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := downloadImage(srv.URL+"/images/card.png", dest); err != nil {
+		t.Fatal(err)
+	}
+	if len(*ranges) != 1 {
+		t.Errorf("downloaded %d times; a file matching the wiki's SHA1 is complete", len(*ranges))
+	}
+	manifest := filepath.Join(dir, "manifest.csv")
+	os.WriteFile(manifest, []byte("Name,ImageName,ImageURL,PageURL\nCard,card.png,"+srv.URL+"/images/card.png,\n"), 0o644)
+	if err := runVerify(manifest, images); err != nil || len(*ranges) != 1 {
+		t.Errorf("verify: %v after %d downloads", err, len(*ranges))
+	}
+}
+
+func TestDownloadReturnsDiskErrors(t *testing.T) {
+	img := testPNG(t)
+	srv, _ := imageSite(t, img, sha1Hex(img))
+	notDir := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(notDir, nil, 0o644)
+
+	err := downloadImage(srv.URL+"/images/card.png", filepath.Join(notDir, "card.png"))
+	if err == nil {
+		t.Fatal("no error for an image directory that cannot be created")
+	}
+	if _, retryable := classifyError(err); retryable {
+		t.Errorf("disk error %v is retryable", err)
+	}
+}
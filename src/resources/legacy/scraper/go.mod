@@ -0,0 +1,12 @@
+module scraper
+
+go 1.25.3
+
+require (
+	github.com/PuerkitoBio/goquery v1.10.3
+	github.com/andybalholm/cascadia v1.3.3
+	golang.org/x/image v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/net v0.39.0 // indirect
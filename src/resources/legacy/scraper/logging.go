@@ -0,0 +1,189 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- Logging ----------
+
+// logger is where every goroutine logs; one record is one line, so
+// concurrent workers no longer interleave. main replaces it from
+// -log-format and -log-level.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+var (
+	logFormat   string // text or json
+	logLevel    string
+	progressArg string // auto, on or off
+)
+
+// setupLogging builds logger, and the progress bar when there is a
+// terminal for it; the caller stops the bar.
+func setupLogging() (*Progress, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return nil, fmt.Errorf("-log-level: %w", err)
+	}
+	var bar *Progress
+	switch progressArg {
+	case "on":
+		bar = newProgress(os.Stderr)
+	case "auto":
+		if isTerminal(os.Stderr) {
+			bar = newProgress(os.Stderr)
+		}
+	case "off":
+	default:
+		return nil, fmt.Errorf("-progress %q: want auto, on or off", progressArg)
+	}
+	var out io.Writer = os.Stdout
+	if bar != nil {
+		out = bar.Writer(os.Stdout)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch logFormat {
+	case "text":
+		logger = slog.New(slog.NewTextHandler(out, opts))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(out, opts))
+	default:
+		return nil, fmt.Errorf("-log-format %q: want text or json", logFormat)
+	}
+	return bar, nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ---------- Progress ----------
+
+// Progress redraws one status line on a terminal from stats: pages, images,
+// bytes, error rate and ETA. Log lines written through Writer clear the bar
+// first and redraw it after, so the two never share a line.
+type Progress struct {
+	mu    sync.Mutex
+	tty   io.Writer
+	shown bool
+	off   bool // stopped
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newProgress(tty io.Writer) *Progress {
+	p := &Progress{tty: tty, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		t := time.NewTicker(250 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-t.C:
+				p.mu.Lock()
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+	return p
+}
+
+// Stop removes the bar for good; later calls do nothing.
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+	p.once.Do(func() {
+		close(p.stop)
+		<-p.done
+		p.mu.Lock()
+		p.clear()
+		p.off = true
+		p.mu.Unlock()
+	})
+}
+
+func (p *Progress) Writer(w io.Writer) io.Writer { return progressWriter{p, w} }
+
+type progressWriter struct {
+	p *Progress
+	w io.Writer
+}
+
+func (pw progressWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.p.clear()
+	n, err := pw.w.Write(b)
+	pw.p.draw()
+	return n, err
+}
+
+func (p *Progress) clear() {
+	if p.shown {
+		fmt.Fprint(p.tty, "\r\x1b[K")
+		p.shown = false
+	}
+}
+
+func (p *Progress) draw() {
+	line := stats.progressLine()
+	if p.off || line == "" {
+		return
+	}
+	fmt.Fprint(p.tty, "\r\x1b[K"+line)
+	p.shown = true
+}
+
+// progressLine is the bar's text; "" until there are pages to scrape.
+func (s *RunStats) progressLine() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pages == 0 {
+		return ""
+	}
+	const width = 20
+	filled := min(width*s.fetched/s.pages, width)
+	done, failed := 0, 0
+	for r, n := range s.results {
+		done += n
+		if r.Failed() {
+			failed += n
+		}
+	}
+	rate := 0.0
+	if done > 0 {
+		rate = 100 * float64(failed) / float64(done)
+	}
+	eta := "?"
+	if s.fetched > 0 {
+		elapsed := time.Since(s.started)
+		left := time.Duration(float64(elapsed) / float64(s.fetched) * float64(s.pages-s.fetched))
+		eta = left.Round(time.Second).String()
+	}
+	return fmt.Sprintf("[%s%s] pages %d/%d  images %d  %s  errors %.1f%%  ETA %s",
+		strings.Repeat("#", filled), strings.Repeat("-", width-filled),
+		s.fetched, s.pages, s.images, formatBytes(s.bytes), rate, eta)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,311 @@
+package scraper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"scraper/setcode"
+)
+
+// ---------- Overrides ----------
+
+// Override is one hand correction that survives re-scraping. Overrides live
+// in a YAML (or .json) file passed with -overrides:
+//
+//	# overrides.yaml
+//	- match: Avenger's_ID_Card_(MNOP)
+//	  note: wiki shows the Avengers Mansion art
+//	  image: https://static.wikia.nocookie.net/cardguide/images/a/ab/AvengersIDCard-MNOP.jpg
+//	- match: https://cardguide.fandom.com/wiki/Gambit_(XMOP)
+//	  fields: {Intellect: "3", Trivia: ""}   # "" removes the row
+//	- match: Card_List_(DCOP)
+//	  suppress: true
+//	- match: Batman_(DCOP)_(var)
+//	  merge: Batman_(DCOP)                   # fill gaps in Batman_(DCOP), drop this one
+//	- match: Twin_Card_(IQOP)
+//	  split:                                 # one page, two cards
+//	    - {name: Twin Card A, fields: {Type: Event}}
+//	    - {name: Twin Card B, fields: {Type: Event}, image: https://...}
+//
+// Match is a PageURL or a card ID. Each printing of a multi-printing page
+// has its own, e.g. Flash_(DCOP) and Flash_(DCOP)#silver, and an override
+// only applies to the one it names.
+type Override struct {
+	Match    string            `yaml:"match" json:"match"`
+	Note     string            `yaml:"note,omitempty" json:"note,omitempty"`
+	Name     string            `yaml:"name,omitempty" json:"name,omitempty"`
+	Fields   map[string]string `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Image    string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Suppress bool              `yaml:"suppress,omitempty" json:"suppress,omitempty"`
+	Merge    string            `yaml:"merge,omitempty" json:"merge,omitempty"`
+	Split    []Override        `yaml:"split,omitempty" json:"split,omitempty"`
+}
+
+// Overrides applies a loaded overrides file and keeps the audit trail.
+// Workers call Apply concurrently.
+type Overrides struct {
+	list []Override
+
+	mu    sync.Mutex
+	hits  []int
+	audit []string
+}
+
+// loadOverrides reads path; an empty path gives an Overrides that changes nothing.
+func loadOverrides(path string) (*Overrides, error) {
+	ov := &Overrides{}
+	if path == "" {
+		return ov, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &ov.list)
+	} else {
+		err = yaml.Unmarshal(data, &ov.list)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, o := range ov.list {
+		if strings.TrimSpace(o.Match) == "" {
+			return nil, fmt.Errorf("%s: override #%d has no match", path, i+1)
+		}
+		if o.Merge != "" && len(o.Split) > 0 {
+			return nil, fmt.Errorf("%s: override #%d (%s) both merges and splits", path, i+1, o.Match)
+		}
+	}
+	ov.hits = make([]int, len(ov.list))
+	return ov, nil
+}
+
+// pageTitle is the card ID the tools use: the wiki page title, plus the
+// printing for a page's later printings. ".../wiki/Flash_(DCOP)" gives
+// "Flash_(DCOP)" and ".../wiki/Flash_(DCOP)#silver" "Flash_(DCOP)#silver";
+// opscrape's catalog.PageTitle must agree.
+func pageTitle(pageURL string) string {
+	p, frag := pageURL, ""
+	if u, err := url.Parse(pageURL); err == nil {
+		p, frag = u.Path, u.Fragment
+	}
+	if dec, err := url.PathUnescape(p); err == nil {
+		p = dec
+	}
+	title := p[strings.LastIndex(p, "/")+1:]
+	if frag != "" {
+		title += "#" + frag
+	}
+	return title
+}
+
+func overrideMatches(match, pageURL string) bool {
+	match = strings.TrimSpace(match)
+	if match == pageURL {
+		return true
+	}
+	return strings.ReplaceAll(match, " ", "_") == pageTitle(pageURL)
+}
+
+func (ov *Overrides) logf(format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	ov.audit = append(ov.audit, line)
+	logger.Info("override", "change", line)
+}
+
+// Apply runs right after scrapeOne, before the image is downloaded, so a
+// replaced image is fetched instead of the wrong one. It returns no records
+// for a suppressed page and several for a split one. Merges wait for
+// Finish, when every record is in.
+func (ov *Overrides) Apply(rec CardRecord) []CardRecord {
+	if len(ov.list) == 0 {
+		return []CardRecord{rec}
+	}
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+
+	out := []CardRecord{rec}
+	for i, o := range ov.list {
+		if !overrideMatches(o.Match, rec.PageURL) {
+			continue
+		}
+		ov.hits[i]++
+		if rec.Error != nil {
+			ov.logf("#%d %s: page %s (%v); override not applied", i+1, o.Match, rec.Result, rec.Error)
+			continue
+		}
+		switch {
+		case o.Suppress:
+			ov.logf("#%d %s: suppressed", i+1, o.Match)
+			return nil
+		case len(o.Split) > 0:
+			out = out[:0]
+			for j, part := range o.Split {
+				r := cloneRecord(rec)
+				r.PageURL = splitURL(rec.PageURL, firstSlug(part.Name, j+1))
+				ov.patch(&r, part, fmt.Sprintf("#%d.%d", i+1, j+1))
+				out = append(out, r)
+			}
+			ov.logf("#%d %s: split into %d cards", i+1, o.Match, len(o.Split))
+		default:
+			for k := range out {
+				ov.patch(&out[k], o, fmt.Sprintf("#%d", i+1))
+			}
+		}
+	}
+	return out
+}
+
+// patch applies the name, field and image edits of o to r and logs each change.
+func (ov *Overrides) patch(r *CardRecord, o Override, tag string) {
+	id := pageTitle(r.PageURL)
+	if o.Name != "" {
+		if o.Name == r.Name {
+			logger.Warn("override not needed; the wiki may be fixed", "override", tag, "page", id, "field", "Name", "value", o.Name)
+		} else {
+			ov.logf("%s %s: Name %q -> %q", tag, id, r.Name, o.Name)
+			r.Name = o.Name
+		}
+	}
+	keys := make([]string, 0, len(o.Fields))
+	for k := range o.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, old := o.Fields[k], r.KV[k]
+		switch {
+		case v == old:
+			logger.Warn("override not needed; the wiki may be fixed", "override", tag, "page", id, "field", k, "value", v)
+		case v == "":
+			ov.logf("%s %s: %s %q removed", tag, id, k, old)
+			delete(r.KV, k)
+		default:
+			ov.logf("%s %s: %s %q -> %q", tag, id, k, old, v)
+			r.KV[k] = v
+		}
+	}
+	if o.Image != "" && o.Image != r.ImageURL {
+		ov.logf("%s %s: image %s -> %s", tag, id, r.ImageURL, o.Image)
+		r.ImageURL = o.Image
+		if u, err := url.Parse(o.Image); err == nil {
+			r.ImageName = pickFilename(u)
+		}
+	}
+	refreshDerived(r)
+}
+
+// Finish performs the merges and reports overrides that matched no page.
+func (ov *Overrides) Finish(recs []CardRecord) []CardRecord {
+	for i, o := range ov.list {
+		if o.Merge == "" || ov.hits[i] == 0 {
+			continue
+		}
+		from, into := -1, -1
+		for j := range recs {
+			if recs[j].Error != nil {
+				continue
+			}
+			if overrideMatches(o.Match, recs[j].PageURL) {
+				from = j
+			} else if overrideMatches(o.Merge, recs[j].PageURL) {
+				into = j
+			}
+		}
+		if from < 0 {
+			continue
+		}
+		if into < 0 {
+			logger.Warn("override merge target was not scraped; kept as is", "override", i+1, "match", o.Match, "merge", o.Merge)
+			continue
+		}
+		dst, src := &recs[into], recs[from]
+		for _, k := range src.OrderedKeys {
+			if _, ok := dst.KV[k]; !ok {
+				dst.KV[k] = src.KV[k]
+				ov.logf("#%d %s: %s %q merged from %s", i+1, o.Merge, k, src.KV[k], o.Match)
+			}
+		}
+		if dst.ImageURL == "" && src.ImageURL != "" {
+			dst.ImageURL, dst.ImageName = src.ImageURL, src.ImageName
+			ov.logf("#%d %s: image %s merged from %s", i+1, o.Merge, src.ImageName, o.Match)
+		}
+		refreshDerived(dst)
+		recs = append(recs[:from], recs[from+1:]...)
+		ov.logf("#%d %s: merged into %s", i+1, o.Match, o.Merge)
+	}
+	for i, o := range ov.list {
+		if ov.hits[i] == 0 {
+			logger.Warn("override matched no scraped page; remove it or fix the match", "override", i+1, "match", o.Match)
+		}
+	}
+	return recs
+}
+
+// WriteAudit saves every change made, in order, for review next to the manifest.
+func (ov *Overrides) WriteAudit(path string) error {
+	if len(ov.list) == 0 {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, line := range ov.audit {
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+func cloneRecord(r CardRecord) CardRecord {
+	c := r
+	c.KV = make(map[string]string, len(r.KV))
+	for k, v := range r.KV {
+		c.KV[k] = v
+	}
+	c.OrderedKeys = append([]string(nil), r.OrderedKeys...)
+	return c
+}
+
+// refreshDerived recomputes what scrapeOne derives from the name, rows and
+// image once an override has changed them.
+func refreshDerived(r *CardRecord) {
+	keys := make([]string, 0, len(r.KV))
+	for k := range r.KV {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	r.OrderedKeys = keys
+	r.SchemaKey = strings.Join(keys, "|")
+	r.SetCode = resolveSetCode(*r)
+	r.ControlCode = setcode.NormalizeControl(r.KV["Control"])
+}
+
+// splitURL is the PageURL of one part of a split page: the part's slug as
+// the fragment, after the printing when the page already has one, so
+// ".../Twin_(IQOP)#silver" gives ".../Twin_(IQOP)#silver-twin-a".
+func splitURL(pageURL, slug string) string {
+	if base, frag, ok := strings.Cut(pageURL, "#"); ok && frag != "" {
+		return base + "#" + frag + "-" + slug
+	}
+	return strings.TrimSuffix(pageURL, "#") + "#" + slug
+}
+
+func firstSlug(name string, n int) string {
+	if s := slugify(name); s != "" {
+		return s
+	}
+	return fmt.Sprintf("part-%d", n)
+}
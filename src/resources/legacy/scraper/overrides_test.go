@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitKeepsPrintingFragment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	err := os.WriteFile(path, []byte(`- match: Twin_Card_(IQOP)#silver
+  split:
+    - {name: Twin Card A}
+    - {fields: {Type: Event}}
+- match: Twin_Card_(IQOP)
+  split:
+    - {name: Twin Card A}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ov, err := loadOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const page = "https://cardguide.fandom.com/wiki/Twin_Card_(IQOP)"
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{page + "#silver", []string{"Twin_Card_(IQOP)#silver-twin-card-a", "Twin_Card_(IQOP)#silver-part-2"}},
+		{page, []string{"Twin_Card_(IQOP)#twin-card-a"}},
+	}
+	for _, tt := range tests {
+		rec := CardRecord{Name: "Twin Card", PageURL: tt.url, Result: ResultCard, KV: map[string]string{"Type": "Character"}}
+		out := ov.Apply(rec)
+		if len(out) != len(tt.want) {
+			t.Fatalf("%s: split into %d records, want %d", tt.url, len(out), len(tt.want))
+		}
+		for i, r := range out {
+			if id := pageTitle(r.PageURL); id != tt.want[i] {
+				t.Errorf("%s part %d: ID %q (PageURL %s), want %q", tt.url, i+1, id, r.PageURL, tt.want[i])
+			}
+			if !overrideMatches(tt.want[i], r.PageURL) {
+				t.Errorf("%s part %d: an override naming %s does not match it", tt.url, i+1, tt.want[i])
+			}
+		}
+	}
+}
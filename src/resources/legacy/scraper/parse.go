@@ -0,0 +1,284 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ---------- Stat blocks ----------
+
+// Page layouts the parser understands, as recorded in CardRecord.Layout.
+const (
+	layoutTable    = "table"    // one "Statistics" table
+	layoutTables   = "tables"   // several tables, one per printing
+	layoutPortable = "portable" // Fandom aside.portable-infobox
+	layoutDefList  = "deflist"  // <dl><dt>key<dd>value
+)
+
+// errNoStats marks a page with no stat block in any known layout.
+var errNoStats = errors.New("no Statistics table")
+
+// statBlock is one card's worth of key/value rows found on a page.
+type statBlock struct {
+	sel      *goquery.Selection
+	headers  []string // header cells of a stat table
+	title    string   // title of a portable infobox
+	printing string   // what the table header says beyond "Statistics", e.g. "Silver"
+	kv       map[string]string
+}
+
+// findStatBlocks returns the page's stat blocks and their layout: every stat
+// table, else every portable infobox, else every definition list that has
+// one of the profile's required keys.
+func (p *Profile) findStatBlocks(doc *goquery.Document) ([]statBlock, string) {
+	if blocks := p.statTables(doc); len(blocks) == 1 {
+		return blocks, layoutTable
+	} else if len(blocks) > 1 {
+		return blocks, layoutTables
+	}
+	if p.Infobox.Portable != "" {
+		if blocks := p.portableInfoboxes(doc); len(blocks) > 0 {
+			return blocks, layoutPortable
+		}
+	}
+	if p.Infobox.DefList != "" {
+		if blocks := p.defLists(doc); len(blocks) > 0 {
+			return blocks, layoutDefList
+		}
+	}
+	return nil, ""
+}
+
+func (p *Profile) statTables(doc *goquery.Document) []statBlock {
+	var out []statBlock
+	want := strings.ToLower(p.Infobox.Header)
+	doc.Find(p.Infobox.Table).Each(func(_ int, t *goquery.Selection) {
+		ths := t.Find("tr").First().Find("th")
+		first := strings.TrimSpace(ths.First().Text())
+		if !strings.Contains(strings.ToLower(first), want) {
+			return
+		}
+		b := statBlock{sel: t, kv: map[string]string{}}
+		ths.Each(func(_ int, th *goquery.Selection) {
+			b.headers = append(b.headers, strings.TrimSpace(cleanInline(th.Text())))
+		})
+		// "Statistics (Silver)" / "Statistics - Foil" name the printing.
+		i := strings.Index(strings.ToLower(first), want)
+		b.printing = strings.Trim(cleanInline(first[:i]+first[i+len(want):]), " -–:()[]")
+		t.Find("tr").Each(func(i int, tr *goquery.Selection) {
+			if i == 0 {
+				return // header row
+			}
+			cells := tr.ChildrenFiltered("th,td")
+			if cells.Length() < 2 {
+				return
+			}
+			b.add(cells.Eq(0).Text(), textWithLinkFallback(cells.Eq(1)))
+		})
+		out = append(out, b)
+	})
+	return out
+}
+
+// portableInfoboxes reads <aside class="portable-infobox"> blocks: each
+// data item carries a data-source key, a label and a value.
+func (p *Profile) portableInfoboxes(doc *goquery.Document) []statBlock {
+	var out []statBlock
+	doc.Find(p.Infobox.Portable).Each(func(_ int, aside *goquery.Selection) {
+		b := statBlock{sel: aside, kv: map[string]string{}}
+		b.title = strings.TrimSpace(cleanInline(aside.Find(".pi-title").First().Text()))
+		aside.Find(".pi-data").Each(func(_ int, item *goquery.Selection) {
+			k := item.Find(".pi-data-label").First().Text()
+			if strings.TrimSpace(k) == "" {
+				ds, _ := item.Attr("data-source")
+				k = dataSourceKey(ds)
+			}
+			b.add(k, textWithLinkFallback(item.Find(".pi-data-value").First()))
+		})
+		if p.hasRequiredKey(b) {
+			out = append(out, b)
+		}
+	})
+	return out
+}
+
+// defLists reads <dt>/<dd> pairs; a <dt> with several <dd>s keeps them
+// comma-separated like the Characters cell of a table.
+func (p *Profile) defLists(doc *goquery.Document) []statBlock {
+	var out []statBlock
+	doc.Find(p.Infobox.DefList).Each(func(_ int, dl *goquery.Selection) {
+		b := statBlock{sel: dl, kv: map[string]string{}}
+		key := ""
+		dl.Children().Each(func(_ int, c *goquery.Selection) {
+			switch goquery.NodeName(c) {
+			case "dt":
+				key = c.Text()
+			case "dd":
+				v := strings.TrimSpace(cleanInline(textWithLinkFallback(c)))
+				k := strings.TrimSpace(cleanInline(key))
+				if prev := b.kv[k]; prev != "" && v != "" {
+					v = prev + ", " + v
+				}
+				b.add(key, v)
+			}
+		})
+		if p.hasRequiredKey(b) {
+			out = append(out, b)
+		}
+	})
+	return out
+}
+
+// dataSourceKey turns an unlabelled item's data-source ("game_text") into
+// the key a stat table would have ("Game Text"), so Require and the
+// manifest columns see the same names whatever the layout.
+func dataSourceKey(ds string) string {
+	words := strings.FieldsFunc(ds, func(r rune) bool { return r == '_' || r == '-' || r == ' ' })
+	for i, w := range words {
+		r, n := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + strings.ToLower(w[n:])
+	}
+	return strings.Join(words, " ")
+}
+
+func (b *statBlock) add(k, v string) {
+	k, v = strings.TrimSpace(cleanInline(k)), strings.TrimSpace(cleanInline(v))
+	if k != "" && v != "" {
+		b.kv[k] = v
+	}
+}
+
+// hasRequiredKey keeps navigation boxes and glossaries, which share the
+// markup, out of the card data.
+func (p *Profile) hasRequiredKey(b statBlock) bool {
+	if len(p.Infobox.Require) == 0 {
+		return len(b.kv) > 0
+	}
+	for _, k := range p.Infobox.Require {
+		if _, ok := b.kv[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------- Records ----------
+
+// blockRecords turns a page's blocks into records. The first block keeps the
+// page URL; further printings get "#<printing>" so each has its own ID, and
+// their image is looked up inside the block first.
+func blockRecords(doc *goquery.Document, pageURL string, blocks []statBlock, layout string) []CardRecord {
+	var out []CardRecord
+	seen := map[string]bool{}
+	for i, b := range blocks {
+		rec := CardRecord{PageURL: pageURL, KV: b.kv, Layout: layout}
+		rec.Name = profile.cardName(doc, b)
+		if len(blocks) > 1 {
+			if _, ok := rec.KV["Printing"]; !ok && b.printing != "" {
+				rec.KV["Printing"] = b.printing
+			}
+			if i > 0 {
+				frag := slugify(firstNonEmpty(rec.KV["Printing"], b.printing))
+				if frag == "" || seen[frag] {
+					frag = fmt.Sprintf("printing-%d", i+1)
+				}
+				seen[frag] = true
+				rec.PageURL = pageURL + "#" + frag
+			}
+		}
+
+		var imgURL string
+		if len(blocks) > 1 {
+			imgURL, _ = findImageURLIn(b.sel, pageURL)
+		}
+		if imgURL == "" {
+			imgURL, _ = findImageURLFromDoc(doc, pageURL)
+		}
+		if imgURL != "" {
+			rec.ImageURL = imgURL
+			if u, err := url.Parse(imgURL); err == nil {
+				rec.ImageName = pickFilename(u)
+			}
+		}
+		rec.Result = ResultCard
+		if rec.Name == "" {
+			rec.Error, rec.Result = errNoName, ResultParseError
+		}
+		refreshDerived(&rec)
+		out = append(out, rec)
+	}
+	return out
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ---------- Coverage ----------
+
+// layoutCoverage counts scraped pages by layout; pages with no stat block
+// at all are counted as "none".
+func layoutCoverage(recs []CardRecord) map[string]int {
+	counts := map[string]int{}
+	pages := map[string]bool{}
+	for _, r := range recs {
+		page := strings.SplitN(r.PageURL, "#", 2)[0]
+		if pages[page] {
+			continue
+		}
+		pages[page] = true
+		switch {
+		case r.Layout != "":
+			counts[r.Layout]++
+		case r.Result == ResultSkipped:
+			counts["none"]++
+		}
+	}
+	return counts
+}
+
+// formatCoverage lists the counts in a fixed order.
+func formatCoverage(counts map[string]int) string {
+	order := []string{layoutTable, layoutTables, layoutPortable, layoutDefList, "none"}
+	var parts []string
+	for _, k := range order {
+		if counts[k] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", k, counts[k]))
+		}
+	}
+	var rest []string
+	for k := range counts {
+		if !contains(order, k) {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		parts = append(parts, fmt.Sprintf("%s %d", k, counts[k]))
+	}
+	if len(parts) == 0 {
+		return "no pages"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package scraper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func parsePage(t *testing.T, html string) []CardRecord {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, layout := profile.findStatBlocks(doc)
+	if len(blocks) == 0 {
+		return nil
+	}
+	return blockRecords(doc, "https://cardguide.fandom.com/wiki/Test_Card_(DCOP)", blocks, layout)
+}
+
+func TestPortableInfobox(t *testing.T) {
+	recs := parsePage(t, `<h1 id="firstHeading">Test Card (DCOP)</h1>
+<aside class="portable-infobox">
+  <h2 class="pi-item pi-title" data-source="title">Green Lantern</h2>
+  <figure class="pi-item pi-image"><a class="image" href="https://img.example/GreenLantern-DCOP.jpg/revision/latest?cb=7">i</a></figure>
+  <div class="pi-item pi-data" data-source="type"><h3 class="pi-data-label">Type</h3><div class="pi-data-value">Character</div></div>
+  <div class="pi-item pi-data" data-source="rarity"><div class="pi-data-value">Rare</div></div>
+</aside>`)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records", len(recs))
+	}
+	r := recs[0]
+	if r.Layout != layoutPortable || r.Name != "Green Lantern" || r.KV["Type"] != "Character" || r.KV["Rarity"] != "Rare" {
+		t.Errorf("rec = %+v", r)
+	}
+	if r.ImageName != "GreenLantern-DCOP_cb7.jpg" {
+		t.Errorf("image = %q", r.ImageName)
+	}
+}
+
+func TestPortableInfoboxWithoutLabels(t *testing.T) {
+	recs := parsePage(t, `<h1 id="firstHeading">Test Card (DCOP)</h1>
+<aside class="portable-infobox">
+  <h2 class="pi-item pi-title" data-source="title">Green Lantern</h2>
+  <div class="pi-item pi-data" data-source="type"><div class="pi-data-value">Character</div></div>
+  <div class="pi-item pi-data" data-source="rarity"><div class="pi-data-value">Rare</div></div>
+  <div class="pi-item pi-data" data-source="game_text"><div class="pi-data-value">Venture 1 additional card.</div></div>
+</aside>`)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records; unlabelled items must still satisfy Require", len(recs))
+	}
+	r := recs[0]
+	if r.KV["Type"] != "Character" || r.KV["Rarity"] != "Rare" || r.KV["Game Text"] != "Venture 1 additional card." {
+		t.Errorf("kv = %v", r.KV)
+	}
+}
+
+func TestStatTablePerPrinting(t *testing.T) {
+	recs := parsePage(t, `<h1 id="firstHeading">Test Card (DCOP)</h1>
+<table><tr><th>Statistics</th><th>Flash</th></tr><tr><th>Type</th><td>Character</td></tr>
+  <tr><td colspan="2"><a class="image" href="https://img.example/Flash-DCOP.jpg">i</a></td></tr></table>
+<table><tr><th>Statistics (Silver)</th><th>Flash</th></tr><tr><th>Type</th><td>Character</td></tr>
+  <tr><td colspan="2"><a class="image" href="https://img.example/FlashSilver-DCOP.jpg">i</a></td></tr></table>`)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records", len(recs))
+	}
+	if recs[0].PageURL != "https://cardguide.fandom.com/wiki/Test_Card_(DCOP)" || recs[0].ImageName != "Flash-DCOP.jpg" {
+		t.Errorf("first = %+v", recs[0])
+	}
+	silver := recs[1]
+	if silver.PageURL != "https://cardguide.fandom.com/wiki/Test_Card_(DCOP)#silver" || silver.KV["Printing"] != "Silver" ||
+		silver.ImageName != "FlashSilver-DCOP.jpg" || silver.Layout != layoutTables || silver.SetCode != "DCOP" {
+		t.Errorf("silver = %+v", silver)
+	}
+	if a, b := pageTitle(recs[0].PageURL), pageTitle(silver.PageURL); a != "Test_Card_(DCOP)" || b != "Test_Card_(DCOP)#silver" {
+		t.Errorf("card IDs = %q, %q; each printing needs its own", a, b)
+	}
+	if overrideMatches("Test_Card_(DCOP)", silver.PageURL) || !overrideMatches("Test Card (DCOP)#silver", silver.PageURL) {
+		t.Error("an override must name the printing it patches")
+	}
+}
+
+func TestDefinitionList(t *testing.T) {
+	recs := parsePage(t, `<h1 id="firstHeading">Teamwork (DCOP)</h1><div id="mw-content-text">
+<dl><dt>See also</dt><dd>Other pages</dd></dl>
+<dl><dt>Type</dt><dd>Teamwork</dd><dt>Characters</dt><dd>Batman</dd><dd>Robin</dd></dl></div>`)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records", len(recs))
+	}
+	if r := recs[0]; r.Layout != layoutDefList || r.Name != "Teamwork (DCOP)" || r.KV["Characters"] != "Batman, Robin" {
+		t.Errorf("rec = %+v", r)
+	}
+}
+
+func TestLayoutCoverage(t *testing.T) {
+	recs := []CardRecord{
+		{PageURL: "a", Layout: layoutTable},
+		{PageURL: "b", Layout: layoutTables},
+		{PageURL: "b#silver", Layout: layoutTables},
+		{PageURL: "c", Error: errNoStats, Result: ResultSkipped},
+		{PageURL: "d", Error: errors.New("GET d: 404 Not Found"), Result: ResultFetchError},
+	}
+	if got, want := formatCoverage(layoutCoverage(recs)), "table 1, tables 1, none 1"; got != want {
+		t.Errorf("coverage = %q, want %q", got, want)
+	}
+}
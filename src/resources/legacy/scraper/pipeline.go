@@ -0,0 +1,159 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ---------- Pipeline ----------
+
+// Stage is one pool of the scrape pipeline: how many goroutines run it and
+// how long each waits between items. A zero Delay means no rate limit.
+type Stage struct {
+	Workers int
+	Delay   time.Duration
+}
+
+// The stages after the page fetch; the fetch pool is -workers/-delay. The
+// parse stage makes no requests, so it is unthrottled unless -parse-delay
+// says otherwise, e.g. to keep a replayed run from saturating the CPU.
+var (
+	parseStage = Stage{Workers: 2}
+	imageStage = Stage{Workers: 4, Delay: 100 * time.Millisecond}
+	withImages = true // -images=false records image URLs but fetches none
+)
+
+// fetchedPage is what the fetch pool hands the parse stage.
+type fetchedPage struct {
+	link    string
+	pageURL string // canonical
+	doc     *goquery.Document
+	err     error
+}
+
+// scrapeAndDownloadAll runs the pipeline
+//
+//	pages -> fetch pool -> parse stage -> image pool -> records
+//
+// with bounded channels between the stages, so a slow image host holds back
+// page fetches only once the image queue is full instead of every fetch
+// worker sleeping through image retries.
+func scrapeAndDownloadAll(pages []string, ov *Overrides) []CardRecord {
+	fetchStage := Stage{Workers: workers, Delay: reqDelay}
+	jobs := make(chan string)
+	fetched := make(chan fetchedPage, fetchStage.Workers)
+	downloads := make(chan CardRecord, 4*imageStage.Workers)
+	results := make(chan CardRecord, imageStage.Workers)
+	stats.update(func(s *RunStats) { s.pages = len(pages) })
+
+	go func() {
+		for _, u := range pages {
+			jobs <- u
+		}
+		close(jobs)
+	}()
+
+	// Fetch: network only, at the page rate.
+	fetchWG := fetchStage.run(func(id int, tick func()) {
+		for link := range jobs {
+			tick()
+			start := time.Now()
+			doc, pageURL, err := fetchPage(link)
+			stats.observe("page", time.Since(start))
+			stats.update(func(s *RunStats) { s.fetched++ })
+			fetched <- fetchedPage{link, pageURL, doc, err}
+		}
+	})
+
+	// Parse: stat blocks, dedupe, overrides. Cards with an image go on to
+	// the image pool; everything else is finished here.
+	var scraped pageSet
+	parseWG := parseStage.run(func(id int, tick func()) {
+		for p := range fetched {
+			tick()
+			page := parseCardPage(p.link, p.pageURL, p.doc, p.err)
+			if first := scraped.claim(page[0].PageURL, p.link); first != "" {
+				logger.Info("same page already scraped", "stage", "parse", "worker", id, "url", p.link, "as", first)
+				continue
+			}
+			for _, r := range page {
+				for _, rec := range ov.Apply(r) {
+					if withImages && rec.Result == ResultCard && rec.ImageURL != "" && rec.ImageName != "" {
+						downloads <- rec
+						continue
+					}
+					logResult("Parse", id, p.link, rec)
+					results <- rec
+				}
+			}
+		}
+	})
+
+	// Images: downloads with their own pool and rate.
+	imageWG := imageStage.run(func(id int, tick func()) {
+		for rec := range downloads {
+			tick()
+			start := time.Now()
+			if err := downloadImage(rec.ImageURL, filepath.Join(outImages, rec.ImageName)); err != nil {
+				rec.Error, rec.Result = fmt.Errorf("download image: %w", err), ResultImageError
+			} else {
+				stats.update(func(s *RunStats) { s.images++ })
+			}
+			stats.observe("image", time.Since(start))
+			logResult("Image", id, rec.PageURL, rec)
+			results <- rec
+		}
+	})
+
+	go func() {
+		fetchWG.Wait()
+		close(fetched)
+		parseWG.Wait()
+		close(downloads)
+		imageWG.Wait()
+		close(results)
+	}()
+
+	var out []CardRecord
+	for r := range results {
+		out = append(out, r)
+	}
+	return out
+}
+
+// run starts the stage's workers; tick waits out the stage's delay.
+func (s Stage) run(work func(id int, tick func())) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for i := 0; i < max(s.Workers, 1); i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tick := func() {}
+			if s.Delay > 0 {
+				limiter := time.NewTicker(s.Delay)
+				defer limiter.Stop()
+				tick = func() { <-limiter.C }
+			}
+			work(id, tick)
+		}(i + 1)
+	}
+	return &wg
+}
+
+// logResult logs a finished record and counts it.
+func logResult(stage string, id int, link string, rec CardRecord) {
+	stats.update(func(s *RunStats) { s.results[rec.Result]++ })
+	attrs := []any{"stage", stage, "worker", id, "result", string(rec.Result), "url", link}
+	switch {
+	case rec.Result == ResultCard:
+		logger.Info("card", append(attrs, "name", rec.Name)...)
+	case rec.Result.Failed():
+		logger.Error("page failed", append(attrs, "err", rec.Error)...)
+	default:
+		logger.Info("page skipped", append(attrs, "err", rec.Error)...)
+	}
+}
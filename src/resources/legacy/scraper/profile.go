@@ -0,0 +1,258 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+// ---------- Site profiles ----------
+
+// Profile says where a wiki keeps what the scraper reads. Supporting another
+// wiki, or a Fandom skin change, is a new profile rather than a code change;
+// -profile takes a built-in name or a YAML file.
+type Profile struct {
+	Name    string        `yaml:"name"`
+	Links   LinkRules     `yaml:"links"`
+	Infobox InfoboxRules  `yaml:"infobox"`
+	Names   []NameSource  `yaml:"card_name"` // first non-empty wins
+	Images  []ImageSource `yaml:"image"`     // first match wins
+	API     string        `yaml:"api"`       // MediaWiki api.php, relative to -url; "" skips SHA1 checks of images
+}
+
+// LinkRules pick card pages out of the index page.
+type LinkRules struct {
+	Selector string   `yaml:"selector"` // anchors to consider
+	Prefix   string   `yaml:"prefix"`   // href must start with this
+	Exclude  []string `yaml:"exclude"`  // href must not contain any of these
+	SetTags  []string `yaml:"set_tags"` // keep only links whose href or text carries "(TAG)"; empty keeps all
+	Next     string   `yaml:"next"`     // "next page" anchors of a paginated listing; "" disables
+	Follow   []string `yaml:"follow"`   // hrefs containing any of these are listings to crawl, not cards
+}
+
+// InfoboxRules locate the key/value rows on a card page: every Table whose
+// first header cell contains Header (case-insensitive), else every Portable
+// infobox, else every DefList. Portable infoboxes and definition lists only
+// count when they have one of the Require keys.
+type InfoboxRules struct {
+	Table    string   `yaml:"table"`
+	Header   string   `yaml:"header"`
+	Portable string   `yaml:"portable"` // "" disables
+	DefList  string   `yaml:"deflist"`  // "" disables
+	Require  []string `yaml:"require"`
+}
+
+// NameSource is either a selector whose text is the name or the n-th
+// (1-based) header cell of a stat table's first row; any infobox_header
+// source takes a portable infobox's title.
+type NameSource struct {
+	Selector      string `yaml:"selector,omitempty"`
+	InfoboxHeader int    `yaml:"infobox_header,omitempty"`
+}
+
+// ImageSource is an element and the attribute holding the image URL.
+type ImageSource struct {
+	Selector string `yaml:"selector"`
+	Attr     string `yaml:"attr"`
+}
+
+// builtinProfiles ship with the scraper. "fandom" is what the scraper has
+// always done; "fandom-mvop" adds the (MVOP) link filter marvelop uses.
+var builtinProfiles = map[string]string{
+	"fandom": `
+name: fandom
+links:
+  selector: "#mw-content-text a[href]"
+  prefix: /wiki/
+  exclude: [":Category", ":File"]
+  next: 'a[href*="pagefrom="]'
+  follow: ["/wiki/Category:"]
+infobox:
+  table: table
+  header: statistics
+  portable: aside.portable-infobox
+  deflist: "#mw-content-text dl"
+  require: [Type, Rarity, Characters]
+card_name:
+  - infobox_header: 2
+  - selector: "#firstHeading"
+image:
+  - {selector: 'a[href*="?file="]', attr: href}
+  - {selector: 'meta[property="og:image"]', attr: content}
+  - {selector: a.image, attr: href}
+api: /api.php
+`,
+	"fandom-mvop": `
+name: fandom-mvop
+links:
+  selector: a[href]
+  prefix: /wiki/
+  exclude: [":Category", ":File"]
+  next: 'a[href*="pagefrom="]'
+  follow: ["/wiki/Category:"]
+  set_tags: [MVOP]
+infobox:
+  table: table
+  header: statistics
+  portable: aside.portable-infobox
+  deflist: "#mw-content-text dl"
+  require: [Type, Rarity, Characters]
+card_name:
+  - infobox_header: 2
+  - selector: "#firstHeading"
+image:
+  - {selector: 'a[href*="?file="]', attr: href}
+  - {selector: 'meta[property="og:image"]', attr: content}
+  - {selector: a.image, attr: href}
+api: /api.php
+`,
+}
+
+// profile is the one in use; main replaces it from -profile.
+var profile = mustProfile(loadProfile("fandom"))
+
+func mustProfile(p *Profile, err error) *Profile {
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// loadProfile returns a built-in profile by name, else reads the YAML file.
+func loadProfile(nameOrPath string) (*Profile, error) {
+	src, ok := builtinProfiles[nameOrPath]
+	where := "profile " + nameOrPath
+	if !ok {
+		data, err := os.ReadFile(nameOrPath)
+		if err != nil {
+			names := make([]string, 0, len(builtinProfiles))
+			for n := range builtinProfiles {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("-profile %q is neither a built-in (%s) nor a readable file: %w", nameOrPath, strings.Join(names, ", "), err)
+		}
+		src, where = string(data), nameOrPath
+	}
+	var p Profile
+	if err := yaml.Unmarshal([]byte(src), &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", where, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", where, err)
+	}
+	return &p, nil
+}
+
+func (p *Profile) validate() error {
+	sels := map[string]string{"links.selector": p.Links.Selector, "infobox.table": p.Infobox.Table}
+	for field, sel := range map[string]string{"links.next": p.Links.Next, "infobox.portable": p.Infobox.Portable, "infobox.deflist": p.Infobox.DefList} {
+		if sel != "" {
+			sels[field] = sel
+		}
+	}
+	for i, n := range p.Names {
+		if (n.Selector == "") == (n.InfoboxHeader == 0) {
+			return fmt.Errorf("card_name[%d]: set exactly one of selector or infobox_header", i)
+		}
+		if n.Selector != "" {
+			sels[fmt.Sprintf("card_name[%d].selector", i)] = n.Selector
+		}
+	}
+	for i, im := range p.Images {
+		if im.Attr == "" {
+			return fmt.Errorf("image[%d]: attr is required", i)
+		}
+		sels[fmt.Sprintf("image[%d].selector", i)] = im.Selector
+	}
+	for field, sel := range sels {
+		if sel == "" {
+			return fmt.Errorf("%s is required", field)
+		}
+		if _, err := cascadia.ParseGroup(sel); err != nil {
+			return fmt.Errorf("%s %q: %w", field, sel, err)
+		}
+	}
+	if len(p.Names) == 0 {
+		return fmt.Errorf("card_name: at least one source is required")
+	}
+	return nil
+}
+
+// apiURL resolves the profile's api path against a page of the wiki.
+func (p *Profile) apiURL(pageURL string) string {
+	if p.API == "" {
+		return ""
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	api, err := u.Parse(p.API)
+	if err != nil {
+		return ""
+	}
+	return api.String()
+}
+
+// wantLink applies the link rules to one anchor of the index page.
+func (p *Profile) wantLink(href, text string) bool {
+	if href == "" || !strings.HasPrefix(href, p.Links.Prefix) {
+		return false
+	}
+	for _, x := range p.Links.Exclude {
+		if strings.Contains(href, x) {
+			return false
+		}
+	}
+	if len(p.Links.SetTags) == 0 {
+		return true
+	}
+	dec := href
+	if d, err := url.PathUnescape(href); err == nil {
+		dec = d
+	}
+	for _, tag := range p.Links.SetTags {
+		t := "(" + strings.ToUpper(tag) + ")"
+		if strings.Contains(dec, t) || strings.Contains(strings.ToUpper(text), t) {
+			return true
+		}
+	}
+	return false
+}
+
+// follows reports whether href is a listing page (a category, say) that a
+// deeper crawl reads for more links.
+func (p *Profile) follows(href string) bool {
+	for _, f := range p.Links.Follow {
+		if strings.Contains(href, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// cardName tries the name sources in order for one stat block.
+func (p *Profile) cardName(doc *goquery.Document, b statBlock) string {
+	for _, n := range p.Names {
+		var name string
+		switch {
+		case n.Selector != "":
+			name = strings.TrimSpace(doc.Find(n.Selector).First().Text())
+		case b.title != "":
+			name = b.title
+		case len(b.headers) >= n.InfoboxHeader:
+			name = b.headers[n.InfoboxHeader-1]
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
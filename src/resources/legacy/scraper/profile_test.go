@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinProfileLinkRules(t *testing.T) {
+	mvop, err := loadProfile("fandom-mvop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		p          string
+		href, text string
+		want       bool
+	}{
+		{"fandom", "/wiki/Batman_(DCOP)", "Batman", true},
+		{"fandom", "/wiki/Special:Category", "", false},
+		{"fandom", "https://example.org/x", "", false},
+		{"fandom-mvop", "/wiki/Captain_America_(MVOP)", "", true},
+		{"fandom-mvop", "/wiki/Captain_America_%28MVOP%29", "", true},
+		{"fandom-mvop", "/wiki/Captain_America", "Captain America (MVOP)", true},
+		{"fandom-mvop", "/wiki/Captain_America_(CLOP)", "Captain America", false},
+	}
+	for _, c := range cases {
+		p := profile
+		if c.p == "fandom-mvop" {
+			p = mvop
+		}
+		if got := p.wantLink(c.href, c.text); got != c.want {
+			t.Errorf("%s wantLink(%q, %q) = %v, want %v", c.p, c.href, c.text, got, c.want)
+		}
+	}
+}
+
+func TestProfileFromFile(t *testing.T) {
+	useReplay(t, filepath.Join("testdata", "site.har"))
+	path := filepath.Join(t.TempDir(), "heading-only.yaml")
+	os.WriteFile(path, []byte(`
+name: heading-only
+links: {selector: "a[href]", prefix: /wiki/}
+infobox: {table: table.wikitable, header: statistics}
+card_name:
+  - selector: "#firstHeading"
+image:
+  - {selector: 'meta[property="og:image"]', attr: content}
+`), 0o644)
+	p, err := loadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := profile
+	t.Cleanup(func() { profile = old })
+	profile = p
+
+	rec := scrapeOne("https://cardguide.fandom.com/wiki/Batman_(DCOP)")[0]
+	if rec.Error != nil || rec.Name != "Batman (DCOP)" || rec.KV["Type"] != "Character" {
+		t.Fatalf("rec = %+v", rec)
+	}
+
+	if _, err := loadProfile("no-such-profile"); err == nil || !strings.Contains(err.Error(), "fandom-mvop") {
+		t.Errorf("unknown profile error = %v", err)
+	}
+	os.WriteFile(path, []byte("name: bad\nlinks: {selector: 'a[', prefix: /}\ninfobox: {table: table}\ncard_name: [{selector: h1}]\n"), 0o644)
+	if _, err := loadProfile(path); err == nil {
+		t.Error("bad selector accepted")
+	}
+}
+
+// keepSetGlobals restores what registerFlags sets from a Set.
+func keepSetGlobals(t *testing.T) {
+	oldURL, oldSet, oldProfile, oldTitle := startURL, setCode, profileArg, title
+	t.Cleanup(func() { startURL, setCode, profileArg, title = oldURL, oldSet, oldProfile, oldTitle })
+}
+
+func TestSetDefaults(t *testing.T) {
+	keepSetGlobals(t)
+
+	mvop := Set{Index: "https://w/wiki/Marvel_OverPower_(expansion)", Code: "MVOP", Profile: "fandom-mvop", Title: "Marvel OverPower"}
+	fs := flag.NewFlagSet("marvelop", flag.ContinueOnError)
+	registerFlags(fs, mvop)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if startURL != mvop.Index || setCode != "MVOP" || profileArg != "fandom-mvop" || title != "Marvel OverPower" {
+		t.Errorf("defaults = %q %q %q %q", startURL, setCode, profileArg, title)
+	}
+
+	fs = flag.NewFlagSet("dcop", flag.ContinueOnError)
+	registerFlags(fs, Set{Code: "DCOP"})
+	if err := fs.Parse([]string{"-url", "https://w/wiki/X", "-set", "P"}); err != nil {
+		t.Fatal(err)
+	}
+	if startURL != "https://w/wiki/X" || setCode != "P" || profileArg != "fandom" || title != "OverPower" {
+		t.Errorf("flags = %q %q %q %q", startURL, setCode, profileArg, title)
+	}
+	if !flagPassed(fs, "url") || flagPassed(fs, "index") {
+		t.Error("flagPassed: -url was passed, -index was not")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	keepSetGlobals(t)
+	var buf strings.Builder
+	fs := flag.NewFlagSet("dcop", flag.ContinueOnError)
+	fs.SetOutput(&buf)
+	registerFlags(fs, Set{Code: "DCOP"})
+	fs.Usage()
+	for _, want := range []string{"Usage of dcop:", "verify", "-url string", "-log-format string"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("usage lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
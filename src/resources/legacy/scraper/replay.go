@@ -0,0 +1,190 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ---------- Fetcher ----------
+
+// Fetcher sends the scraper's requests. It is httpClient in normal runs;
+// -record/-replay and tests put a Recorder in front of it.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+var fetcher Fetcher = httpClient // tests swap in a replay
+
+// ---------- Record / replay ----------
+
+// Recorder is a RoundTripper that either records every exchange made
+// through Next or answers from a previous recording without touching the
+// network. Recordings are HAR-like JSON files, one entry per URL.
+type Recorder struct {
+	Replay bool
+	Next   http.RoundTripper // used when recording
+
+	mu      sync.Mutex
+	entries map[string]harEntry // by method + " " + URL
+}
+
+// har mirrors the subset of HAR 1.2 the recorder writes.
+type har struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status     int         `json:"status"`
+		StatusText string      `json:"statusText"`
+		Headers    []harHeader `json:"headers"`
+		Content    struct {
+			Size     int    `json:"size"`
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding,omitempty"` // "base64" for binary bodies
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewRecorder records through next.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	return &Recorder{Next: next, entries: map[string]harEntry{}}
+}
+
+// LoadReplay reads a recording to replay.
+func LoadReplay(path string) (*Recorder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h har
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r := &Recorder{Replay: true, entries: map[string]harEntry{}}
+	for _, e := range h.Log.Entries {
+		r.entries[e.Request.Method+" "+e.Request.URL] = e
+	}
+	return r, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	k := req.Method + " " + req.URL.String()
+	if r.Replay {
+		r.mu.Lock()
+		e, ok := r.entries[k]
+		r.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("replay: no recorded response for %s", k)
+		}
+		return e.response(req)
+	}
+
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var e harEntry
+	e.Request.Method, e.Request.URL = req.Method, req.URL.String()
+	e.Response.Status = resp.StatusCode
+	e.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode)))
+	for _, name := range []string{"Content-Type", "Content-Length", "Location", "Retry-After", "ETag", "Last-Modified"} {
+		if v := resp.Header.Get(name); v != "" {
+			e.Response.Headers = append(e.Response.Headers, harHeader{name, v})
+		}
+	}
+	c := &e.Response.Content
+	c.Size, c.MimeType = len(body), resp.Header.Get("Content-Type")
+	if isTextBody(c.MimeType, body) {
+		c.Text = string(body)
+	} else {
+		c.Text, c.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	r.mu.Lock()
+	r.entries[k] = e
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func isTextBody(mime string, body []byte) bool {
+	return (strings.HasPrefix(mime, "text/") || strings.Contains(mime, "json") || strings.Contains(mime, "xml")) && utf8.Valid(body)
+}
+
+func (e harEntry) response(req *http.Request) (*http.Response, error) {
+	body := []byte(e.Response.Content.Text)
+	if e.Response.Content.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+			return nil, fmt.Errorf("replay %s: %w", e.Request.URL, err)
+		}
+	}
+	h := http.Header{}
+	for _, kv := range e.Response.Headers {
+		h.Add(kv.Name, kv.Value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, e.Response.StatusText),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the recording, entries sorted by URL so re-recording a site
+// gives a readable diff.
+func (r *Recorder) Save(path string) error {
+	var h har
+	h.Log.Version = "1.2"
+	h.Log.Creator = harCreator{Name: "overpower-scrape", Version: "1.1"}
+	r.mu.Lock()
+	for _, e := range r.entries {
+		h.Log.Entries = append(h.Log.Entries, e)
+	}
+	r.mu.Unlock()
+	sort.Slice(h.Log.Entries, func(i, j int) bool { return h.Log.Entries[i].Request.URL < h.Log.Entries[j].Request.URL })
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+)
+
+// ---------- Run results ----------
+
+// Result says what became of one scraped page. Only the *-error results are
+// failures: the permissive link collector always picks up some pages that
+// are not cards, and those are expected.
+type Result string
+
+const (
+	ResultCard       Result = "card"
+	ResultSkipped    Result = "skipped-non-card" // no stat block in any known layout
+	ResultFetchError Result = "fetch-error"      // the page could not be fetched
+	ResultParseError Result = "parse-error"      // a stat block that did not make a card
+	ResultImageError Result = "image-error"      // the card's image did not download
+	ResultDisallowed Result = "disallowed"       // robots.txt does not let the scraper fetch the page
+)
+
+var resultOrder = []Result{ResultCard, ResultSkipped, ResultDisallowed, ResultFetchError, ResultParseError, ResultImageError}
+
+// Failed reports whether r is a real failure.
+func (r Result) Failed() bool {
+	return r == ResultFetchError || r == ResultParseError || r == ResultImageError
+}
+
+// errNoName marks a stat block that has rows but nothing to call the card.
+var errNoName = errors.New("stat block without a card name")
+
+// ---------- Run report ----------
+
+// RunReport is run-report.json: result counts per set, with the URL of
+// every page that did not become a card, so a real regression is not lost
+// among pages that are expected to be skipped.
+type RunReport struct {
+	Source string                `json:"source"`
+	Set    string                `json:"set"`
+	Totals map[Result]int        `json:"totals"`
+	Sets   map[string]*SetReport `json:"sets"` // by each page's set tag, else the run's
+
+	Disallowed []string `json:"disallowed,omitempty"` // every URL robots.txt refused, images and listings included
+}
+
+type SetReport struct {
+	Counts map[Result]int          `json:"counts"`
+	Pages  map[Result][]ReportPage `json:"pages,omitempty"` // every result but card
+}
+
+type ReportPage struct {
+	URL   string `json:"url"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func buildRunReport(recs []CardRecord, src, set string) RunReport {
+	rep := RunReport{Source: src, Set: set, Totals: map[Result]int{}, Sets: map[string]*SetReport{}}
+	for _, r := range recs {
+		code := firstNonEmpty(r.SetCode, setCodeFromPageURL(r.PageURL), set, "unknown")
+		sr := rep.Sets[code]
+		if sr == nil {
+			sr = &SetReport{Counts: map[Result]int{}, Pages: map[Result][]ReportPage{}}
+			rep.Sets[code] = sr
+		}
+		rep.Totals[r.Result]++
+		sr.Counts[r.Result]++
+		if r.Result == ResultCard {
+			continue
+		}
+		p := ReportPage{URL: r.PageURL, Name: r.Name}
+		if r.Error != nil {
+			p.Error = r.Error.Error()
+		}
+		sr.Pages[r.Result] = append(sr.Pages[r.Result], p)
+	}
+	for _, sr := range rep.Sets {
+		for _, pages := range sr.Pages {
+			sort.Slice(pages, func(i, j int) bool { return pages[i].URL < pages[j].URL })
+		}
+	}
+	return rep
+}
+
+func writeRunReport(rep RunReport, path string) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,34 @@
+package scraper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunReportSeparatesSkips(t *testing.T) {
+	recs := []CardRecord{
+		{PageURL: "https://w/wiki/Angel_(XMOP)", Name: "Angel", SetCode: "XMOP", Result: ResultCard},
+		{PageURL: "https://w/wiki/Storm_(P)", Name: "Storm", SetCode: "P", Result: ResultCard},
+		{PageURL: "https://w/wiki/X-Men_(team)", Error: errNoStats, Result: ResultSkipped},
+		{PageURL: "https://w/wiki/Beast_(XMOP)", Error: errors.New("GET: 503"), Result: ResultFetchError},
+		{PageURL: "https://w/wiki/Bishop_(XMOP)", Name: "Bishop", Error: errors.New("download image: 404"), Result: ResultImageError},
+	}
+	rep := buildRunReport(recs, "https://w/wiki/X-Men_(expansion)", "XMOP")
+
+	if rep.Totals[ResultCard] != 2 || rep.Totals[ResultSkipped] != 1 || rep.Totals[ResultFetchError] != 1 {
+		t.Errorf("totals = %v", rep.Totals)
+	}
+	x := rep.Sets["XMOP"]
+	if x == nil || x.Counts[ResultCard] != 1 || x.Counts[ResultSkipped] != 1 || rep.Sets["P"].Counts[ResultCard] != 1 {
+		t.Fatalf("sets = %+v", rep.Sets)
+	}
+	if p := x.Pages[ResultImageError]; len(p) != 1 || p[0].Name != "Bishop" || p[0].Error != "download image: 404" {
+		t.Errorf("image errors = %+v", p)
+	}
+	if _, ok := x.Pages[ResultCard]; ok {
+		t.Error("card URLs listed")
+	}
+	if ResultSkipped.Failed() || !ResultParseError.Failed() {
+		t.Error("Failed() misclassifies")
+	}
+}
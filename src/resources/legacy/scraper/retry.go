@@ -0,0 +1,250 @@
+package scraper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ---------- Retry policy ----------
+
+// RetryPolicy is how every request the scraper makes is retried: up to
+// Attempts tries, waiting a random time up to Base*2^n (full jitter) between
+// them, or what a Retry-After header asks for, never longer than Max.
+type RetryPolicy struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+var retry = RetryPolicy{Attempts: 5, Base: 500 * time.Millisecond, Max: 30 * time.Second}
+
+// Do runs op until it succeeds, fails permanently or runs out of attempts.
+// what names the request in log lines.
+func (p RetryPolicy) Do(ctx context.Context, what string, op func() error) error {
+	attempts := max(p.Attempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		class, retryable := classifyError(err)
+		if !retryable {
+			return err
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("%s after %d attempts: %w", class, attempt, err)
+		}
+		wait := p.wait(attempt, err)
+		stats.update(func(s *RunStats) { s.retries[class]++ })
+		logger.Warn("retrying", "request", what, "err", err, "class", class, "retry", attempt, "of", attempts-1, "wait", wait.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// wait is how long to wait before retry n after err: what a Retry-After
+// header asks for, else the backoff. Either is capped at Max, so a server
+// asking for a day does not park a worker for a day.
+func (p RetryPolicy) wait(n int, err error) time.Duration {
+	var se *statusError
+	if !errors.As(err, &se) || se.RetryAfter <= 0 {
+		return p.backoff(n)
+	}
+	if p.Max > 0 {
+		return min(se.RetryAfter, p.Max)
+	}
+	return se.RetryAfter
+}
+
+// backoff is the full-jitter wait before retry n (1-based).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	ceil := p.Base << min(n-1, 30)
+	if ceil <= 0 || (p.Max > 0 && ceil > p.Max) {
+		ceil = p.Max
+	}
+	if ceil <= 0 {
+		return 0
+	}
+	return rand.N(ceil + 1)
+}
+
+// ---------- Error classes ----------
+
+// statusError is a response the scraper cannot use.
+type statusError struct {
+	URL        string
+	Code       int
+	Status     string
+	RetryAfter time.Duration // from the Retry-After header, 0 if none
+}
+
+func (e *statusError) Error() string { return fmt.Sprintf("GET %s: %s", e.URL, e.Status) }
+
+func newStatusError(raw string, resp *http.Response) *statusError {
+	return &statusError{URL: raw, Code: resp.StatusCode, Status: resp.Status, RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+}
+
+// retryAfter reads delay-seconds or an HTTP date.
+func retryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(h); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// permanentError is a failure no retry can fix, e.g. a local disk error.
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// classifyError names the kind of failure and says whether retrying can
+// help. Timeouts, resets, 5xx and 429 can; DNS names that do not exist,
+// certificate problems, other 4xx and anything unrecognised cannot.
+func classifyError(err error) (class string, retryable bool) {
+	var (
+		se   *statusError
+		perm permanentError
+		dns  *net.DNSError
+		cert *tls.CertificateVerificationError
+		hn   x509.HostnameError
+		ua   x509.UnknownAuthorityError
+		ci   x509.CertificateInvalidError
+		rh   tls.RecordHeaderError
+		ne   net.Error
+		op   *net.OpError
+		ce   contentError
+	)
+	switch {
+	case errors.As(err, &perm), errors.Is(err, context.Canceled):
+		return "permanent", false
+	case errors.As(err, &se):
+		switch {
+		case se.Code == http.StatusTooManyRequests:
+			return "http-429", true
+		case se.Code == http.StatusRequestTimeout, se.Code >= 500:
+			return "http-5xx", true
+		}
+		return "http-4xx", false
+	case errors.As(err, &dns):
+		return "dns", dns.IsTimeout || dns.IsTemporary
+	case errors.As(err, &cert), errors.As(err, &hn), errors.As(err, &ua), errors.As(err, &ci), errors.As(err, &rh):
+		return "tls", false
+	case errors.As(err, &ne) && ne.Timeout():
+		return "timeout", true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF), errors.As(err, &op):
+		return "network", true
+	case errors.As(err, &ce):
+		return "content", true
+	}
+	return "other", false
+}
+
+// contentError is a body that arrived but is wrong: cut short, not the
+// image it claims to be, or not the file the wiki has. Fetching it again
+// usually helps.
+type contentError struct{ error }
+
+func (e contentError) Unwrap() error { return e.error }
+
+// ---------- Circuit breaker ----------
+
+// Breaker stops all requests to a host for Pause once Threshold requests in
+// a row have failed in a retryable way, so an outage costs one pause rather
+// than every page's retries. After the pause requests flow again; the first
+// failure re-opens it, the first success closes it.
+type Breaker struct {
+	Threshold int // 0 disables
+	Pause     time.Duration
+
+	mu    sync.Mutex
+	fails map[string]int
+	until map[string]time.Time
+}
+
+var breaker = &Breaker{Threshold: 10, Pause: time.Minute}
+
+// wait blocks while host's circuit is open.
+func (b *Breaker) wait(ctx context.Context, host string) error {
+	for {
+		b.mu.Lock()
+		until := b.until[host]
+		b.mu.Unlock()
+		d := time.Until(until)
+		if d <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+}
+
+// record counts one request's outcome.
+func (b *Breaker) record(host string, failed bool) {
+	if b.Threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fails == nil {
+		b.fails, b.until = map[string]int{}, map[string]time.Time{}
+	}
+	if !failed {
+		b.fails[host] = 0
+		return
+	}
+	b.fails[host]++
+	if n := b.fails[host]; n >= b.Threshold && time.Now().After(b.until[host]) {
+		b.until[host] = time.Now().Add(b.Pause)
+		stats.update(func(s *RunStats) { s.pauses[host]++ })
+		logger.Warn("host failing; pausing requests to it", "host", host, "failures_in_a_row", n, "pause", b.Pause)
+	}
+}
+
+// doRequest sends req through the crawl policy and the breaker for its host.
+func doRequest(req *http.Request) (*http.Response, error) {
+	if err := policy.admit(req.Context(), req.URL); err != nil {
+		return nil, err
+	}
+	host := req.URL.Host
+	if err := breaker.wait(req.Context(), host); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := fetcher.Do(req)
+	stats.observe("request", time.Since(start))
+	stats.update(func(s *RunStats) { s.hosts[host]++ })
+	var failed bool
+	if err != nil {
+		_, failed = classifyError(&url.Error{Op: req.Method, URL: req.URL.String(), Err: err})
+	} else {
+		failed = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		resp.Body = countingBody{resp.Body, stats}
+	}
+	breaker.record(host, failed)
+	return resp, err
+}
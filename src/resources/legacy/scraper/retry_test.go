@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFetchPageRetriesTransientErrors(t *testing.T) {
+	calls, down := 0, false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		if calls == 1 || down {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<h1 id="firstHeading">Card</h1>`)
+	}))
+	defer srv.Close()
+	oldFetcher, oldRetry := fetcher, retry
+	t.Cleanup(func() { fetcher, retry = oldFetcher, oldRetry })
+	fetcher, retry = srv.Client(), RetryPolicy{Attempts: 3, Base: time.Millisecond, Max: time.Millisecond}
+
+	if _, _, err := fetchPage(srv.URL + "/wiki/Card"); err != nil || calls != 2 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+
+	calls, down = 0, true
+	_, _, err := fetchPage(srv.URL + "/wiki/Card")
+	var se *statusError
+	if !errors.As(err, &se) || se.Code != 503 || calls != 3 {
+		t.Errorf("err = %v after %d calls", err, calls)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err       error
+		class     string
+		retryable bool
+	}{
+		{&statusError{Code: 503}, "http-5xx", true},
+		{&statusError{Code: 429}, "http-429", true},
+		{&statusError{Code: 404}, "http-4xx", false},
+		{&url.Error{Op: "Get", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, "dns", false},
+		{&url.Error{Op: "Get", Err: &net.DNSError{Err: "timeout", IsTimeout: true}}, "dns", true},
+		{&url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}, "tls", false},
+		{&url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, "network", true},
+		{&url.Error{Op: "Get", Err: context.DeadlineExceeded}, "timeout", true},
+		{contentError{errors.New("short body")}, "content", true},
+		{permanentError{errors.New("disk full")}, "permanent", false},
+		{&url.Error{Op: "Get", Err: errors.New("replay: no recorded response")}, "other", false},
+	}
+	for _, c := range cases {
+		if class, ok := classifyError(c.err); class != c.class || ok != c.retryable {
+			t.Errorf("classifyError(%v) = %s, %v; want %s, %v", c.err, class, ok, c.class, c.retryable)
+		}
+	}
+}
+
+func TestRetryAfterAndBackoff(t *testing.T) {
+	if d := retryAfter("7"); d != 7*time.Second {
+		t.Errorf("retryAfter(7) = %s", d)
+	}
+	if d := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); d < 59*time.Minute {
+		t.Errorf("retryAfter(date) = %s", d)
+	}
+	p := RetryPolicy{Base: 100 * time.Millisecond, Max: time.Second}
+	for n := 1; n <= 40; n++ {
+		if d := p.backoff(n); d < 0 || d > time.Second || (n == 1 && d > 100*time.Millisecond) {
+			t.Fatalf("backoff(%d) = %s", n, d)
+		}
+	}
+
+	day := &statusError{Code: http.StatusTooManyRequests, RetryAfter: 24 * time.Hour}
+	if d := p.wait(1, day); d != time.Second {
+		t.Errorf("Retry-After 86400 waits %s, want -retry-max", d)
+	}
+	soon := &statusError{Code: http.StatusServiceUnavailable, RetryAfter: 300 * time.Millisecond}
+	if d := p.wait(1, fmt.Errorf("page: %w", soon)); d != 300*time.Millisecond {
+		t.Errorf("Retry-After 0.3s waits %s", d)
+	}
+}
+
+func TestBreakerPausesHost(t *testing.T) {
+	b := &Breaker{Threshold: 2, Pause: 50 * time.Millisecond}
+	b.record("wiki", true)
+	if err := b.wait(context.Background(), "wiki"); err != nil {
+		t.Fatal(err)
+	}
+	b.record("wiki", true)
+	start := time.Now()
+	b.wait(context.Background(), "other")
+	if time.Since(start) > 20*time.Millisecond {
+		t.Error("other host paused")
+	}
+	b.wait(context.Background(), "wiki")
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("wiki paused %s, want 50ms", d)
+	}
+	b.record("wiki", false)
+	if b.fails["wiki"] != 0 {
+		t.Error("success did not close the circuit")
+	}
+}
@@ -0,0 +1,279 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- User agent ----------
+
+// botName is the product token robots.txt groups are matched against.
+const botName = "overpower-scrape"
+
+var contact string // -contact, added to the User-Agent
+
+// userAgent names the bot and, with -contact, where to reach whoever runs
+// it: "overpower-scrape/1.1 (+https://example.org/bot)" or
+// "overpower-scrape/1.1 (+mailto:ops@example.org)". The "+URL" is by
+// convention the bot's own info page, so there is none without -contact;
+// the wiki being scraped would misattribute the bot.
+func userAgent() string {
+	if contact == "" {
+		return botName + "/1.1"
+	}
+	c := contact
+	if strings.Contains(c, "@") && !strings.Contains(c, ":") {
+		c = "mailto:" + c
+	}
+	return botName + "/1.1 (+" + c + ")"
+}
+
+// ---------- Crawl policy ----------
+
+// CrawlPolicy reads each host's robots.txt once and applies it to every
+// request: disallowed URLs are refused, and requests to a host with a
+// Crawl-delay are spaced at least that far apart across all workers.
+type CrawlPolicy struct {
+	mu      sync.Mutex
+	hosts   map[string]*hostPolicy // by scheme://host
+	refused map[string]bool
+}
+
+type hostPolicy struct {
+	once  sync.Once
+	rules robotsRules
+	next  time.Time // earliest time for the next request; guarded by CrawlPolicy.mu
+}
+
+var policy = &CrawlPolicy{}
+
+// robotsError is a request robots.txt does not allow.
+type robotsError struct{ URL string }
+
+func (e *robotsError) Error() string {
+	return fmt.Sprintf("robots.txt disallows %s for %s", e.URL, botName)
+}
+
+// admit waits out the host's Crawl-delay, or refuses u.
+func (p *CrawlPolicy) admit(ctx context.Context, u *url.URL) error {
+	if u.Path == "/robots.txt" {
+		return nil
+	}
+	key := u.Scheme + "://" + u.Host
+	p.mu.Lock()
+	if p.hosts == nil {
+		p.hosts, p.refused = map[string]*hostPolicy{}, map[string]bool{}
+	}
+	h := p.hosts[key]
+	if h == nil {
+		h = &hostPolicy{}
+		p.hosts[key] = h
+	}
+	p.mu.Unlock()
+
+	h.once.Do(func() { h.rules = fetchRobots(ctx, key) })
+	if !h.rules.allows(u) {
+		p.mu.Lock()
+		p.refused[u.String()] = true
+		p.mu.Unlock()
+		return permanentError{&robotsError{u.String()}}
+	}
+	if h.rules.delay <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	at := h.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	h.next = at.Add(h.rules.delay)
+	p.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+	}
+	return nil
+}
+
+// Refused lists the URLs robots.txt kept the scraper from, sorted.
+func (p *CrawlPolicy) Refused() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, 0, len(p.refused))
+	for u := range p.refused {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// fetchRobots follows RFC 9309: a missing robots.txt (4xx) allows
+// everything, an unreachable one (5xx or no answer, after retries)
+// disallows everything.
+func fetchRobots(ctx context.Context, origin string) robotsRules {
+	raw := origin + "/robots.txt"
+	var rules robotsRules
+	err := retry.Do(ctx, "robots "+raw, func() error {
+		req, err := newGetRequest(ctx, raw)
+		if err != nil {
+			return permanentError{err}
+		}
+		resp, err := fetcher.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			rules = parseRobots(io.LimitReader(resp.Body, 500<<10), botName)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+			rules = robotsRules{}
+		default:
+			return newStatusError(raw, resp)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warn("robots.txt unreachable; treating every URL on the host as disallowed", "url", raw, "err", err)
+		return robotsRules{rules: []robotsRule{{pattern: "/", allow: false}}}
+	}
+	if rules.delay > 0 {
+		logger.Info("robots.txt asks for a crawl delay", "url", raw, "crawl_delay", rules.delay)
+	}
+	return rules
+}
+
+// ---------- robots.txt ----------
+
+type robotsRules struct {
+	rules []robotsRule
+	delay time.Duration
+}
+
+type robotsRule struct {
+	pattern string // path pattern; '*' matches anything, a final '$' anchors
+	allow   bool
+}
+
+// parseRobots keeps the rules of the groups naming agent, or of the "*"
+// groups when none does.
+func parseRobots(r io.Reader, agent string) robotsRules {
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+	var groups []*group
+	var cur *group
+	inAgents := false
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		switch k {
+		case "user-agent":
+			if !inAgents || cur == nil {
+				cur = &group{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(v))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if cur == nil || v == "" { // "Disallow:" alone allows everything
+				continue
+			}
+			cur.rules.rules = append(cur.rules.rules, robotsRule{pattern: v, allow: k == "allow"})
+		case "crawl-delay":
+			inAgents = false
+			if cur == nil {
+				continue
+			}
+			if s, err := strconv.ParseFloat(v, 64); err == nil && s > 0 {
+				cur.rules.delay = time.Duration(s * float64(time.Second))
+			}
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var mine, star robotsRules
+	found := false
+	for _, g := range groups {
+		for _, a := range g.agents {
+			switch {
+			case a == "*":
+				star.rules = append(star.rules, g.rules.rules...)
+				star.delay = max(star.delay, g.rules.delay)
+			case a == agent:
+				found = true
+				mine.rules = append(mine.rules, g.rules.rules...)
+				mine.delay = max(mine.delay, g.rules.delay)
+			default:
+				continue
+			}
+			break
+		}
+	}
+	if found {
+		return mine
+	}
+	return star
+}
+
+// allows applies the longest matching rule; Allow wins a tie and no match
+// allows.
+func (r robotsRules) allows(u *url.URL) bool {
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, p) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allow = n, rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches a robots.txt path pattern against the start of p.
+func robotsMatch(pattern, p string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(p, parts[0]) {
+		return false
+	}
+	rest := p[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored {
+		last := parts[len(parts)-1]
+		return rest == "" || (len(parts) > 1 && strings.HasSuffix(p, last))
+	}
+	return true
+}
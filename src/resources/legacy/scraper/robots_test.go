@@ -0,0 +1,139 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const stubRobots = `# stub
+User-agent: *
+Disallow: /
+
+User-agent: Overpower-Scrape
+Disallow: /wiki/Special:
+Allow: /wiki/Special:Version$
+Disallow: /*action=edit
+Crawl-delay: 0.05
+`
+
+// robotsSite serves robots (or a 503 when robots is "") and records every
+// other request it gets.
+func robotsSite(t *testing.T, robots string) (*httptest.Server, func() []*http.Request) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			if robots == "" {
+				http.Error(w, "down", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, robots)
+			return
+		}
+		mu.Lock()
+		seen = append(seen, r)
+		mu.Unlock()
+		fmt.Fprint(w, `<h1 id="firstHeading">Page</h1>`)
+	}))
+	t.Cleanup(srv.Close)
+	oldFetcher, oldPolicy, oldRetry, oldContact := fetcher, policy, retry, contact
+	t.Cleanup(func() { fetcher, policy, retry, contact = oldFetcher, oldPolicy, oldRetry, oldContact })
+	fetcher, policy = srv.Client(), &CrawlPolicy{}
+	retry = RetryPolicy{Attempts: 2, Base: time.Millisecond, Max: time.Millisecond}
+	return srv, func() []*http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*http.Request(nil), seen...)
+	}
+}
+
+func TestRobotsPolicy(t *testing.T) {
+	srv, seen := robotsSite(t, stubRobots)
+	contact = "ops@example.org"
+
+	start := time.Now()
+	for _, p := range []string{"/wiki/Batman_(DCOP)", "/wiki/Special:Version"} {
+		if _, _, err := fetchPage(srv.URL + p); err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+	}
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Errorf("two requests took %s; Crawl-delay is 50ms", d)
+	}
+
+	rec := scrapeOne(srv.URL + "/wiki/Special:Random")[0]
+	var re *robotsError
+	if rec.Result != ResultDisallowed || !errors.As(rec.Error, &re) {
+		t.Errorf("disallowed page = %+v", rec)
+	}
+	if got := policy.Refused(); len(got) != 1 || !strings.HasSuffix(got[0], "/wiki/Special:Random") {
+		t.Errorf("Refused() = %v", got)
+	}
+
+	reqs := seen()
+	if len(reqs) != 2 {
+		t.Fatalf("server saw %d requests, want 2", len(reqs))
+	}
+	if ua := reqs[0].Header.Get("User-Agent"); ua != "overpower-scrape/1.1 (+mailto:ops@example.org)" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+}
+
+func TestUserAgentPointsAtContact(t *testing.T) {
+	old := contact
+	t.Cleanup(func() { contact = old })
+	for c, want := range map[string]string{
+		"":                        "overpower-scrape/1.1",
+		"ops@example.org":         "overpower-scrape/1.1 (+mailto:ops@example.org)",
+		"mailto:ops@example.org":  "overpower-scrape/1.1 (+mailto:ops@example.org)",
+		"https://example.org/bot": "overpower-scrape/1.1 (+https://example.org/bot)",
+	} {
+		contact = c
+		if got := userAgent(); got != want {
+			t.Errorf("-contact %q: User-Agent = %q, want %q", c, got, want)
+		}
+		if strings.Contains(userAgent(), "fandom.com") {
+			t.Errorf("User-Agent %q points at the wiki", userAgent())
+		}
+	}
+}
+
+func TestRobotsUnreachableDisallowsAll(t *testing.T) {
+	srv, seen := robotsSite(t, "")
+	if _, _, err := fetchPage(srv.URL + "/wiki/Batman_(DCOP)"); err == nil || len(seen()) != 0 {
+		t.Errorf("err = %v, %d requests", err, len(seen()))
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	rules := parseRobots(strings.NewReader(stubRobots), botName)
+	if rules.delay != 50*time.Millisecond {
+		t.Errorf("delay = %s", rules.delay)
+	}
+	cases := map[string]bool{
+		"/wiki/Batman_(DCOP)":              true,
+		"/wiki/Special:Random":             false,
+		"/wiki/Special:Version":            true,
+		"/wiki/Special:Version/x":          false,
+		"/index.php?title=X&action=edit":   false,
+		"/index.php?title=X&action=render": true,
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse("https://w" + raw)
+		if got := rules.allows(u); got != want {
+			t.Errorf("allows(%s) = %v, want %v", raw, got, want)
+		}
+	}
+	other := parseRobots(strings.NewReader(stubRobots), "someone-else")
+	if u, _ := url.Parse("https://w/wiki/Batman"); other.allows(u) {
+		t.Error("* group not applied to other agents")
+	}
+}
@@ -0,0 +1,673 @@
+// Package scraper is the card guide scraper behind every per-set command
+// (dcop, xmenop, ...): it crawls an expansion's index, parses each card
+// page, downloads the images and writes md/, manifest.csv and the run
+// reports. The per-set directories only hold a Set and their output.
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"scraper/setcode"
+)
+
+// ---------- Per-set configuration ----------
+
+// Set is what differs between the per-set commands.
+type Set struct {
+	Index   string // expansion index page, the -url default
+	Code    string // expected set tag, the -set default; "" infers it
+	Profile string // site profile, the -profile default; "" is fandom
+	Title   string // README heading; "" is "OverPower"
+}
+
+// ---------- Flags & globals ----------
+
+var (
+	startURL   string // -url, defaults to Set.Index
+	deprIndex  string // deprecated compatibility with old scripts
+	setCode    string // expected set tag; inferred from the index links when empty
+	outImages  = "images"
+	outMD      = "md"
+	overPath   string // hand corrections applied after scraping
+	recordPath string // save every HTTP exchange here
+	replayPath string // answer HTTP from a recording instead of the network
+	profileArg string // built-in site profile name or YAML file
+	runPath    string // run.json summary
+	bar        *Progress
+	includeRE  string // crawl filters, compiled into crawl
+	excludeRE  string
+	setTags    string
+	reportPath string        // JSON run report
+	title      = "OverPower" // README heading, from Set.Title
+	workers    = 10
+	reqDelay   = 300 * time.Millisecond
+	httpClient = &http.Client{
+		Timeout: 45 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+	}
+)
+
+// registerFlags binds fs to the globals, with set's defaults.
+func registerFlags(fs *flag.FlagSet, set Set) {
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+		fmt.Fprintln(w, "  go run . [flags]         scrape the expansion at -url")
+		fmt.Fprintln(w, "  go run . [flags] verify  re-check -out-images against manifest.csv and repair bad files")
+		fmt.Fprintln(w, "Example:")
+		fmt.Fprintln(w, "  go run . -url 'https://cardguide.fandom.com/wiki/Classic_OverPower_(expansion)'")
+		fmt.Fprintln(w, "Flags:")
+		fs.PrintDefaults()
+	}
+	if set.Profile == "" {
+		set.Profile = "fandom"
+	}
+	if set.Title == "" {
+		set.Title = "OverPower"
+	}
+	title = set.Title
+	fs.StringVar(&startURL, "url", set.Index, "Expansion index URL")
+	fs.StringVar(&deprIndex, "index", "", "DEPRECATED: use -url instead (kept for compatibility)")
+	fs.StringVar(&setCode, "set", set.Code, "Expected set tag, e.g. DCOP (empty: most common tag on the index page)")
+	fs.StringVar(&outImages, "out-images", outImages, "Directory for downloaded images")
+	fs.StringVar(&outMD, "out-md", outMD, "Directory for generated Markdown")
+	fs.StringVar(&overPath, "overrides", "", "Curation overrides (YAML or JSON) applied to every run")
+	fs.IntVar(&workers, "workers", workers, "Concurrent page fetches")
+	fs.DurationVar(&reqDelay, "delay", reqDelay, "Delay between page requests per fetch worker")
+	fs.IntVar(&parseStage.Workers, "parse-workers", parseStage.Workers, "Concurrent page parsers")
+	fs.DurationVar(&parseStage.Delay, "parse-delay", parseStage.Delay, "Delay between pages per parse worker (parsing makes no requests; 0 is unthrottled)")
+	fs.IntVar(&imageStage.Workers, "image-workers", imageStage.Workers, "Concurrent image downloads")
+	fs.DurationVar(&imageStage.Delay, "image-delay", imageStage.Delay, "Delay between image requests per download worker")
+	fs.BoolVar(&withImages, "images", true, "Download card images; -images=false records image URLs only")
+	fs.StringVar(&contact, "contact", "", "Where to reach you, an email or an info page URL; the User-Agent's +URL")
+	fs.IntVar(&retry.Attempts, "retries", retry.Attempts, "Tries per request, first included")
+	fs.DurationVar(&retry.Base, "retry-base", retry.Base, "Backoff before the first retry; doubles each retry, with full jitter")
+	fs.DurationVar(&retry.Max, "retry-max", retry.Max, "Longest wait between retries, Retry-After included")
+	fs.IntVar(&breaker.Threshold, "breaker", breaker.Threshold, "Pause a host after this many failed requests in a row (0 disables)")
+	fs.DurationVar(&breaker.Pause, "breaker-pause", breaker.Pause, "How long to pause a failing host")
+	fs.StringVar(&recordPath, "record", "", "Record every HTTP exchange to this HAR-like JSON file")
+	fs.StringVar(&replayPath, "replay", "", "Replay HTTP from a -record file; no network access")
+	fs.StringVar(&runPath, "summary", "run.json", "Write the machine-readable run summary (timings, requests, retries) here")
+	fs.StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	fs.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	fs.StringVar(&progressArg, "progress", "auto", "Progress bar on stderr: auto (when a terminal), on or off")
+	fs.StringVar(&reportPath, "report", "run-report.json", "Write the per-set JSON run report here")
+	fs.StringVar(&profileArg, "profile", set.Profile, "Site profile: built-in name (fandom, fandom-mvop) or a YAML file")
+	fs.IntVar(&crawl.Depth, "depth", 0, "Link levels to follow beyond the index page and its listings")
+	fs.IntVar(&crawl.MaxPages, "max-pages", crawl.MaxPages, "Stop crawling after fetching this many pages")
+	fs.StringVar(&includeRE, "include", "", "Only crawl and scrape links matching this regexp")
+	fs.StringVar(&excludeRE, "exclude", "", "Never crawl or scrape links matching this regexp")
+	fs.StringVar(&setTags, "tags", "", "Comma-separated set tags a card link must carry, e.g. XMOP,P (overrides the profile)")
+}
+
+// ---------- Types ----------
+
+type CardRecord struct {
+	PageURL     string
+	Name        string
+	KV          map[string]string
+	OrderedKeys []string
+
+	SetCode     string // "(DCOP)" tag from the page title, else from the image name
+	ControlCode string // two-character Control code on Specials, e.g. "AD"
+	SetMismatch bool   // SetCode disagrees with the expansion being crawled
+
+	ImageURL  string
+	ImageName string
+
+	SchemaKey string
+	Layout    string // stat block layout the page used; see parse.go
+	Error     error
+	Result    Result // card, skipped or which stage failed; see report.go
+}
+
+// ---------- Main ----------
+
+// Main runs the scraper command for set; it is the whole main of every
+// per-set command.
+func Main(set Set) {
+	registerFlags(flag.CommandLine, set)
+	flag.Parse()
+	var err error
+	bar, err = setupLogging()
+	must(err)
+	defer bar.Stop()
+
+	if flag.Arg(0) == "verify" {
+		profile, err = loadProfile(profileArg)
+		must(err)
+		must(runVerify("manifest.csv", outImages))
+		return
+	}
+
+	// Backward compatibility: allow -index, prefer -url. -url has the set's
+	// index as its default, so only an explicit -url beats -index.
+	if deprIndex != "" && !flagPassed(flag.CommandLine, "url") {
+		startURL = deprIndex
+	}
+	if startURL == "" {
+		bar.Stop()
+		logger.Error("missing -url: pass the expansion index page")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ov, err := loadOverrides(overPath)
+	must(err)
+	profile, err = loadProfile(profileArg)
+	must(err)
+	imageInfoAPI = profile.apiURL(startURL)
+	if setTags != "" {
+		profile.Links.SetTags = strings.Split(setTags, ",")
+	}
+	if includeRE != "" {
+		if crawl.Include, err = regexp.Compile(includeRE); err != nil {
+			must(fmt.Errorf("-include: %w", err))
+		}
+	}
+	if excludeRE != "" {
+		if crawl.Exclude, err = regexp.Compile(excludeRE); err != nil {
+			must(fmt.Errorf("-exclude: %w", err))
+		}
+	}
+
+	var rec *Recorder
+	switch {
+	case recordPath != "" && replayPath != "":
+		must(fmt.Errorf("-record and -replay are exclusive"))
+	case replayPath != "":
+		rec, err = LoadReplay(replayPath)
+		must(err)
+		httpClient.Transport = rec
+	case recordPath != "":
+		rec = NewRecorder(httpClient.Transport)
+		httpClient.Transport = rec
+	}
+
+	if withImages {
+		must(os.MkdirAll(outImages, 0o755))
+	}
+	must(os.MkdirAll(outMD, 0o755))
+
+	pages, err := collectCardPages(startURL)
+	must(err)
+	if len(pages) == 0 {
+		logger.Warn("no card pages found; check the URL", "url", startURL)
+		return
+	}
+	logger.Info("found candidate pages", "pages", len(pages))
+
+	if setCode == "" {
+		setCode = inferSetCode(pages)
+	}
+	setCode = strings.ToUpper(setCode)
+	if setCode != "" {
+		logger.Info("expecting set tag", "set", setCode)
+	}
+
+	// Fetch, parse and download in a pipeline; overrides patch each page
+	// before its image is fetched.
+	recs := scrapeAndDownloadAll(pages, ov)
+	recs = ov.Finish(recs)
+	flagSetMismatches(recs, setCode)
+
+	// Group by schema & write Markdown + README
+	groups := groupBySchema(recs)
+	must(writeMarkdownGroups(groups, outMD))
+	coverage := formatCoverage(layoutCoverage(recs))
+	logger.Info("page layouts", "set", setCode, "coverage", coverage)
+	must(writeIndex(groups, outMD, startURL, coverage))
+
+	// Write manifest CSV
+	must(writeManifestCSV(recs, "manifest.csv"))
+	must(ov.WriteAudit("overrides.log"))
+	if recordPath != "" {
+		must(rec.Save(recordPath))
+		logger.Info("recorded HTTP", "file", recordPath)
+	}
+
+	report := buildRunReport(recs, startURL, setCode)
+	report.Disallowed = policy.Refused()
+	if n := len(report.Disallowed); n > 0 {
+		logger.Warn("robots.txt refused URLs; listed under \"disallowed\"", "urls", n, "report", reportPath)
+	}
+	must(writeRunReport(report, reportPath))
+	must(writeRunSummary(stats.summary(startURL, setCode), runPath))
+
+	bar.Stop()
+	fail := 0
+	var failures []any
+	for _, k := range resultOrder {
+		if n := report.Totals[k]; k.Failed() && n > 0 {
+			fail += n
+			failures = append(failures, string(k), n)
+		}
+	}
+	if len(failures) > 0 {
+		logger.Warn("failures", append(failures, "report", reportPath)...)
+	}
+	logger.Info("done", "ok", report.Totals[ResultCard], "skipped", report.Totals[ResultSkipped], "failed", fail,
+		"images", outImages, "markdown", outMD, "manifest", "manifest.csv", "report", reportPath, "summary", runPath)
+}
+
+// ---------- Scrape ----------
+
+// scrapeOne parses a card page into one record per stat block; pages
+// listing several printings give several. Records carry the page's
+// canonical URL, not the link followed. A failed page gives one record
+// carrying the error.
+func scrapeOne(link string) []CardRecord {
+	doc, pageURL, err := fetchPage(link)
+	return parseCardPage(link, pageURL, doc, err)
+}
+
+// parseCardPage is scrapeOne after the fetch; the pipeline's parse stage
+// calls it with what the fetch pool got.
+func parseCardPage(link, pageURL string, doc *goquery.Document, err error) []CardRecord {
+	if err != nil {
+		res := ResultFetchError
+		var re *robotsError
+		if errors.As(err, &re) {
+			res = ResultDisallowed
+		}
+		return []CardRecord{{PageURL: link, KV: map[string]string{}, Error: err, Result: res}}
+	}
+	blocks, layout := profile.findStatBlocks(doc)
+	if len(blocks) == 0 {
+		return []CardRecord{{
+			PageURL: pageURL,
+			Name:    profile.cardName(doc, statBlock{}),
+			KV:      map[string]string{},
+			Error:   errNoStats,
+			Result:  ResultSkipped,
+		}}
+	}
+	return blockRecords(doc, pageURL, blocks, layout)
+}
+
+// flagSetMismatches marks cards tagged for another set, typically promos
+// linked from an expansion page.
+func flagSetMismatches(recs []CardRecord, want string) {
+	if want == "" {
+		return
+	}
+	for i := range recs {
+		r := &recs[i]
+		if r.Error != nil || r.SetCode == "" || r.SetCode == want {
+			continue
+		}
+		r.SetMismatch = true
+		logger.Warn("card tagged for another set", "name", r.Name, "set", r.SetCode, "want", want, "url", r.PageURL)
+	}
+}
+
+// ---------- HTML helpers ----------
+
+func textWithLinkFallback(sel *goquery.Selection) string {
+	txt := strings.TrimSpace(sel.Text())
+	if txt != "" {
+		return txt
+	}
+	if a := sel.Find("a").First(); a.Length() > 0 {
+		return strings.TrimSpace(a.Text())
+	}
+	return strings.TrimSpace(sel.Clone().Children().Remove().End().Text())
+}
+
+func cleanInline(s string) string {
+	s = strings.ReplaceAll(s, "\u00A0", " ")
+	s = strings.ReplaceAll(s, "’", "'")
+	s = strings.ReplaceAll(s, "“", "\"")
+	s = strings.ReplaceAll(s, "”", "\"")
+	s = strings.Join(strings.Fields(s), " ")
+	return s
+}
+
+// ---------- Image helpers ----------
+
+var badNameChars = regexp.MustCompile(`[^A-Za-z0-9._\-]+`)
+
+func pickFilename(u *url.URL) string {
+	// 1) Prefer explicit ?file=...
+	if f := u.Query().Get("file"); f != "" {
+		return sanitizeFilename(f)
+	}
+
+	// 2) .../SomeCard.jpg/revision/latest?cb=123456
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	name := filepath.Base(u.Path)
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == "revision" && i-1 >= 0 {
+			name = segments[i-1]
+			break
+		}
+	}
+	if name == "latest" && len(segments) >= 2 {
+		name = segments[len(segments)-2]
+	}
+	if cb := u.Query().Get("cb"); cb != "" {
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		if base == "" {
+			base = "image"
+		}
+		if ext == "" {
+			ext = ".jpg"
+		}
+		name = fmt.Sprintf("%s_cb%s%s", base, cb, ext)
+	}
+	if !strings.Contains(name, ".") {
+		name += ".jpg"
+	}
+	return sanitizeFilename(name)
+}
+
+func sanitizeFilename(name string) string {
+	if dec, err := url.PathUnescape(name); err == nil && dec != "" {
+		name = dec
+	}
+	name = strings.ReplaceAll(name, " ", "_")
+	name = badNameChars.ReplaceAllString(name, "_")
+	if len(name) <= 6 {
+		name = "wikia_" + name
+	}
+	return name
+}
+
+func findImageURLFromDoc(doc *goquery.Document, pageURL string) (string, error) {
+	return findImageURLIn(doc.Selection, pageURL)
+}
+
+// findImageURLIn looks for the image under sel only.
+func findImageURLIn(sel *goquery.Selection, pageURL string) (string, error) {
+	base, _ := url.Parse(pageURL)
+
+	// In profile priority order, by default ?file= links, og:image, <a class="image">.
+	for _, src := range profile.Images {
+		node := sel.Find(src.Selector).First()
+		if node.Length() == 0 {
+			continue
+		}
+		if ref, ok := node.Attr(src.Attr); ok && ref != "" {
+			if u, err := base.Parse(ref); err == nil {
+				return u.String(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no image link found")
+}
+
+// ---------- HTTP ----------
+
+func httpGetWithUA(ctx context.Context, raw string) (*http.Response, error) {
+	req, err := newGetRequest(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(req)
+}
+
+func newGetRequest(ctx context.Context, raw string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("Accept", "*/*")
+	return req, nil
+}
+
+// ---------- Grouping & Markdown output ----------
+
+type SchemaGroup struct {
+	SchemaKey   string
+	OrderedKeys []string
+	Records     []CardRecord
+	FileName    string
+	Title       string
+}
+
+func groupBySchema(recs []CardRecord) []SchemaGroup {
+	tmp := map[string]*SchemaGroup{}
+
+	for _, r := range recs {
+		if r.Error != nil {
+			continue
+		}
+		key := r.SchemaKey
+		if key == "" {
+			key = "_empty"
+		}
+		g, ok := tmp[key]
+		if !ok {
+			title := deriveGroupTitle(r.OrderedKeys)
+			tmp[key] = &SchemaGroup{
+				SchemaKey:   key,
+				OrderedKeys: append([]string{}, r.OrderedKeys...),
+				Records:     []CardRecord{},
+				Title:       title,
+			}
+			g = tmp[key]
+		}
+		g.Records = append(g.Records, r)
+	}
+
+	var groups []SchemaGroup
+	for _, g := range tmp {
+		sum := sha1.Sum([]byte(g.SchemaKey))
+		short := hex.EncodeToString(sum[:])[:10]
+		base := "group-" + short
+		if g.Title != "" {
+			if slug := slugify(g.Title); slug != "" {
+				base = slug + "-" + short
+			}
+		}
+		g.FileName = base + ".md"
+		sort.Slice(g.Records, func(i, j int) bool { return g.Records[i].Name < g.Records[j].Name })
+		groups = append(groups, *g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Title == groups[j].Title {
+			return groups[i].FileName < groups[j].FileName
+		}
+		return groups[i].Title < groups[j].Title
+	})
+
+	return groups
+}
+
+func deriveGroupTitle(keys []string) string {
+	if len(keys) == 0 {
+		return "Misc"
+	}
+	pinned := []string{"Type", "Control", "Numbers", "Game Text", "Characters", "Rarity"}
+	var have []string
+	for _, p := range pinned {
+		for _, k := range keys {
+			if k == p {
+				have = append(have, p)
+			}
+		}
+	}
+	if len(have) == 0 {
+		return strings.Join(keys, " / ")
+	}
+	return strings.Join(have, " / ")
+}
+
+func writeMarkdownGroups(groups []SchemaGroup, outDir string) error {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		path := filepath.Join(outDir, g.FileName)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+
+		fmt.Fprintf(w, "# %s\n\n", g.Title)
+		fmt.Fprintf(w, "_%d cards_\n\n", len(g.Records))
+
+		headers := append([]string{"Name"}, append(g.OrderedKeys, "Image")...)
+		writeMDHeader(w, headers)
+
+		for _, r := range g.Records {
+			row := []string{escapePipes(r.Name)}
+			for _, k := range g.OrderedKeys {
+				row = append(row, escapePipes(r.KV[k]))
+			}
+			row = append(row, escapePipes(r.ImageName))
+			writeMDRow(w, row)
+		}
+
+		if err := w.Flush(); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeIndex(groups []SchemaGroup, outDir, src, coverage string) error {
+	path := filepath.Join(outDir, "README.md")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintf(w, "# %s — Card Tables\n", title)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "_Source_: %s\n\n", src)
+	fmt.Fprintf(w, "_Page layouts_: %s\n\n", coverage)
+	fmt.Fprintf(w, "_%d groups_\n\n", len(groups))
+	for _, g := range groups {
+		fmt.Fprintf(w, "- [%s](%s) — %d cards\n", g.Title, g.FileName, len(g.Records))
+	}
+	return w.Flush()
+}
+
+func writeMDHeader(w *bufio.Writer, headers []string) {
+	fmt.Fprint(w, "| ")
+	fmt.Fprint(w, strings.Join(headers, " | "))
+	fmt.Fprintln(w, " |")
+	fmt.Fprint(w, "| ")
+	seps := make([]string, len(headers))
+	for i := range seps {
+		seps[i] = "---"
+	}
+	fmt.Fprint(w, strings.Join(seps, " | "))
+	fmt.Fprintln(w, " |")
+}
+
+func writeMDRow(w *bufio.Writer, cols []string) {
+	fmt.Fprint(w, "| ")
+	fmt.Fprint(w, strings.Join(cols, " | "))
+	fmt.Fprintln(w, " |")
+}
+
+// ---------- Manifest CSV ----------
+
+func writeManifestCSV(recs []CardRecord, path string) error {
+	// Gather all keys to make consistent columns
+	allKeysSet := map[string]struct{}{}
+	for _, r := range recs {
+		for k := range r.KV {
+			allKeysSet[k] = struct{}{}
+		}
+	}
+	var allKeys []string
+	for k := range allKeysSet {
+		allKeys = append(allKeys, k)
+	}
+	sort.Strings(allKeys)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+
+	header := append([]string{"Name", "ImageName", "ImageURL", "PageURL", "SetCode", "ControlCode", "ControlOrder", "SetMismatch"}, allKeys...)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range recs {
+		if r.Error != nil {
+			continue
+		}
+		order := ""
+		if n, ok := setcode.ControlOrdinal(r.ControlCode); ok {
+			order = strconv.Itoa(n)
+		}
+		row := []string{r.Name, r.ImageName, r.ImageURL, r.PageURL, r.SetCode, r.ControlCode, order, strconv.FormatBool(r.SetMismatch)}
+		for _, k := range allKeys {
+			row = append(row, r.KV[k])
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// ---------- Utils ----------
+
+func slugify(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, " ", "-")
+	s = regexp.MustCompile(`[^a-z0-9\-]+`).ReplaceAllString(s, "")
+	return strings.Trim(s, "-")
+}
+
+// flagPassed reports whether name was set on the command line.
+func flagPassed(fs *flag.FlagSet, name string) bool {
+	passed := false
+	fs.Visit(func(f *flag.Flag) { passed = passed || f.Name == name })
+	return passed
+}
+
+func escapePipes(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func must(err error) {
+	if err != nil {
+		bar.Stop()
+		logger.Error("fatal", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureIndex = "https://cardguide.fandom.com/wiki/DC_OverPower_(expansion)"
+
+// useReplay points the scraper at a recording and a temp image directory
+// for the rest of the test.
+func useReplay(t *testing.T, path string) {
+	t.Helper()
+	rec, err := LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFetcher, oldImages, oldWorkers, oldDelay, oldStage := fetcher, outImages, workers, reqDelay, imageStage
+	t.Cleanup(func() {
+		fetcher, outImages, workers, reqDelay, imageStage = oldFetcher, oldImages, oldWorkers, oldDelay, oldStage
+	})
+	oldPolicy := policy
+	t.Cleanup(func() { policy = oldPolicy })
+	oldStats := stats
+	t.Cleanup(func() { stats = oldStats })
+	fetcher, policy, stats = &http.Client{Transport: rec}, &CrawlPolicy{}, newRunStats()
+	outImages, workers, reqDelay = t.TempDir(), 2, time.Millisecond
+	imageStage = Stage{Workers: 2, Delay: time.Millisecond}
+}
+
+func TestScrapeReplayedSite(t *testing.T) {
+	useReplay(t, filepath.Join("testdata", "site.har"))
+
+	pages, err := collectCardPages(fixtureIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://cardguide.fandom.com/wiki/Batman_(DCOP)",
+		"https://cardguide.fandom.com/wiki/Batman_-_Dark_Knight_Detective_(DCOP)",
+	}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	if got := inferSetCode(pages); got != "DCOP" {
+		t.Errorf("inferSetCode = %q, want DCOP", got)
+	}
+
+	recs := scrapeAndDownloadAll(pages, &Overrides{})
+	byName := map[string]CardRecord{}
+	for _, r := range recs {
+		if r.Error != nil {
+			t.Fatalf("%s: %v", r.PageURL, r.Error)
+		}
+		byName[r.Name] = r
+	}
+
+	bat, ok := byName["Batman"]
+	if !ok {
+		t.Fatalf("no Batman in %v", byName)
+	}
+	if bat.KV["Type"] != "Character" || bat.KV["Characters"] != "Batman" || bat.SetCode != "DCOP" {
+		t.Errorf("Batman = %+v", bat)
+	}
+	if bat.ImageName != "Batman-DCOP_cb20200405134959.jpg" {
+		t.Errorf("Batman image = %q", bat.ImageName)
+	}
+	data, err := os.ReadFile(filepath.Join(outImages, bat.ImageName))
+	if err != nil || !bytes.Contains(data, []byte("JFIF batman")) {
+		t.Errorf("Batman image on disk = %q, %v", data, err)
+	}
+
+	spc := byName["Batman - Dark Knight Detective"]
+	if spc.ControlCode != "AD" || spc.KV["Game Text"] != "Venture 1 additional card." {
+		t.Errorf("special = %+v", spc)
+	}
+	if want := "Characters|Control|Game Text|Rarity|Type"; spc.SchemaKey != want {
+		t.Errorf("SchemaKey = %q, want %q", spc.SchemaKey, want)
+	}
+}
+
+func TestMetadataOnlyRun(t *testing.T) {
+	useReplay(t, filepath.Join("testdata", "site.har"))
+	old := withImages
+	t.Cleanup(func() { withImages = old })
+	withImages = false
+
+	recs := scrapeAndDownloadAll([]string{"https://cardguide.fandom.com/wiki/Batman_(DCOP)"}, &Overrides{})
+	if len(recs) != 1 || recs[0].Result != ResultCard || recs[0].ImageURL == "" {
+		t.Fatalf("recs = %+v", recs)
+	}
+	if files, _ := os.ReadDir(outImages); len(files) != 0 {
+		t.Errorf("-images=false downloaded %d files", len(files))
+	}
+}
+
+func TestParseDelayThrottlesParseStage(t *testing.T) {
+	useReplay(t, filepath.Join("testdata", "site.har"))
+	oldParse, oldImages := parseStage, withImages
+	t.Cleanup(func() { parseStage, withImages = oldParse, oldImages })
+	parseStage, withImages = Stage{Workers: 1, Delay: 150 * time.Millisecond}, false
+
+	pages := []string{
+		"https://cardguide.fandom.com/wiki/Batman_(DCOP)",
+		"https://cardguide.fandom.com/wiki/Batman_-_Dark_Knight_Detective_(DCOP)",
+	}
+	start := time.Now()
+	recs := scrapeAndDownloadAll(pages, &Overrides{})
+	// One worker waits out the delay before each of the two pages.
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("2 pages parsed in %v with a 150ms parse delay", elapsed)
+	}
+	if len(recs) != 2 {
+		t.Errorf("got %d records", len(recs))
+	}
+}
+
+func TestReplayHasNoNetworkFallback(t *testing.T) {
+	useReplay(t, filepath.Join("testdata", "site.har"))
+	rec := scrapeOne("https://cardguide.fandom.com/wiki/Not_Recorded_(DCOP)")[0]
+	if rec.Error == nil || !strings.Contains(rec.Error.Error(), "no recorded response") {
+		t.Fatalf("error = %v, want a replay miss", rec.Error)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	img := testPNG(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wiki/Card_(DCOP)":
+			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+			fmt.Fprint(w, `<h1 id="firstHeading">Card (DCOP)</h1><table><tr><th>Statistics</th></tr><tr><th>Type</th><td>Event</td></tr></table>`)
+		case "/card.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(img)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	oldFetcher := fetcher
+	t.Cleanup(func() { fetcher = oldFetcher })
+
+	rec := NewRecorder(http.DefaultTransport)
+	fetcher = &http.Client{Transport: rec}
+	live := scrapeOne(srv.URL + "/wiki/Card_(DCOP)")[0]
+	if live.Error != nil {
+		t.Fatal(live.Error)
+	}
+	dir := t.TempDir()
+	if err := downloadImage(srv.URL+"/card.png", filepath.Join(dir, "live.png")); err != nil {
+		t.Fatal(err)
+	}
+	har := filepath.Join(dir, "rec.har")
+	if err := rec.Save(har); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	useReplay(t, har)
+	replayed := scrapeOne(srv.URL + "/wiki/Card_(DCOP)")[0]
+	if replayed.Error != nil || replayed.Name != live.Name || replayed.KV["Type"] != "Event" {
+		t.Fatalf("replayed = %+v, live = %+v", replayed, live)
+	}
+	if err := downloadImage(srv.URL+"/card.png", filepath.Join(dir, "replayed.png")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "replayed.png")); !bytes.Equal(got, img) {
+		t.Errorf("replayed image = %q, want %q", got, img)
+	}
+}
@@ -0,0 +1,52 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+
+	"scraper/setcode"
+)
+
+// ---------- Set codes ----------
+
+// The tag table and Control code collation are in package setcode, shared
+// with opscrape's catalog.
+
+// setCodeFromPageURL returns the set tag carried in the page title, or "".
+func setCodeFromPageURL(pageURL string) string {
+	title := pageURL
+	if u, err := url.Parse(pageURL); err == nil {
+		title = u.Path
+	}
+	if dec, err := url.PathUnescape(title); err == nil {
+		title = dec
+	}
+	return setcode.FromPageTitle(title[strings.LastIndex(title, "/")+1:])
+}
+
+// resolveSetCode prefers the page title tag and falls back to the image name.
+func resolveSetCode(rec CardRecord) string {
+	if code := setCodeFromPageURL(rec.PageURL); code != "" {
+		return code
+	}
+	return setcode.FromImageName(rec.ImageName)
+}
+
+// inferSetCode picks the most common set tag among the collected page URLs.
+// Expansion index pages link mostly to their own cards, so the majority tag
+// is the expansion being crawled.
+func inferSetCode(pages []string) string {
+	counts := map[string]int{}
+	for _, p := range pages {
+		if code := setCodeFromPageURL(p); code != "" {
+			counts[code]++
+		}
+	}
+	best, bestN := "", 0
+	for code, n := range counts {
+		if n > bestN || (n == bestN && code < best) {
+			best, bestN = code, n
+		}
+	}
+	return best
+}
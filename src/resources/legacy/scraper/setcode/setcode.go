@@ -0,0 +1,106 @@
+// Package setcode decodes the set tags and Control codes the card guide puts
+// on card pages. The scraper writes them into manifest.csv and opscrape's
+// catalog reads them back, so both share this one table.
+package setcode
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ---------- Set codes ----------
+
+// names lists the set tags the card guide appends to page titles and image
+// names, e.g. "Adam_Warlock_-_The_Infinity_Watch_(CLOP)" or
+// "Adamwarlocktheinfinitywatch-CLOP_cb2021.jpg". "P" is the promo tag.
+var names = map[string]string{
+	"CLOP":  "Classic OverPower",
+	"PSOP":  "PowerSurge",
+	"MCOP":  "Mission Control",
+	"IQOP":  "IQ",
+	"XMOP":  "X-Men",
+	"MNOP":  "Monumental",
+	"DCOP":  "DC OverPower",
+	"JLAOP": "JLA",
+	"IMOP":  "Image OverPower",
+	"MVOP":  "Marvel OverPower",
+	"P":     "Promo",
+}
+
+var (
+	// "(CLOP)" or the broken "(PSOP_(Silver)" titles used by a few wiki pages.
+	pageTag = regexp.MustCompile(`\(([A-Z]+)(?:\)|_\()`)
+	// "-CLOP_cb..." / "-P-Marvel_cb..." / "-DCOP.jpg"
+	imageTag = regexp.MustCompile(`-([A-Z]+)(?:[-_.]|$)`)
+)
+
+// Name returns the expansion name for a set tag, or the tag itself.
+func Name(code string) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return code
+}
+
+// Known reports whether code is a known set tag, in any case.
+func Known(code string) bool {
+	_, ok := names[strings.ToUpper(code)]
+	return ok
+}
+
+// FromPageTitle returns the set tag of a decoded page title, or "". The
+// last known tag wins: "Deal_with_the_Devil_(Marvel)_(P)" is a promo.
+func FromPageTitle(title string) string {
+	code := ""
+	for _, m := range pageTag.FindAllStringSubmatch(title, -1) {
+		if _, ok := names[m[1]]; ok {
+			code = m[1]
+		}
+	}
+	return code
+}
+
+// FromImageName returns the set tag embedded in a downloaded image name, or "".
+func FromImageName(name string) string {
+	for _, m := range imageTag.FindAllStringSubmatch(name, -1) {
+		if _, ok := names[m[1]]; ok {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// ---------- Control codes ----------
+
+// Control codes are two characters stamped on Specials (and some Events);
+// the cards were collated alphabetically by code, AA first, with digits
+// following letters (X-Men uses A1..A9).
+const controlAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// controlOrder maps every two-character Control code to its collector position (1-based).
+var controlOrder = func() map[string]int {
+	m := make(map[string]int, len(controlAlphabet)*len(controlAlphabet))
+	n := 0
+	for _, a := range controlAlphabet {
+		for _, b := range controlAlphabet {
+			n++
+			m[string(a)+string(b)] = n
+		}
+	}
+	return m
+}()
+
+// NormalizeControl returns the upper-cased code when it is a valid Control code.
+func NormalizeControl(raw string) string {
+	code := strings.ToUpper(strings.TrimSpace(raw))
+	if _, ok := controlOrder[code]; ok {
+		return code
+	}
+	return ""
+}
+
+// ControlOrdinal decodes a Control code into its collector ordering.
+func ControlOrdinal(code string) (int, bool) {
+	n, ok := controlOrder[strings.ToUpper(code)]
+	return n, ok
+}
@@ -0,0 +1,49 @@
+package setcode
+
+import "testing"
+
+func TestFromPageTitle(t *testing.T) {
+	for title, want := range map[string]string{
+		"Adam_Warlock_-_The_Infinity_Watch_(CLOP)": "CLOP",
+		"Deal_with_the_Devil_(Marvel)_(P)":         "P",
+		"Wolverine_(PSOP_(Silver)":                 "PSOP",
+		"Acolytes_(team)":                          "",
+		"Flash_(DCOP)_(XYZ)":                       "DCOP",
+	} {
+		if got := FromPageTitle(title); got != want {
+			t.Errorf("FromPageTitle(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestFromImageName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Adamwarlocktheinfinitywatch-CLOP_cb2021.jpg": "CLOP",
+		"Dealwiththedevil-P-Marvel_cb2022.jpg":        "P",
+		"Batman-DCOP.jpg":                             "DCOP",
+		"Spider-Man_cb2020.jpg":                       "",
+	} {
+		if got := FromImageName(name); got != want {
+			t.Errorf("FromImageName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestControlOrdinal(t *testing.T) {
+	for code, want := range map[string]int{"AA": 1, "AB": 2, "az": 26, "A1": 28, "A9": 36, "BA": 37, "99": 36 * 36} {
+		if got, ok := ControlOrdinal(code); !ok || got != want {
+			t.Errorf("ControlOrdinal(%q) = %d, %v, want %d", code, got, ok, want)
+		}
+	}
+	for _, bad := range []string{"", "A", "AAA", "A-"} {
+		if _, ok := ControlOrdinal(bad); ok {
+			t.Errorf("ControlOrdinal(%q) ok", bad)
+		}
+	}
+	if got := NormalizeControl(" kh "); got != "KH" {
+		t.Errorf("NormalizeControl = %q", got)
+	}
+	if Name("XMOP") != "X-Men" || Name("ZZ") != "ZZ" || !Known("dcop") || Known("ZZ") {
+		t.Error("set names")
+	}
+}
@@ -0,0 +1,39 @@
+package scraper
+
+import "testing"
+
+func TestSetCodeFromPageURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://cardguide.fandom.com/wiki/Batman_(DCOP)":                            "DCOP",
+		"https://cardguide.fandom.com/wiki/Deal_with_the_Devil_%28Marvel%29_%28P%29": "P",
+		"https://cardguide.fandom.com/wiki/Acolytes_(team)":                          "",
+		"https://cardguide.fandom.com/wiki/Index?title=Flash_(DCOP)":                 "",
+	} {
+		if got := setCodeFromPageURL(raw); got != want {
+			t.Errorf("setCodeFromPageURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+	rec := CardRecord{PageURL: "https://w/wiki/Storm", ImageName: "Storm-XMOP_cb2022.jpg"}
+	if got := resolveSetCode(rec); got != "XMOP" {
+		t.Errorf("resolveSetCode falls back to %q, want XMOP", got)
+	}
+}
+
+func TestInferSetCode(t *testing.T) {
+	pages := []string{
+		"https://w/wiki/Angel_(XMOP)",
+		"https://w/wiki/Beast_(XMOP)",
+		"https://w/wiki/Storm_(P)",
+		"https://w/wiki/X-Men_(team)",
+	}
+	if got := inferSetCode(pages); got != "XMOP" {
+		t.Errorf("inferSetCode = %q, want XMOP", got)
+	}
+	// Ties go to the alphabetically first tag so reruns agree.
+	if got := inferSetCode([]string{"https://w/wiki/A_(PSOP)", "https://w/wiki/B_(CLOP)"}); got != "CLOP" {
+		t.Errorf("tie = %q, want CLOP", got)
+	}
+	if got := inferSetCode(nil); got != "" {
+		t.Errorf("no pages = %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ---------- Run statistics ----------
+
+// RunStats counts what a run did as it goes; the progress bar reads it live
+// and run.json is its final state.
+type RunStats struct {
+	mu      sync.Mutex
+	started time.Time
+	pages   int // card pages to scrape
+	fetched int
+	images  int
+	bytes   int64
+	results map[Result]int
+	hosts   map[string]int // requests per host
+	retries map[string]int // by error class
+	pauses  map[string]int // breaker pauses per host
+	timings map[string]*Histogram
+}
+
+var stats = newRunStats()
+
+func newRunStats() *RunStats {
+	return &RunStats{
+		started: time.Now(),
+		results: map[Result]int{}, hosts: map[string]int{}, retries: map[string]int{}, pauses: map[string]int{},
+		timings: map[string]*Histogram{},
+	}
+}
+
+func (s *RunStats) update(f func(s *RunStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s)
+}
+
+// observe records how long one step of kind took ("request", "page", "image").
+func (s *RunStats) observe(kind string, d time.Duration) {
+	s.update(func(s *RunStats) {
+		h := s.timings[kind]
+		if h == nil {
+			h = newHistogram()
+			s.timings[kind] = h
+		}
+		h.add(d)
+	})
+}
+
+// countingBody adds what is read from a response body to the byte count.
+type countingBody struct {
+	io.ReadCloser
+	s *RunStats
+}
+
+func (b countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.s.update(func(s *RunStats) { s.bytes += int64(n) })
+	}
+	return n, err
+}
+
+// ---------- Histograms ----------
+
+// histogramBounds are the bucket upper bounds in milliseconds.
+var histogramBounds = []int64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// Histogram counts durations into fixed buckets; the last bucket is
+// everything above the largest bound.
+type Histogram struct {
+	Counts []int `json:"counts"`
+	Count  int   `json:"count"`
+	SumMS  int64 `json:"sum_ms"`
+	MaxMS  int64 `json:"max_ms"`
+}
+
+func newHistogram() *Histogram { return &Histogram{Counts: make([]int, len(histogramBounds)+1)} }
+
+func (h *Histogram) add(d time.Duration) {
+	ms := d.Milliseconds()
+	i := 0
+	for i < len(histogramBounds) && ms > histogramBounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.SumMS += ms
+	h.MaxMS = max(h.MaxMS, ms)
+}
+
+// ---------- run.json ----------
+
+// RunSummary is run.json, the machine-readable end of a run.
+type RunSummary struct {
+	Source     string                `json:"source"`
+	Set        string                `json:"set"`
+	Started    time.Time             `json:"started"`
+	Finished   time.Time             `json:"finished"`
+	DurationMS int64                 `json:"duration_ms"`
+	Pages      int                   `json:"pages"`
+	Fetched    int                   `json:"fetched"`
+	Images     int                   `json:"images"`
+	Bytes      int64                 `json:"bytes"`
+	Results    map[Result]int        `json:"results"`
+	Failures   map[Result]int        `json:"failures"` // the *-error results only
+	Requests   map[string]int        `json:"requests_per_host"`
+	Retries    map[string]int        `json:"retries"` // by error class, see classifyError
+	Pauses     map[string]int        `json:"breaker_pauses,omitempty"`
+	BucketsMS  []int64               `json:"histogram_bounds_ms"`
+	Timings    map[string]*Histogram `json:"timings"`
+}
+
+func (s *RunStats) summary(src, set string) RunSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	sum := RunSummary{
+		Source: src, Set: set, Started: s.started, Finished: now, DurationMS: now.Sub(s.started).Milliseconds(),
+		Pages: s.pages, Fetched: s.fetched, Images: s.images, Bytes: s.bytes,
+		Results: map[Result]int{}, Failures: map[Result]int{},
+		Requests: map[string]int{}, Retries: map[string]int{}, Pauses: map[string]int{},
+		BucketsMS: histogramBounds, Timings: map[string]*Histogram{},
+	}
+	for k, v := range s.results {
+		sum.Results[k] = v
+		if k.Failed() {
+			sum.Failures[k] = v
+		}
+	}
+	for k, v := range s.hosts {
+		sum.Requests[k] = v
+	}
+	for k, v := range s.retries {
+		sum.Retries[k] = v
+	}
+	for k, v := range s.pauses {
+		sum.Pauses[k] = v
+	}
+	for k, h := range s.timings {
+		c := *h
+		c.Counts = append([]int(nil), h.Counts...)
+		sum.Timings[k] = &c
+	}
+	return sum
+}
+
+func writeRunSummary(sum RunSummary, path string) error {
+	data, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
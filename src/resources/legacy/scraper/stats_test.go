@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	h := newHistogram()
+	for _, ms := range []int{10, 50, 51, 700, 45000} {
+		h.add(time.Duration(ms) * time.Millisecond)
+	}
+	want := []int{2, 1, 0, 0, 1, 0, 0, 0, 0, 1}
+	if fmt.Sprint(h.Counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", h.Counts, want)
+	}
+	if h.Count != 5 || h.SumMS != 45811 || h.MaxMS != 45000 {
+		t.Errorf("histogram = %+v", h)
+	}
+}
+
+func TestRunSummaryFromReplay(t *testing.T) {
+	useReplay(t, filepath.Join("testdata", "site.har"))
+	recs := scrapeAndDownloadAll([]string{
+		"https://cardguide.fandom.com/wiki/Batman_(DCOP)",
+		"https://cardguide.fandom.com/wiki/Not_Recorded_(DCOP)",
+	}, &Overrides{})
+	if len(recs) != 2 {
+		t.Fatalf("recs = %+v", recs)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := writeRunSummary(stats.summary("https://w/", "DCOP"), path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum RunSummary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Pages != 2 || sum.Fetched != 2 || sum.Images != 1 || sum.Bytes == 0 {
+		t.Errorf("summary = %+v", sum)
+	}
+	if sum.Results[ResultCard] != 1 || sum.Failures[ResultFetchError] != 1 || len(sum.Failures) != 1 {
+		t.Errorf("results = %v, failures = %v", sum.Results, sum.Failures)
+	}
+	if sum.Requests["cardguide.fandom.com"] == 0 || sum.Requests["static.wikia.nocookie.net"] == 0 {
+		t.Errorf("requests = %v", sum.Requests)
+	}
+	for _, kind := range []string{"request", "page", "image"} {
+		if h := sum.Timings[kind]; h == nil || h.Count == 0 || len(h.Counts) != len(sum.BucketsMS)+1 {
+			t.Errorf("timings[%s] = %+v", kind, h)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	s := newRunStats()
+	if line := s.progressLine(); line != "" {
+		t.Errorf("before the crawl: %q", line)
+	}
+	s.pages, s.fetched, s.images, s.bytes = 4, 2, 1, 3<<20
+	s.results[ResultCard], s.results[ResultFetchError] = 1, 1
+	line := s.progressLine()
+	for _, want := range []string{"[##########----------]", "pages 2/4", "images 1", "3.0 MB", "errors 50.0%", "ETA "} {
+		if !strings.Contains(line, want) {
+			t.Errorf("%q lacks %q", line, want)
+		}
+	}
+}
+
+func TestSetupLoggingRejectsBadFlags(t *testing.T) {
+	old := []string{logFormat, logLevel, progressArg}
+	oldLogger := logger
+	t.Cleanup(func() { logFormat, logLevel, progressArg, logger = old[0], old[1], old[2], oldLogger })
+	for _, c := range [][3]string{{"xml", "info", "off"}, {"json", "loud", "off"}, {"json", "info", "maybe"}} {
+		logFormat, logLevel, progressArg = c[0], c[1], c[2]
+		if _, err := setupLogging(); err == nil {
+			t.Errorf("%v: no error", c)
+		}
+	}
+	logFormat, logLevel, progressArg = "json", "warn", "off"
+	if bar, err := setupLogging(); err != nil || bar != nil {
+		t.Errorf("json/warn/off = %v, %v", bar, err)
+	}
+}
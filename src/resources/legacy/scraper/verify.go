@@ -0,0 +1,122 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ---------- Verify ----------
+
+// runVerify is "go run . verify": it re-checks every image in dir and every
+// image manifest names, against the wiki's SHA1 where the imageinfo API has
+// one, and downloads bad or missing files again from the manifest's
+// ImageURL. Files the manifest does not know can only be reported.
+func runVerify(manifest, dir string) error {
+	urls, err := manifestImageURLs(manifest)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for n := range urls {
+		names[n] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names[strings.TrimSuffix(e.Name(), ".part")] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	ok, repaired, bad := 0, 0, 0
+	for _, name := range sorted {
+		path := filepath.Join(dir, name)
+		src := urls[name]
+		var want imageInfo
+		if src != "" && imageInfoAPI != "" {
+			time.Sleep(imageStage.Delay)
+			want = lookupImageInfo(src)
+		}
+		err := verifyImage(path, name, want)
+		if err == nil {
+			ok++
+			continue
+		}
+		if src == "" {
+			logger.Error("bad image not in the manifest; cannot repair", "file", path, "err", err, "manifest", manifest)
+			bad++
+			continue
+		}
+		logger.Warn("bad image; downloading again", "file", path, "err", err)
+		if rmErr := os.Remove(path); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+			return rmErr
+		}
+		time.Sleep(imageStage.Delay)
+		if err := downloadImage(src, path); err != nil {
+			logger.Error("repair failed", "file", path, "err", err)
+			bad++
+			continue
+		}
+		repaired++
+	}
+	logger.Info("verified", "images", len(sorted), "dir", dir, "ok", ok, "repaired", repaired, "bad", bad)
+	if bad > 0 {
+		return fmt.Errorf("%d images are still bad", bad)
+	}
+	return nil
+}
+
+// manifestImageURLs maps ImageName to ImageURL from a manifest.csv, and
+// points imageInfoAPI at the wiki its pages came from. A missing manifest,
+// or one written before the ImageURL column, gives no URLs.
+func manifestImageURLs(path string) (map[string]string, error) {
+	urls := map[string]string{}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return urls, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return urls, nil
+	}
+	col := map[string]int{}
+	for i, h := range rows[0] {
+		col[h] = i
+	}
+	iName, okName := col["ImageName"]
+	iURL, okURL := col["ImageURL"]
+	iPage, okPage := col["PageURL"]
+	if !okName || !okURL {
+		return urls, nil
+	}
+	for _, r := range rows[1:] {
+		if r[iName] != "" && r[iURL] != "" {
+			urls[r[iName]] = r[iURL]
+		}
+		if okPage && imageInfoAPI == "" && r[iPage] != "" {
+			imageInfoAPI = profile.apiURL(r[iPage])
+		}
+	}
+	return urls, nil
+}